@@ -17,10 +17,16 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"github.com/SENERGY-Platform/connection-log/pkg/alerting"
 	"github.com/SENERGY-Platform/connection-log/pkg/api"
 	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
 	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/digest"
+	"github.com/SENERGY-Platform/connection-log/pkg/ingestion"
+	"github.com/SENERGY-Platform/connection-log/pkg/tracing"
+	"github.com/SENERGY-Platform/connection-log/pkg/webhook"
 	"log"
 )
 
@@ -31,9 +37,39 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	shutdownTracing, err := tracing.Init(ctx, conf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
 	ctrl, err := controller.New(conf)
 	if err != nil {
 		log.Fatal(err)
 	}
-	api.StartRest(conf, ctrl)
+	err = ingestion.StartKafka(ctx, conf, ctrl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	deliveries, err := webhook.NewQueue(ctx, conf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = webhook.Start(ctx, conf, ctrl, deliveries)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = alerting.Start(ctx, conf, ctrl, deliveries)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = digest.Start(ctx, conf, ctrl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = api.StartRest(conf, ctrl)
+	if err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,175 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alerting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/webhook"
+	"github.com/google/uuid"
+)
+
+// Start evaluates the alert rules every config.AlertInterval until ctx is done.
+// Notifications are delivered by queue, which is shared with the webhooks and bound to the same ctx.
+// Nothing is started if the interval is empty, the current state store does not support alerts
+// or no user tokens can be requested to check the permissions of the rule owners.
+func Start(ctx context.Context, config configuration.Config, ctrl *controller.Controller, queue *webhook.Queue) error {
+	if config.AlertInterval == "" || config.AlertInterval == "-" {
+		return nil
+	}
+	if config.AuthEndpoint == "" || config.AuthEndpoint == "-" {
+		config.GetLogger().Warn("alerts disabled", "reason", controller.ErrTokenExchangeDisabled)
+		return nil
+	}
+	if err := ctrl.CheckAlerts(); err != nil {
+		if errors.Is(err, controller.ErrAlertsNotSupported) {
+			config.GetLogger().Warn("alerts disabled", "reason", err)
+			return nil
+		}
+		return err
+	}
+	interval, err := time.ParseDuration(config.AlertInterval)
+	if err != nil {
+		return fmt.Errorf("invalid alert interval: %w", err)
+	}
+	e := &engine{
+		config: config,
+		ctrl:   ctrl,
+		queue:  queue,
+	}
+	go e.run(ctx, interval)
+	return nil
+}
+
+type engine struct {
+	config configuration.Config
+	ctrl   *controller.Controller
+	queue  *webhook.Queue
+}
+
+func (this *engine) run(ctx context.Context, interval time.Duration) {
+	this.config.GetLogger().Info("start alert engine", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		this.evaluate(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			this.config.GetLogger().Info("stop alert engine")
+			return
+		}
+	}
+}
+
+func (this *engine) evaluate(ctx context.Context) {
+	rules, err := this.ctrl.ListAlertRules(ctx, "")
+	if err != nil {
+		this.config.GetLogger().Error("unable to list alert rules", "error", err)
+		return
+	}
+	for _, rule := range rules {
+		if err = this.evaluateRule(ctx, rule); err != nil {
+			this.config.GetLogger().Error("unable to evaluate alert rule", "rule", rule.ID, "error", err)
+		}
+	}
+}
+
+// evaluateRule fires an alert for each matching resource without active alert
+// and resolves the active alerts of resources that no longer match.
+// The resources are resolved on each evaluation, with the current permissions of the owner and the current group and location members,
+// so alerts of resources that are no longer accessible or members are resolved.
+func (this *engine) evaluateRule(ctx context.Context, rule model.AlertRule) error {
+	resources, err := this.ctrl.ResolveOwnerResources(ctx, rule.Owner, rule.IDs)
+	if err != nil {
+		return err
+	}
+	matches, err := this.ctrl.EvaluateAlertCondition(ctx, resources, rule.Condition)
+	if err != nil {
+		return err
+	}
+	active, err := this.ctrl.ListAlerts(ctx, model.AlertFilter{
+		RuleID: rule.ID,
+		States: []string{model.AlertStateFiring, model.AlertStateAcknowledged},
+	})
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	activeResources := map[string]bool{}
+	for _, alert := range active {
+		activeResources[alert.ResourceID] = true
+		if _, ok := matches[alert.ResourceID]; ok {
+			continue
+		}
+		alert.State = model.AlertStateResolved
+		alert.ResolvedAt = &now
+		if err = this.notify(ctx, rule, alert); err != nil {
+			return err
+		}
+		if err = this.ctrl.SetAlert(context.WithoutCancel(ctx), alert); err != nil {
+			return err
+		}
+	}
+	for id, value := range matches {
+		if activeResources[id] {
+			continue
+		}
+		kind, _ := controller.GetKindFromId(id, false)
+		alert := model.Alert{
+			ID:         uuid.NewString(),
+			RuleID:     rule.ID,
+			Owner:      rule.Owner,
+			ResourceID: id,
+			Kind:       kind,
+			Condition:  rule.Condition.Type,
+			Severity:   rule.Severity,
+			State:      model.AlertStateFiring,
+			Value:      value,
+			FiredAt:    now,
+		}
+		if err = this.notify(ctx, rule, alert); err != nil {
+			return err
+		}
+		if err = this.ctrl.SetAlert(context.WithoutCancel(ctx), alert); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notify queues alert for the webhooks of the rule channels, the state change of alert is stored after it is accepted by the queue.
+// If it is not accepted, the state change is not stored and the evaluation of the next interval notifies again.
+func (this *engine) notify(ctx context.Context, rule model.AlertRule, alert model.Alert) error {
+	for _, channel := range rule.Channels {
+		target, err := this.ctrl.GetWebhook(ctx, rule.Owner, channel.ID)
+		if err != nil {
+			this.config.GetLogger().Error("unable to notify alert channel", "rule", rule.ID, "webhook", channel.ID, "error", err)
+			continue
+		}
+		if err = this.queue.Enqueue(ctx, target, alert); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -17,11 +17,17 @@
 package api
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"slices"
+	"time"
 
 	"github.com/SENERGY-Platform/connection-log/pkg/api/util"
 	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
 	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/metrics"
+	"github.com/SENERGY-Platform/connection-log/pkg/tracing"
 	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
 	"github.com/SENERGY-Platform/service-commons/pkg/accesslog"
 	"github.com/julienschmidt/httprouter"
@@ -47,10 +53,44 @@ var routes = []func(ctrl *controller.Controller, dr deviceRepo.Interface) (m, p
 	PostQueryHistoricalStatesMap,
 	PostQueryHistoricalStatesMapOriginal,
 	PostQueryHistoricalStatesList,
+	PostQueryAvailabilityMap,
+	PostQueryDowntimesMap,
+	PostQueryFlapping,
+	PostQueryBucketsMap,
 	OfflineSinceDevices,
+	OfflineSinceHubs,
+	OfflineSince,
+	OnlineSinceDevices,
+	GetCurrentStream,
+	GetCurrentSubscriptions,
+	PostIngestEvents,
+	PostInternVernemqHook,
+	ListWebhooks,
+	GetWebhook,
+	PostWebhook,
+	PutWebhook,
+	DeleteWebhook,
+	ListAlertRules,
+	GetAlertRule,
+	PostAlertRule,
+	PutAlertRule,
+	DeleteAlertRule,
+	ListAlerts,
+	PostAcknowledgeAlert,
+	ListDigestSubscriptions,
+	GetDigestSubscription,
+	PostDigestSubscription,
+	PutDigestSubscription,
+	DeleteDigestSubscription,
+	PostSendDigest,
+	GetMetrics,
+	GetFleetMetrics,
 	GetSwaggerDoc,
 }
 
+// streamPaths are served without access log, the access log response writer does not support flushing and hijacking.
+var streamPaths = []string{"/current/stream", "/current/ws"}
+
 // StartRest
 // @title Connection Log API
 // @Version {version}
@@ -61,16 +101,66 @@ var routes = []func(ctrl *controller.Controller, dr deviceRepo.Interface) (m, p
 // @in header
 // @name Authorization
 // @BasePath /
-func StartRest(config configuration.Config, ctrl *controller.Controller) {
+func StartRest(config configuration.Config, ctrl *controller.Controller) error {
 	config.GetLogger().Info("start server", "port", config.ServerPort)
 	router := httprouter.New()
-	dr := deviceRepo.NewClient(config.DeviceRepoUrl, nil)
+	dr := ctrl.DeviceRepo()
+	registered := map[string]bool{}
 	for _, rf := range routes {
 		m, p, hf := rf(ctrl, dr)
-		router.Handle(m, p, hf)
+		timeout, err := requestTimeout(config, m, p)
+		if err != nil {
+			return fmt.Errorf("invalid request timeout of %s %s: %w", m, p, err)
+		}
+		router.Handle(m, p, instrument(m, p, timeout, hf))
+		registered[m+" "+p] = true
 		config.GetLogger().Info("added route", "method", m, "path", p)
 	}
+	for route := range config.RequestTimeouts {
+		if !registered[route] {
+			return fmt.Errorf("unknown route '%s' in RequestTimeouts, expected method and registered path, e.g. 'POST /historical/query/map'", route)
+		}
+	}
 	corseHandler := util.NewCors(router)
 	logger := accesslog.New(corseHandler)
-	config.GetLogger().Info("server stopped", "result", http.ListenAndServe(":"+config.ServerPort, logger))
+	handler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if slices.Contains(streamPaths, request.URL.Path) {
+			corseHandler.ServeHTTP(writer, request)
+			return
+		}
+		logger.ServeHTTP(writer, request)
+	})
+	return http.ListenAndServe(":"+config.ServerPort, handler)
+}
+
+// requestTimeout returns the configured deadline of a route, 0 if requests of the route have no deadline.
+func requestTimeout(config configuration.Config, method string, path string) (time.Duration, error) {
+	timeout, ok := config.RequestTimeouts[method+" "+path]
+	if !ok {
+		if slices.Contains(streamPaths, path) {
+			return 0, nil
+		}
+		timeout = config.RequestTimeout
+	}
+	if timeout == "" || timeout == "-" {
+		return 0, nil
+	}
+	return time.ParseDuration(timeout)
+}
+
+// instrument records the request durations and a span of handle, labeled with the registered path instead of the request path.
+// With a timeout > 0 the request context of handle ends after timeout, which cancels the backend calls of the request.
+func instrument(method string, path string, timeout time.Duration, handle httprouter.Handle) httprouter.Handle {
+	handler := tracing.Handler(method+" "+path, metrics.InstrumentHandler(path, http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		handle(writer, request, httprouter.ParamsFromContext(request.Context()))
+	})))
+	return func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		ctx := context.WithValue(request.Context(), httprouter.ParamsKey, params)
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		handler.ServeHTTP(writer, request.WithContext(ctx))
+	}
 }
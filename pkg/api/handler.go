@@ -1,16 +1,19 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/url"
-	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	_ "github.com/SENERGY-Platform/connection-log/docs"
 	"github.com/SENERGY-Platform/connection-log/pkg/api/util"
 	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/devicerepo"
+	"github.com/SENERGY-Platform/connection-log/pkg/metrics"
 	"github.com/SENERGY-Platform/connection-log/pkg/model"
 	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
 	"github.com/SENERGY-Platform/models/go/models"
@@ -53,7 +56,7 @@ func GetCurrentDeviceState(ctrl *controller.Controller, _ deviceRepo.Interface)
 			http.Error(writer, "devices endpoint only handles devices", http.StatusBadRequest)
 			return
 		}
-		ok, err := ctrl.CheckRightList(util.GetAuthToken(request), []string{id}, "r")
+		ok, err := ctrl.CheckRightList(request.Context(), util.GetAuthToken(request), []string{id}, "r")
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
 			return
@@ -103,7 +106,7 @@ func GetCurrentGatewayState(ctrl *controller.Controller, _ deviceRepo.Interface)
 			http.Error(writer, "gateways endpoint only handles gateways", http.StatusBadRequest)
 			return
 		}
-		ok, err := ctrl.CheckRightList(util.GetAuthToken(request), []string{id}, "r")
+		ok, err := ctrl.CheckRightList(request.Context(), util.GetAuthToken(request), []string{id}, "r")
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
 			return
@@ -146,17 +149,17 @@ func PostQueryBaseStatesMap(ctrl *controller.Controller, dr deviceRepo.Interface
 			return
 		}
 		token := util.GetAuthToken(request)
-		query.IDs, err = ctrl.PermissionsFilterIDs(token, query.IDs)
+		query.IDs, err = ctrl.PermissionsFilterIDs(request.Context(), token, query.IDs)
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		query.IDs, _, err = resolveDeviceIds(dr, token, query.IDs)
+		query.IDs, _, err = resolveDeviceIds(request.Context(), dr, token, query.IDs)
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		query.IDs, err = filterDevices(dr, token, query.IDs, query.DeviceAttributeBlacklist)
+		query.IDs, err = filterDevices(request.Context(), dr, token, query.IDs, query.DeviceAttributeBlacklist)
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
 			return
@@ -195,17 +198,17 @@ func PostQueryBaseStatesList(ctrl *controller.Controller, dr deviceRepo.Interfac
 			return
 		}
 		token := util.GetAuthToken(request)
-		query.IDs, err = ctrl.PermissionsFilterIDs(token, query.IDs)
+		query.IDs, err = ctrl.PermissionsFilterIDs(request.Context(), token, query.IDs)
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		query.IDs, _, err = resolveDeviceIds(dr, token, query.IDs)
+		query.IDs, _, err = resolveDeviceIds(request.Context(), dr, token, query.IDs)
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		query.IDs, err = filterDevices(dr, token, query.IDs, query.DeviceAttributeBlacklist)
+		query.IDs, err = filterDevices(request.Context(), dr, token, query.IDs, query.DeviceAttributeBlacklist)
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
 			return
@@ -246,25 +249,25 @@ func PostQueryWithAttributeFilterMapOriginal(ctrl *controller.Controller, dr dev
 		token := util.GetAuthToken(request)
 		var deviceIdToInputId map[string]string
 		if len(query.IDs) == 0 {
-			query.IDs, err = ctrl.ListIds(token, "devices")
+			query.IDs, err = ctrl.ListIds(request.Context(), token, "devices")
 			if err != nil {
 				http.Error(writer, err.Error(), http.StatusInternalServerError)
 				return
 			}
 		} else {
-			query.IDs, err = ctrl.PermissionsFilterIDs(token, query.IDs)
+			query.IDs, err = ctrl.PermissionsFilterIDs(request.Context(), token, query.IDs)
 			if err != nil {
 				http.Error(writer, err.Error(), http.StatusInternalServerError)
 				return
 			}
 
-			query.IDs, deviceIdToInputId, err = resolveDeviceIds(dr, token, query.IDs)
+			query.IDs, deviceIdToInputId, err = resolveDeviceIds(request.Context(), dr, token, query.IDs)
 			if err != nil {
 				http.Error(writer, err.Error(), http.StatusInternalServerError)
 				return
 			}
 		}
-		query.IDs, err = filterDevices(dr, token, query.IDs, query.DeviceAttributeBlacklist)
+		query.IDs, err = filterDevices(request.Context(), dr, token, query.IDs, query.DeviceAttributeBlacklist)
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
 			return
@@ -309,88 +312,66 @@ func PostQueryWithAttributeFilterMapOriginal(ctrl *controller.Controller, dr dev
 // OfflineSinceDevices godoc
 // @Summary Query offline timestamps of devices
 // @Description Query offline timestamps of devices with multiple IDs (supported: devices, device-groups, locations). If no IDs are provided, all accessible device IDs will be queried. Device-groups and locations will be resolved to their device IDs.
+// @Description Results can be filtered by offline duration, sorted by offline timestamp, name or ID and paginated with limit and offset.
 // @Tags Offline List
 // @Accept json
 // @Produce	json
 // @Security Bearer
-// @Param query body model.QueryWithAttributeFilter true "query object, attribute value and origin will only be checked if set, otherwise all values or origins will be blacklisted"
+// @Param query body model.QueryOfflineSince true "query object, attribute value and origin will only be checked if set, otherwise all values or origins will be blacklisted"
 // @Param include-names query boolean false "include device names in the response"
 // @Success	200 {array} model.OfflineSinceResponse "offline timestamps by device IDs"
+// @Header	200 {integer} X-Total-Count "number of results without limit and offset"
 // @Failure	400 {string} string "error message"
 // @Failure	500 {string} string "error message"
 // @Router /offline-since/devices [post]
 func OfflineSinceDevices(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
 	return http.MethodPost, "/offline-since/devices", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
-		var query model.QueryWithAttributeFilter
+		var query model.QueryOfflineSince
 		err := json.NewDecoder(request.Body).Decode(&query)
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusBadRequest)
 			return
 		}
+		if err = validateOfflineSinceOptions(query.OfflineSinceOptions); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
 		token := util.GetAuthToken(request)
 		if len(query.IDs) == 0 {
-			query.IDs, err = ctrl.ListIds(token, "devices")
+			query.IDs, err = ctrl.ListIds(request.Context(), token, "devices")
 			if err != nil {
 				http.Error(writer, err.Error(), http.StatusInternalServerError)
 				return
 			}
 		} else {
-			query.IDs, err = ctrl.PermissionsFilterIDs(token, query.IDs)
+			query.IDs, err = ctrl.PermissionsFilterIDs(request.Context(), token, query.IDs)
 			if err != nil {
 				http.Error(writer, err.Error(), http.StatusInternalServerError)
 				return
 			}
 
-			query.IDs, _, err = resolveDeviceIds(dr, token, query.IDs)
+			query.IDs, _, err = resolveDeviceIds(request.Context(), dr, token, query.IDs)
 			if err != nil {
 				http.Error(writer, err.Error(), http.StatusInternalServerError)
 				return
 			}
 		}
-		query.IDs, err = filterDevices(dr, token, query.IDs, query.DeviceAttributeBlacklist)
+		query.IDs, err = filterDevices(request.Context(), dr, token, query.IDs, query.DeviceAttributeBlacklist)
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		states, err := ctrl.GetOfflineSince(request.Context(), query.IDs, model.DeviceKind)
+		states, err := ctrl.GetOfflineSince(request.Context(), query.IDs, model.DeviceKind, query.OfflineSinceFilter)
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		includeNames := request.URL.Query().Get("include-names") == "true"
-		if includeNames {
-			deviceIds := []string{}
-			for _, state := range states {
-				deviceIds = append(deviceIds, state.ID)
-			}
-			devices, err, code := dr.ListDevices(token, deviceRepo.DeviceListOptions{Ids: deviceIds})
-			if err != nil {
-				http.Error(writer, err.Error(), code)
-				return
-			}
-			slices.SortFunc(devices, func(a, b models.Device) int {
-				return strings.Compare(a.Id, b.Id)
-			})
-			for i, state := range states {
-				idx, ok := slices.BinarySearchFunc(devices, models.Device{Id: state.ID}, func(a, b models.Device) int {
-					return strings.Compare(a.Id, b.Id)
-				})
-				if !ok {
-					continue
-				}
-				device := devices[idx]
-				for _, a := range device.Attributes {
-					if a.Key == "shared/nickname" {
-						state.Name = a.Value
-					}
-				}
-				if state.Name == "" {
-					state.Name = device.Name
-				}
-				states[i] = state
-			}
+		states, total, err, code := pageOfflineSince(request.Context(), dr, token, states, query.OfflineSinceOptions, request.URL.Query().Get("include-names") == "true")
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
 		}
-
+		writer.Header().Set("X-Total-Count", strconv.Itoa(total))
 		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
 		if err = json.NewEncoder(writer).Encode(states); err != nil {
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
@@ -430,7 +411,7 @@ func GetHistoricalDeviceStates(ctrl *controller.Controller, _ deviceRepo.Interfa
 			http.Error(writer, "devices endpoint only handles devices", http.StatusBadRequest)
 			return
 		}
-		ok, err := ctrl.CheckRightList(util.GetAuthToken(request), []string{id}, "r")
+		ok, err := ctrl.CheckRightList(request.Context(), util.GetAuthToken(request), []string{id}, "r")
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
 			return
@@ -488,7 +469,7 @@ func GetHistoricalGatewayStates(ctrl *controller.Controller, _ deviceRepo.Interf
 			http.Error(writer, "gateways endpoint only handles gateways", http.StatusBadRequest)
 			return
 		}
-		ok, err := ctrl.CheckRightList(util.GetAuthToken(request), []string{id}, "r")
+		ok, err := ctrl.CheckRightList(request.Context(), util.GetAuthToken(request), []string{id}, "r")
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
 			return
@@ -535,7 +516,7 @@ func PostQueryHistoricalStatesMap(ctrl *controller.Controller, _ deviceRepo.Inte
 			http.Error(writer, err.Error(), http.StatusBadRequest)
 			return
 		}
-		query.IDs, err = ctrl.PermissionsFilterIDs(util.GetAuthToken(request), query.IDs)
+		query.IDs, err = ctrl.PermissionsFilterIDs(request.Context(), util.GetAuthToken(request), query.IDs)
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
 			return
@@ -573,7 +554,7 @@ func PostQueryHistoricalStatesList(ctrl *controller.Controller, _ deviceRepo.Int
 			http.Error(writer, err.Error(), http.StatusBadRequest)
 			return
 		}
-		query.IDs, err = ctrl.PermissionsFilterIDs(util.GetAuthToken(request), query.IDs)
+		query.IDs, err = ctrl.PermissionsFilterIDs(request.Context(), util.GetAuthToken(request), query.IDs)
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
 			return
@@ -612,13 +593,13 @@ func PostQueryHistoricalStatesMapOriginal(ctrl *controller.Controller, dr device
 			return
 		}
 		token := util.GetAuthToken(request)
-		query.IDs, err = ctrl.PermissionsFilterIDs(token, query.IDs)
+		query.IDs, err = ctrl.PermissionsFilterIDs(request.Context(), token, query.IDs)
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
 			return
 		}
 		var deviceIdToInputId map[string]string
-		query.IDs, deviceIdToInputId, err = resolveDeviceIds(dr, token, query.IDs)
+		query.IDs, deviceIdToInputId, err = resolveDeviceIds(request.Context(), dr, token, query.IDs)
 		if err != nil {
 			http.Error(writer, err.Error(), http.StatusInternalServerError)
 			return
@@ -692,47 +673,12 @@ func parseHistoricalStatesQuery(query url.Values) (rng time.Duration, since time
 	return
 }
 
-func resolveDeviceIds(deviceRepoClient deviceRepo.Interface, token string, originalIds []string) (result []string, deviceIdToInputId map[string]string, err error) {
-	ids := slices.Clone(originalIds)
-	result = []string{}
-	deviceIdToInputId = map[string]string{}
-	for _, id := range ids {
-		if strings.HasPrefix(id, models.DEVICE_GROUP_PREFIX) {
-			deviceGroup, err, _ := deviceRepoClient.ReadDeviceGroup(id, token, false)
-			if err != nil {
-				return nil, nil, err
-			}
-			result = append(result, deviceGroup.DeviceIds...)
-			for _, deviceId := range deviceGroup.DeviceIds {
-				deviceIdToInputId[deviceId] = id
-			}
-		} else if strings.HasPrefix(id, models.LOCATION_PREFIX) {
-			location, err, _ := deviceRepoClient.GetLocation(id, token)
-			if err != nil {
-				return nil, nil, err
-			}
-			result = append(result, location.DeviceIds...)
-			for _, deviceId := range location.DeviceIds {
-				deviceIdToInputId[deviceId] = id
-			}
-			for _, deviceGroupId := range location.DeviceGroupIds {
-				deviceGroup, err, _ := deviceRepoClient.ReadDeviceGroup(deviceGroupId, token, false)
-				if err != nil {
-					return nil, nil, err
-				}
-				result = append(result, deviceGroup.DeviceIds...)
-				for _, deviceId := range deviceGroup.DeviceIds {
-					deviceIdToInputId[deviceId] = id
-				}
-			}
-		} else {
-			result = append(result, id)
-		}
-	}
-	return result, deviceIdToInputId, nil
+func resolveDeviceIds(ctx context.Context, deviceRepoClient deviceRepo.Interface, token string, originalIds []string) (result []string, deviceIdToInputId map[string]string, err error) {
+	return controller.ResolveDeviceIds(devicerepo.WithContext(ctx, deviceRepoClient), token, originalIds)
 }
 
-func filterDevices(deviceRepoClient deviceRepo.Interface, token string, ids []string, deviceAttributeBlacklist []models.Attribute) (filteredIds []string, err error) {
+func filterDevices(ctx context.Context, deviceRepoClient deviceRepo.Interface, token string, ids []string, deviceAttributeBlacklist []models.Attribute) (filteredIds []string, err error) {
+	deviceRepoClient = devicerepo.WithContext(ctx, deviceRepoClient)
 	if len(deviceAttributeBlacklist) == 0 {
 		return ids, nil
 	}
@@ -749,7 +695,7 @@ func filterDevices(deviceRepoClient deviceRepo.Interface, token string, ids []st
 	if len(deviceIds) == 0 {
 		return
 	}
-	devices, err, _ := deviceRepoClient.ListDevices(token, deviceRepo.DeviceListOptions{Ids: deviceIds})
+	devices, err, _ := controller.ListDevices(deviceRepoClient, token, deviceIds)
 	if err != nil {
 		return nil, err
 	}
@@ -768,3 +714,11 @@ outer:
 	}
 	return
 }
+
+// GetMetrics serves the prometheus metrics of the service.
+func GetMetrics(_ *controller.Controller, _ deviceRepo.Interface) (string, string, httprouter.Handle) {
+	handler := metrics.Handler()
+	return http.MethodGet, "/metrics", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		handler.ServeHTTP(writer, request)
+	}
+}
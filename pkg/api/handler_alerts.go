@@ -0,0 +1,278 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/api/util"
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ListAlertRules godoc
+// @Summary List alert rules
+// @Description Lists the alert rules created by the user of the token.
+// @Tags Alerts
+// @Produce	json
+// @Security Bearer
+// @Success	200 {array} model.AlertRule "alert rules"
+// @Failure	401 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /alert-rules [get]
+func ListAlertRules(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodGet, "/alert-rules", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		owner, err := getUserId(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		rules, err := ctrl.ListAlertRules(request.Context(), owner)
+		if err != nil {
+			http.Error(writer, err.Error(), alertErrorCode(err))
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(rules)
+		if err != nil {
+			ctrl.Config().GetLogger().Error("unable to encode response", "error", err)
+		}
+	}
+}
+
+// GetAlertRule godoc
+// @Summary Get alert rule
+// @Tags Alerts
+// @Produce	json
+// @Security Bearer
+// @Param id path string true "alert rule id"
+// @Success	200 {object} model.AlertRule "alert rule"
+// @Failure	401 {string} string "error message"
+// @Failure	404 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /alert-rules/{id} [get]
+func GetAlertRule(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodGet, "/alert-rules/:id", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		owner, err := getUserId(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		rule, err := ctrl.GetAlertRule(request.Context(), owner, params.ByName("id"))
+		if err != nil {
+			http.Error(writer, err.Error(), alertErrorCode(err))
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(rule)
+		if err != nil {
+			ctrl.Config().GetLogger().Error("unable to encode response", "error", err)
+		}
+	}
+}
+
+// PostAlertRule godoc
+// @Summary Create alert rule
+// @Description Creates a rule that fires an alert per resource (supported: devices, hubs, device-groups, locations) matching the condition: offline longer than 'duration' (offline),
+// @Description more than 'transitions' state changes per hour within 'window' (flapping) or less than 'availability' percent online within 'window' (availability).
+// @Description The token needs read permission for all IDs. Permissions are checked again and device-groups and locations are resolved to their current device IDs on each evaluation, IDs without permission are skipped. Channels reference webhooks of the user, they are called with the model.Alert when it fires and when it is resolved.
+// @Tags Alerts
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param rule body model.AlertRule true "alert rule, id and owner are set by the service"
+// @Success	200 {object} model.AlertRule "created alert rule"
+// @Failure	400 {string} string "error message"
+// @Failure	401 {string} string "error message"
+// @Failure	403 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /alert-rules [post]
+func PostAlertRule(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodPost, "/alert-rules", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		setAlertRule(ctrl, dr, writer, request, uuid.NewString(), true)
+	}
+}
+
+// PutAlertRule godoc
+// @Summary Update alert rule
+// @Description Replaces the alert rule, permissions are checked again. Active alerts of resources that no longer match are resolved by the next evaluation.
+// @Tags Alerts
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param id path string true "alert rule id"
+// @Param rule body model.AlertRule true "alert rule, id and owner are set by the service"
+// @Success	200 {object} model.AlertRule "updated alert rule"
+// @Failure	400 {string} string "error message"
+// @Failure	401 {string} string "error message"
+// @Failure	403 {string} string "error message"
+// @Failure	404 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /alert-rules/{id} [put]
+func PutAlertRule(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodPut, "/alert-rules/:id", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		setAlertRule(ctrl, dr, writer, request, params.ByName("id"), false)
+	}
+}
+
+// DeleteAlertRule godoc
+// @Summary Delete alert rule
+// @Description Deletes the alert rule and its alerts.
+// @Tags Alerts
+// @Security Bearer
+// @Param id path string true "alert rule id"
+// @Success	204
+// @Failure	401 {string} string "error message"
+// @Failure	404 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /alert-rules/{id} [delete]
+func DeleteAlertRule(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodDelete, "/alert-rules/:id", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		owner, err := getUserId(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		err = ctrl.DeleteAlertRule(request.Context(), owner, params.ByName("id"))
+		if err != nil {
+			http.Error(writer, err.Error(), alertErrorCode(err))
+			return
+		}
+		writer.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ListAlerts godoc
+// @Summary List alerts
+// @Description Lists the alerts of the rules of the user of the token, latest first.
+// @Tags Alerts
+// @Produce	json
+// @Security Bearer
+// @Param state query string false "comma separated list of states (firing, acknowledged, resolved)"
+// @Param rule query string false "alert rule id"
+// @Success	200 {array} model.Alert "alerts"
+// @Failure	401 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /alerts [get]
+func ListAlerts(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodGet, "/alerts", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		owner, err := getUserId(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		filter := model.AlertFilter{Owner: owner, RuleID: request.URL.Query().Get("rule")}
+		if states := request.URL.Query().Get("state"); states != "" {
+			filter.States = strings.Split(states, ",")
+		}
+		alerts, err := ctrl.ListAlerts(request.Context(), filter)
+		if err != nil {
+			http.Error(writer, err.Error(), alertErrorCode(err))
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(alerts)
+		if err != nil {
+			ctrl.Config().GetLogger().Error("unable to encode response", "error", err)
+		}
+	}
+}
+
+// PostAcknowledgeAlert godoc
+// @Summary Acknowledge alert
+// @Description Marks a firing alert as acknowledged, it stays active until the condition is no longer met.
+// @Tags Alerts
+// @Produce	json
+// @Security Bearer
+// @Param id path string true "alert id"
+// @Success	200 {object} model.Alert "acknowledged alert"
+// @Failure	401 {string} string "error message"
+// @Failure	404 {string} string "error message"
+// @Failure	409 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /alerts/{id}/acknowledge [post]
+func PostAcknowledgeAlert(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodPost, "/alerts/:id/acknowledge", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		owner, err := getUserId(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		alert, err := ctrl.AcknowledgeAlert(request.Context(), owner, params.ByName("id"))
+		if err != nil {
+			http.Error(writer, err.Error(), alertErrorCode(err))
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(alert)
+		if err != nil {
+			ctrl.Config().GetLogger().Error("unable to encode response", "error", err)
+		}
+	}
+}
+
+func setAlertRule(ctrl *controller.Controller, dr deviceRepo.Interface, writer http.ResponseWriter, request *http.Request, id string, create bool) {
+	owner, err := getUserId(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if !create {
+		_, err = ctrl.GetAlertRule(request.Context(), owner, id)
+		if err != nil {
+			http.Error(writer, err.Error(), alertErrorCode(err))
+			return
+		}
+	}
+	var rule model.AlertRule
+	err = json.NewDecoder(request.Body).Decode(&rule)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rule.ID = id
+	rule.Owner = owner
+	var code int
+	rule.Resources, err, code = resolvePermittedIds(request.Context(), ctrl, dr, util.GetAuthToken(request), rule.IDs)
+	if err != nil {
+		http.Error(writer, err.Error(), code)
+		return
+	}
+	err = ctrl.SetAlertRule(request.Context(), rule)
+	if err != nil {
+		http.Error(writer, err.Error(), alertErrorCode(err))
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(writer).Encode(rule)
+	if err != nil {
+		ctrl.Config().GetLogger().Error("unable to encode response", "error", err)
+	}
+}
+
+func alertErrorCode(err error) int {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, controller.ErrInvalidAlertRule):
+		return http.StatusBadRequest
+	case errors.Is(err, controller.ErrAlertResolved):
+		return http.StatusConflict
+	case errors.Is(err, controller.ErrAlertsNotSupported), errors.Is(err, controller.ErrWebhooksNotSupported):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/api/util"
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/devicerepo"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PostQueryAvailabilityMap godoc
+// @Summary Query availability
+// @Description Query online ratio, online, offline and unknown durations within the selected time frame for multiple IDs (supported: devices, gateways/hubs, device-groups, locations).
+// @Description Device-groups and locations get the sum of the durations of their devices. Without 'since' or 'range' the time frame starts with the first known state, it never extends beyond the current time.
+// @Tags Historical states
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param query body model.QueryHistorical true "query object"
+// @Success	200 {object} map[string]model.Availability "availability mapped to IDs"
+// @Failure	400 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Router /historical/query/availability [post]
+func PostQueryAvailabilityMap(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodPost, "/historical/query/availability", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		var query model.QueryHistorical
+		err := json.NewDecoder(request.Body).Decode(&query)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		token := util.GetAuthToken(request)
+		inputIds, err := ctrl.PermissionsFilterIDs(request.Context(), token, query.IDs)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		members, err := resolveMembers(request.Context(), dr, token, inputIds)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		query.IDs = []string{}
+		for _, ids := range members {
+			query.IDs = append(query.IDs, ids...)
+		}
+		slices.Sort(query.IDs)
+		query.IDs = slices.Compact(query.IDs)
+		availability, err := ctrl.QueryAvailabilityMap(request.Context(), query)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		res := map[string]model.Availability{}
+		for inputId, ids := range members {
+			if len(ids) == 1 && ids[0] == inputId {
+				res[inputId] = availability[inputId]
+				continue
+			}
+			items := []model.Availability{}
+			for _, id := range ids {
+				items = append(items, availability[id])
+			}
+			res[inputId] = controller.AggregateAvailability(items...)
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err = json.NewEncoder(writer).Encode(res); err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func resolveMembers(ctx context.Context, deviceRepoClient deviceRepo.Interface, token string, inputIds []string) (map[string][]string, error) {
+	return controller.ResolveMembers(devicerepo.WithContext(ctx, deviceRepoClient), token, inputIds)
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+func TestPostQueryAvailabilityMap(t *testing.T) {
+	api := newTestApi(t)
+	seedHistory(api)
+	result := decode[map[string]model.Availability](t, serve(api.router, http.MethodPost, "/historical/query/availability", historyQuery([]string{flappingId, recoveringId}, nil)))
+	expected := map[string]model.Availability{
+		flappingId:   {OnlineRatio: 0.625, Online: model.Duration(5 * time.Hour), Offline: model.Duration(3 * time.Hour)},
+		recoveringId: {OnlineRatio: 0.25, Online: model.Duration(2 * time.Hour), Offline: model.Duration(6 * time.Hour)},
+	}
+	if len(result) != len(expected) {
+		t.Fatalf("\n%#v\n%#v", result, expected)
+	}
+	for id, availability := range expected {
+		if result[id] != availability {
+			t.Errorf("%v:\n%#v\n%#v", id, result[id], availability)
+		}
+	}
+}
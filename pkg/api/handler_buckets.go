@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/api/util"
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PostQueryBucketsMap godoc
+// @Summary Query availability buckets
+// @Description Query availability series for multiple IDs (supported: devices, gateways/hubs). Each bucket contains the online ratio, the number of transitions and the state at bucket start.
+// @Description Buckets are aligned to midnight in the given timezone, the first and last bucket are cut by the selected time frame.
+// @Tags Historical states
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param query body model.QueryBuckets true "query object"
+// @Success	200 {object} map[string][]model.Bucket "buckets mapped to IDs"
+// @Failure	400 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Router /historical/query/buckets [post]
+func PostQueryBucketsMap(ctrl *controller.Controller, _ deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodPost, "/historical/query/buckets", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		var query model.QueryBuckets
+		err := json.NewDecoder(request.Body).Decode(&query)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err = controller.ValidateBucketsQuery(query); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query.IDs, err = ctrl.PermissionsFilterIDs(request.Context(), util.GetAuthToken(request), query.IDs)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		res, err := ctrl.QueryBucketsMap(request.Context(), query)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err = json.NewEncoder(writer).Encode(res); err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
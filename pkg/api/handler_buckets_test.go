@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+func TestPostQueryBucketsMap(t *testing.T) {
+	api := newTestApi(t)
+	seedHistory(api)
+	result := decode[map[string][]model.Bucket](t, serve(api.router, http.MethodPost, "/historical/query/buckets", historyQuery([]string{flappingId, recoveringId}, map[string]any{"bucket": "4h"})))
+	online := true
+	expected := map[string][]model.Bucket{
+		flappingId: {
+			{Start: t0, End: t0.Add(4 * time.Hour), OnlineRatio: 0.5, Transitions: 2, StartState: &online},
+			{Start: t0.Add(4 * time.Hour), End: t0.Add(8 * time.Hour), OnlineRatio: 0.75, Transitions: 2, StartState: &online},
+		},
+		recoveringId: {
+			{Start: t0, End: t0.Add(4 * time.Hour), OnlineRatio: 0.5, Transitions: 1, StartState: &online},
+			{Start: t0.Add(4 * time.Hour), End: t0.Add(8 * time.Hour), OnlineRatio: 0, Transitions: 0, StartState: new(bool)},
+		},
+	}
+	if len(result) != len(expected) {
+		t.Fatalf("\n%#v\n%#v", result, expected)
+	}
+	for id, buckets := range expected {
+		if len(result[id]) != len(buckets) {
+			t.Fatalf("%v:\n%#v\n%#v", id, result[id], buckets)
+		}
+		for i, bucket := range buckets {
+			actual := result[id][i]
+			if !actual.Start.Equal(bucket.Start) || !actual.End.Equal(bucket.End) || actual.OnlineRatio != bucket.OnlineRatio || actual.Transitions != bucket.Transitions ||
+				actual.StartState == nil || *actual.StartState != *bucket.StartState {
+				t.Errorf("%v %v:\n%#v\n%#v", id, i, actual, bucket)
+			}
+		}
+	}
+
+	for _, fields := range []map[string]any{{"bucket": "500ms"}, {"bucket": "1h", "timezone": "Unknown/Zone"}} {
+		resp := serve(api.router, http.MethodPost, "/historical/query/buckets", historyQuery([]string{flappingId}, fields))
+		if resp.Code != http.StatusBadRequest {
+			t.Errorf("%v: unexpected status %v: %v", fields, resp.Code, resp.Body.String())
+		}
+	}
+}
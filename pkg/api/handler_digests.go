@@ -0,0 +1,260 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/api/util"
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/devicerepo"
+	"github.com/SENERGY-Platform/connection-log/pkg/digest"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/notifier"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ListDigestSubscriptions godoc
+// @Summary List digest subscriptions
+// @Description Lists the email digest subscriptions of the user of the token.
+// @Tags Digests
+// @Produce	json
+// @Security Bearer
+// @Success	200 {array} model.DigestSubscription "digest subscriptions"
+// @Failure	401 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /digests [get]
+func ListDigestSubscriptions(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodGet, "/digests", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		owner, err := getUserId(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		subscriptions, err := ctrl.ListDigestSubscriptions(request.Context(), owner)
+		if err != nil {
+			http.Error(writer, err.Error(), digestErrorCode(err))
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(subscriptions)
+		if err != nil {
+			ctrl.Config().GetLogger().Error("unable to encode response", "error", err)
+		}
+	}
+}
+
+// GetDigestSubscription godoc
+// @Summary Get digest subscription
+// @Tags Digests
+// @Produce	json
+// @Security Bearer
+// @Param id path string true "digest subscription id"
+// @Success	200 {object} model.DigestSubscription "digest subscription"
+// @Failure	401 {string} string "error message"
+// @Failure	404 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /digests/{id} [get]
+func GetDigestSubscription(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodGet, "/digests/:id", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		owner, err := getUserId(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		subscription, err := ctrl.GetDigestSubscription(request.Context(), owner, params.ByName("id"))
+		if err != nil {
+			http.Error(writer, err.Error(), digestErrorCode(err))
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(subscription)
+		if err != nil {
+			ctrl.Config().GetLogger().Error("unable to encode response", "error", err)
+		}
+	}
+}
+
+// PostDigestSubscription godoc
+// @Summary Subscribe to email digests
+// @Description Subscribes to a daily or weekly email with the offline intervals of devices and hubs and the availability of each ID (supported: devices, hubs, device-groups, locations) within the last day or week.
+// @Description The token needs read permission for all IDs. Permissions are checked again and device-groups and locations are resolved to their current device IDs for each digest, IDs without permission are skipped. The first digest is sent at the next scheduled hour.
+// @Tags Digests
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param subscription body model.DigestSubscription true "digest subscription, id, owner and last_sent are set by the service"
+// @Success	200 {object} model.DigestSubscription "created digest subscription"
+// @Failure	400 {string} string "error message"
+// @Failure	401 {string} string "error message"
+// @Failure	403 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /digests [post]
+func PostDigestSubscription(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodPost, "/digests", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		setDigestSubscription(ctrl, dr, writer, request, uuid.NewString(), true)
+	}
+}
+
+// PutDigestSubscription godoc
+// @Summary Update digest subscription
+// @Description Replaces the digest subscription, permissions are checked again.
+// @Tags Digests
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param id path string true "digest subscription id"
+// @Param subscription body model.DigestSubscription true "digest subscription, id, owner and last_sent are set by the service"
+// @Success	200 {object} model.DigestSubscription "updated digest subscription"
+// @Failure	400 {string} string "error message"
+// @Failure	401 {string} string "error message"
+// @Failure	403 {string} string "error message"
+// @Failure	404 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /digests/{id} [put]
+func PutDigestSubscription(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodPut, "/digests/:id", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		setDigestSubscription(ctrl, dr, writer, request, params.ByName("id"), false)
+	}
+}
+
+// DeleteDigestSubscription godoc
+// @Summary Delete digest subscription
+// @Tags Digests
+// @Security Bearer
+// @Param id path string true "digest subscription id"
+// @Success	204
+// @Failure	401 {string} string "error message"
+// @Failure	404 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /digests/{id} [delete]
+func DeleteDigestSubscription(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodDelete, "/digests/:id", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		owner, err := getUserId(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		err = ctrl.DeleteDigestSubscription(request.Context(), owner, params.ByName("id"))
+		if err != nil {
+			http.Error(writer, err.Error(), digestErrorCode(err))
+			return
+		}
+		writer.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// PostSendDigest godoc
+// @Summary Send digest now
+// @Description Sends the digest of the last day or week immediately, e.g. to check the SMTP settings. The schedule is not changed.
+// @Tags Digests
+// @Security Bearer
+// @Param id path string true "digest subscription id"
+// @Success	204
+// @Failure	401 {string} string "error message"
+// @Failure	404 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /digests/{id}/send [post]
+func PostSendDigest(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodPost, "/digests/:id/send", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		owner, err := getUserId(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		subscription, err := ctrl.GetDigestSubscription(request.Context(), owner, params.ByName("id"))
+		if err != nil {
+			http.Error(writer, err.Error(), digestErrorCode(err))
+			return
+		}
+		err = digest.Send(request.Context(), ctrl, notifier.NewSMTP(*ctrl.Config()), subscription, time.Now())
+		if err != nil {
+			http.Error(writer, err.Error(), digestErrorCode(err))
+			return
+		}
+		writer.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func setDigestSubscription(ctrl *controller.Controller, dr deviceRepo.Interface, writer http.ResponseWriter, request *http.Request, id string, create bool) {
+	owner, err := getUserId(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if !create {
+		_, err = ctrl.GetDigestSubscription(request.Context(), owner, id)
+		if err != nil {
+			http.Error(writer, err.Error(), digestErrorCode(err))
+			return
+		}
+	}
+	var subscription model.DigestSubscription
+	err = json.NewDecoder(request.Body).Decode(&subscription)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	subscription.ID = id
+	subscription.Owner = owner
+	token := util.GetAuthToken(request)
+	var code int
+	if _, err, code = resolvePermittedIds(request.Context(), ctrl, dr, token, subscription.IDs); err != nil {
+		http.Error(writer, err.Error(), code)
+		return
+	}
+	subscription.Members, err = resolveMembers(request.Context(), dr, token, subscription.IDs)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	subscription.Names, err, code = memberNames(request.Context(), dr, token, subscription.Members)
+	if err != nil {
+		http.Error(writer, err.Error(), code)
+		return
+	}
+	err = ctrl.SetDigestSubscription(request.Context(), subscription)
+	if err != nil {
+		http.Error(writer, err.Error(), digestErrorCode(err))
+		return
+	}
+	subscription, err = ctrl.GetDigestSubscription(request.Context(), owner, id)
+	if err != nil {
+		http.Error(writer, err.Error(), digestErrorCode(err))
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(writer).Encode(subscription)
+	if err != nil {
+		ctrl.Config().GetLogger().Error("unable to encode response", "error", err)
+	}
+}
+
+// memberNames maps the requested ids and their members to their names.
+func memberNames(ctx context.Context, dr deviceRepo.Interface, token string, members map[string][]string) (map[string]string, error, int) {
+	return controller.ResourceNames(devicerepo.WithContext(ctx, dr), token, members)
+}
+
+func digestErrorCode(err error) int {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, controller.ErrInvalidDigestSubscription):
+		return http.StatusBadRequest
+	case errors.Is(err, controller.ErrDigestsNotSupported), errors.Is(err, digest.ErrSmtpDisabled):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/api/util"
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PostQueryDowntimesMap godoc
+// @Summary Query downtimes
+// @Description Query offline intervals overlapping the selected time frame for multiple IDs (supported: devices, gateways/hubs).
+// @Description Intervals start with an offline state and end with the next online state, ongoing intervals have no end. Limit and offset are applied per ID.
+// @Tags Historical states
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param query body model.QueryDowntimes true "query object"
+// @Success	200 {object} map[string][]model.Downtime "offline intervals mapped to IDs"
+// @Failure	400 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Router /historical/query/downtimes [post]
+func PostQueryDowntimesMap(ctrl *controller.Controller, _ deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodPost, "/historical/query/downtimes", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		var query model.QueryDowntimes
+		err := json.NewDecoder(request.Body).Decode(&query)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err = controller.ValidateDowntimesQuery(query); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query.IDs, err = ctrl.PermissionsFilterIDs(request.Context(), util.GetAuthToken(request), query.IDs)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		res, err := ctrl.QueryDowntimesMap(request.Context(), query)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err = json.NewEncoder(writer).Encode(res); err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+func TestPostQueryDowntimesMap(t *testing.T) {
+	api := newTestApi(t)
+	seedHistory(api)
+	type interval struct {
+		start time.Duration
+		end   time.Duration
+	}
+	tests := []struct {
+		name     string
+		fields   map[string]any
+		expected map[string][]interval
+	}{
+		{
+			name: "default",
+			expected: map[string][]interval{
+				flappingId:   {{time.Hour, 3 * time.Hour}, {5 * time.Hour, 6 * time.Hour}},
+				recoveringId: {{2 * time.Hour, 10 * time.Hour}},
+			},
+		},
+		{
+			name:   "sorted by duration",
+			fields: map[string]any{"sort_by": model.DowntimeSortByDuration},
+			expected: map[string][]interval{
+				flappingId:   {{5 * time.Hour, 6 * time.Hour}, {time.Hour, 3 * time.Hour}},
+				recoveringId: {{2 * time.Hour, 10 * time.Hour}},
+			},
+		},
+		{
+			name:   "sorted descending with limit",
+			fields: map[string]any{"sort_desc": true, "limit": 1},
+			expected: map[string][]interval{
+				flappingId:   {{5 * time.Hour, 6 * time.Hour}},
+				recoveringId: {{2 * time.Hour, 10 * time.Hour}},
+			},
+		},
+		{
+			name:   "offset",
+			fields: map[string]any{"offset": 1},
+			expected: map[string][]interval{
+				flappingId:   {{5 * time.Hour, 6 * time.Hour}},
+				recoveringId: {},
+			},
+		},
+		{
+			name:   "min duration",
+			fields: map[string]any{"min_duration": "90m"},
+			expected: map[string][]interval{
+				flappingId:   {{time.Hour, 3 * time.Hour}},
+				recoveringId: {{2 * time.Hour, 10 * time.Hour}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := decode[map[string][]model.Downtime](t, serve(api.router, http.MethodPost, "/historical/query/downtimes", historyQuery([]string{flappingId, recoveringId}, test.fields)))
+			if len(result) != len(test.expected) {
+				t.Fatalf("\n%#v\n%#v", result, test.expected)
+			}
+			for id, intervals := range test.expected {
+				if len(result[id]) != len(intervals) {
+					t.Fatalf("%v:\n%#v\n%#v", id, result[id], intervals)
+				}
+				for i, expected := range intervals {
+					downtime := result[id][i]
+					if downtime.Ongoing || downtime.End == nil || !downtime.Start.Equal(t0.Add(expected.start)) || !downtime.End.Equal(t0.Add(expected.end)) || time.Duration(downtime.Duration) != expected.end-expected.start {
+						t.Errorf("%v %v:\n%#v\n%#v", id, i, downtime, expected)
+					}
+				}
+			}
+		})
+	}
+
+	resp := serve(api.router, http.MethodPost, "/historical/query/downtimes", historyQuery([]string{flappingId}, map[string]any{"sort_by": "unknown"}))
+	if resp.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status %v: %v", resp.Code, resp.Body.String())
+	}
+}
+
+func TestPostQueryDowntimesMapRecovery(t *testing.T) {
+	api := newTestApi(t)
+	at := func(offset time.Duration, connected bool) model.State {
+		return model.State{Time: t0.Add(offset), Connected: connected}
+	}
+	recovered := recoveringId + "-later"
+	ongoing := recoveringId + "-ongoing"
+	// the next states after the time frame are offline, recovered is online again at t0+11h
+	api.set(recovered, at(7*time.Hour, false), at(9*time.Hour, false), at(10*time.Hour, false), at(11*time.Hour, true), at(12*time.Hour, true))
+	api.set(ongoing, at(7*time.Hour, false), at(12*time.Hour, false))
+	result := decode[map[string][]model.Downtime](t, serve(api.router, http.MethodPost, "/historical/query/downtimes", historyQuery([]string{recovered, ongoing}, nil)))
+	if downtimes := result[recovered]; len(downtimes) != 1 || downtimes[0].Ongoing || downtimes[0].End == nil || !downtimes[0].End.Equal(t0.Add(11*time.Hour)) || time.Duration(downtimes[0].Duration) != 4*time.Hour {
+		t.Errorf("unexpected downtimes %#v", downtimes)
+	}
+	if downtimes := result[ongoing]; len(downtimes) != 1 || !downtimes[0].Ongoing || downtimes[0].End != nil || !downtimes[0].Start.Equal(t0.Add(7*time.Hour)) {
+		t.Errorf("unexpected downtimes %#v", downtimes)
+	}
+}
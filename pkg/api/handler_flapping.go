@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/api/util"
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PostQueryFlapping godoc
+// @Summary Query flapping connections
+// @Description Count transitions between online and offline within the selected time frame for multiple IDs (supported: devices, gateways/hubs). If no IDs are provided, all accessible devices and hubs will be queried.
+// @Description Without 'since' or 'range' the configured default range is used. The number of IDs is limited, also if all accessible resources are queried.
+// @Description Resources with more transitions per hour than the threshold are flagged, results are sorted by rate, worst offenders first.
+// @Tags Historical states
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param query body model.QueryFlapping true "query object"
+// @Success	200 {array} model.Flapping "transition counts"
+// @Failure	400 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Router /historical/query/flapping [post]
+func PostQueryFlapping(ctrl *controller.Controller, _ deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodPost, "/historical/query/flapping", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		var query model.QueryFlapping
+		err := json.NewDecoder(request.Body).Decode(&query)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if query.Threshold < 0 || query.Limit < 0 {
+			http.Error(writer, "threshold and limit must not be negative", http.StatusBadRequest)
+			return
+		}
+		token := util.GetAuthToken(request)
+		if len(query.IDs) == 0 {
+			deviceIds, err := ctrl.ListIds(request.Context(), token, model.PermDeviceKind)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			hubIds, err := ctrl.ListIds(request.Context(), token, model.PermGatewayKind)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			query.IDs = slices.Concat(deviceIds, hubIds)
+		} else {
+			query.IDs, err = ctrl.PermissionsFilterIDs(request.Context(), token, query.IDs)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		res, err := ctrl.QueryFlapping(request.Context(), query)
+		if errors.Is(err, controller.ErrTooManyIds) {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err = json.NewEncoder(writer).Encode(res); err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
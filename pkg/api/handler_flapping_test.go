@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+func TestPostQueryFlapping(t *testing.T) {
+	api := newTestApi(t)
+	seedHistory(api)
+	ids := []string{recoveringId, flappingId}
+	tests := []struct {
+		name     string
+		fields   map[string]any
+		expected []model.Flapping
+	}{
+		{
+			name:   "sorted by rate",
+			fields: map[string]any{"threshold": 0.25},
+			expected: []model.Flapping{
+				{ID: flappingId, Transitions: 4, Rate: 0.5, Flapping: true},
+				{ID: recoveringId, Transitions: 1, Rate: 0.125, Flapping: false},
+			},
+		},
+		{
+			name:     "only flapping",
+			fields:   map[string]any{"threshold": 0.25, "only_flapping": true},
+			expected: []model.Flapping{{ID: flappingId, Transitions: 4, Rate: 0.5, Flapping: true}},
+		},
+		{
+			name:     "limit",
+			fields:   map[string]any{"threshold": 1, "limit": 1},
+			expected: []model.Flapping{{ID: flappingId, Transitions: 4, Rate: 0.5, Flapping: false}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := decode[[]model.Flapping](t, serve(api.router, http.MethodPost, "/historical/query/flapping", historyQuery(ids, test.fields)))
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("\n%#v\n%#v", result, test.expected)
+			}
+		})
+	}
+
+	resp := serve(api.router, http.MethodPost, "/historical/query/flapping", historyQuery(ids, map[string]any{"threshold": -1}))
+	if resp.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status %v: %v", resp.Code, resp.Body.String())
+	}
+}
@@ -0,0 +1,314 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/api/util"
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/devicerepo"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/SENERGY-Platform/service-commons/pkg/jwt"
+	golangjwt "github.com/golang-jwt/jwt"
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	fleetStateOnline  = "online"
+	fleetStateOffline = "offline"
+	fleetStateUnknown = "unknown"
+)
+
+var fleetStates = []string{fleetStateOnline, fleetStateOffline, fleetStateUnknown}
+
+// GetFleetMetrics godoc
+// @Summary Fleet connection state metrics
+// @Description Prometheus metrics with the number of online, offline and unknown devices per location, device-group and hub, and the totals of devices and hubs.
+// @Description The metrics cover all resources the token has access to. Only the configured service user (FleetExporterServiceUser) may scrape, the endpoint is disabled if it or AuthEndpoint is not set.
+// @Description The signature and expiration of the token are verified on each scrape with the certs of the keycloak realm at AuthEndpoint.
+// @Description Locations, device-groups and hubs are cached for FleetExporterCacheDuration, the connection states are read on each scrape.
+// @Description If FleetExporterDeviceStates is set, a connection_state gauge (1 online, 0 offline) is added for each device and hub.
+// @Tags Metrics
+// @Produce	plain
+// @Security Bearer
+// @Success	200 {string} string "metrics in the prometheus text format"
+// @Failure	401 {string} string "error message"
+// @Failure	403 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Router /metrics/fleet [get]
+func GetFleetMetrics(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	cache := &fleetCache{}
+	certs := &jwt.KeycloakCertProvider{CertUrl: ctrl.Config().AuthEndpoint + "/auth/realms/master/protocol/openid-connect/certs"}
+	return http.MethodGet, "/metrics/fleet", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		serviceUser := ctrl.Config().FleetExporterServiceUser
+		if serviceUser == "" || ctrl.Config().AuthEndpoint == "" || ctrl.Config().AuthEndpoint == "-" {
+			http.Error(writer, "fleet metrics are disabled", http.StatusForbidden)
+			return
+		}
+		userId, err := verifyScrapeToken(certs, util.GetAuthToken(request))
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if userId != serviceUser {
+			http.Error(writer, "fleet metrics are only available to the configured service user", http.StatusForbidden)
+			return
+		}
+		cacheDuration := time.Duration(0)
+		if value := ctrl.Config().FleetExporterCacheDuration; value != "" && value != "-" {
+			cacheDuration, err = time.ParseDuration(value)
+			if err != nil {
+				http.Error(writer, fmt.Sprintf("invalid fleet exporter cache duration '%s'", value), http.StatusInternalServerError)
+				return
+			}
+		}
+		fleet, err, code := cache.get(cacheDuration, func() (fleetTopology, error, int) {
+			return readFleetTopology(request.Context(), ctrl, dr, util.GetAuthToken(request))
+		})
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		registry, err, code := collectFleetMetrics(request.Context(), ctrl, fleet, ctrl.Config().FleetExporterDeviceStates)
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(writer, request)
+	}
+}
+
+// verifyScrapeToken returns the subject of token after checking its signature with the keycloak certs and its expiration.
+// The token is verified on each scrape, cached topologies are only returned to the service user.
+func verifyScrapeToken(certs *jwt.KeycloakCertProvider, token string) (string, error) {
+	if len(token) > 7 && strings.ToLower(token[:7]) == "bearer " {
+		token = token[7:]
+	}
+	claims := &golangjwt.StandardClaims{}
+	parser := &golangjwt.Parser{ValidMethods: []string{"RS256", "RS384", "RS512"}}
+	if _, err := parser.ParseWithClaims(token, claims, certs.GetKeycloakCert); err != nil {
+		return "", fmt.Errorf("invalid auth token: %w", err)
+	}
+	if claims.ExpiresAt == 0 || claims.Subject == "" {
+		return "", errors.New("invalid auth token: missing expiration or subject")
+	}
+	return claims.Subject, nil
+}
+
+// fleetTopology contains the locations, device-groups and hubs accessible with the token of the service user.
+type fleetTopology struct {
+	accessible      map[string][]string // permission kind -> ids
+	groups          map[string]models.DeviceGroup
+	locations       []models.Location
+	locationDevices map[string][]string // location id -> device ids, including the devices of the device-groups
+	hubs            []models.Hub
+}
+
+// fleetCache keeps the topology between scrapes, the device-repository is read at most once per cache duration.
+type fleetCache struct {
+	mux     sync.Mutex
+	fleet   fleetTopology
+	expires time.Time
+}
+
+// get returns the cached topology or reads it if the cache is expired, a duration of 0 disables the cache.
+func (this *fleetCache) get(duration time.Duration, read func() (fleetTopology, error, int)) (fleetTopology, error, int) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if duration > 0 && time.Now().Before(this.expires) {
+		return this.fleet, nil, http.StatusOK
+	}
+	fleet, err, code := read()
+	if err != nil {
+		return fleet, err, code
+	}
+	this.fleet = fleet
+	this.expires = time.Now().Add(duration)
+	return fleet, nil, http.StatusOK
+}
+
+// readFleetTopology reads the locations, device-groups and hubs accessible with token, each device-group is read once.
+func readFleetTopology(ctx context.Context, ctrl *controller.Controller, dr deviceRepo.Interface, token string) (fleetTopology, error, int) {
+	dr = devicerepo.WithContext(ctx, dr)
+	accessible := map[string][]string{}
+	for _, kind := range []string{model.PermDeviceKind, model.PermGatewayKind, model.PermDeviceGroupKind, model.PermLocationsKind} {
+		ids, err := ctrl.ListIds(ctx, token, kind)
+		if err != nil {
+			return fleetTopology{}, err, http.StatusInternalServerError
+		}
+		accessible[kind] = ids
+	}
+
+	groups := map[string]models.DeviceGroup{}
+	readGroup := func(id string) (models.DeviceGroup, error, int) {
+		if group, ok := groups[id]; ok {
+			return group, nil, http.StatusOK
+		}
+		group, err, code := dr.ReadDeviceGroup(id, token, false)
+		if err != nil {
+			return group, err, code
+		}
+		groups[id] = group
+		return group, nil, http.StatusOK
+	}
+	for _, id := range accessible[model.PermDeviceGroupKind] {
+		if _, err, code := readGroup(id); err != nil {
+			return fleetTopology{}, err, code
+		}
+	}
+	locations := []models.Location{}
+	locationDevices := map[string][]string{}
+	for _, id := range accessible[model.PermLocationsKind] {
+		location, err, code := dr.GetLocation(id, token)
+		if err != nil {
+			return fleetTopology{}, err, code
+		}
+		deviceIds := slices.Clone(location.DeviceIds)
+		for _, groupId := range location.DeviceGroupIds {
+			group, err, code := readGroup(groupId)
+			if err != nil {
+				return fleetTopology{}, err, code
+			}
+			deviceIds = append(deviceIds, group.DeviceIds...)
+		}
+		slices.Sort(deviceIds)
+		locationDevices[id] = slices.Compact(deviceIds)
+		locations = append(locations, location)
+	}
+	hubs := []models.Hub{}
+	if len(accessible[model.PermGatewayKind]) > 0 {
+		var err error
+		var code int
+		hubs, err, code = controller.ListHubs(dr, token, accessible[model.PermGatewayKind])
+		if err != nil {
+			return fleetTopology{}, err, code
+		}
+	}
+	return fleetTopology{
+		accessible:      accessible,
+		groups:          groups,
+		locations:       locations,
+		locationDevices: locationDevices,
+		hubs:            hubs,
+	}, nil, http.StatusOK
+}
+
+// collectFleetMetrics counts the current states of the devices of fleet.
+func collectFleetMetrics(ctx context.Context, ctrl *controller.Controller, fleet fleetTopology, deviceStates bool) (*prometheus.Registry, error, int) {
+	accessible, groups, locations, locationDevices, hubs := fleet.accessible, fleet.groups, fleet.locations, fleet.locationDevices, fleet.hubs
+
+	ids := slices.Clone(accessible[model.PermDeviceKind])
+	ids = append(ids, accessible[model.PermGatewayKind]...)
+	for _, group := range groups {
+		ids = append(ids, group.DeviceIds...)
+	}
+	for _, deviceIds := range locationDevices {
+		ids = append(ids, deviceIds...)
+	}
+	for _, hub := range hubs {
+		ids = append(ids, hub.DeviceIds...)
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	states, err := ctrl.QueryBaseStatesMap(ctx, model.QueryBase{IDs: ids})
+	if err != nil {
+		return nil, err, http.StatusInternalServerError
+	}
+	count := func(ids []string) map[string]float64 {
+		result := map[string]float64{}
+		for _, id := range ids {
+			connected, ok := states[id]
+			switch {
+			case !ok:
+				result[fleetStateUnknown]++
+			case connected:
+				result[fleetStateOnline]++
+			default:
+				result[fleetStateOffline]++
+			}
+		}
+		return result
+	}
+
+	registry := prometheus.NewRegistry()
+	devicesTotal := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "connection_devices",
+		Help: "Number of devices per connection state.",
+	}, []string{"state"})
+	hubsTotal := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "connection_hubs",
+		Help: "Number of hubs per connection state.",
+	}, []string{"state"})
+	locationGauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "connection_location_devices",
+		Help: "Number of devices per location and connection state, including the devices of the device-groups of the location.",
+	}, []string{"location", "name", "state"})
+	groupGauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "connection_device_group_devices",
+		Help: "Number of devices per device-group and connection state.",
+	}, []string{"device_group", "name", "state"})
+	hubGauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "connection_hub_devices",
+		Help: "Number of devices per hub and connection state.",
+	}, []string{"hub", "name", "state"})
+	registry.MustRegister(devicesTotal, hubsTotal, locationGauge, groupGauge, hubGauge)
+
+	deviceCounts := count(accessible[model.PermDeviceKind])
+	hubCounts := count(accessible[model.PermGatewayKind])
+	for _, state := range fleetStates {
+		devicesTotal.WithLabelValues(state).Set(deviceCounts[state])
+		hubsTotal.WithLabelValues(state).Set(hubCounts[state])
+	}
+	for _, location := range locations {
+		counts := count(locationDevices[location.Id])
+		for _, state := range fleetStates {
+			locationGauge.WithLabelValues(location.Id, location.Name, state).Set(counts[state])
+		}
+	}
+	for _, id := range accessible[model.PermDeviceGroupKind] {
+		group := groups[id]
+		counts := count(group.DeviceIds)
+		for _, state := range fleetStates {
+			groupGauge.WithLabelValues(group.Id, group.Name, state).Set(counts[state])
+		}
+	}
+	for _, hub := range hubs {
+		counts := count(hub.DeviceIds)
+		for _, state := range fleetStates {
+			hubGauge.WithLabelValues(hub.Id, controller.Nickname(hub.Name, hub.Attributes), state).Set(counts[state])
+		}
+	}
+
+	if deviceStates {
+		stateGauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "connection_state",
+			Help: "Connection state of a device or hub, 1 if online and 0 if offline. Resources without known state are omitted.",
+		}, []string{"id", "kind"})
+		registry.MustRegister(stateGauge)
+		for kind, kindIds := range map[string][]string{model.DeviceKind: accessible[model.PermDeviceKind], model.GatewayKind: accessible[model.PermGatewayKind]} {
+			for _, id := range kindIds {
+				if connected, ok := states[id]; ok {
+					stateGauge.WithLabelValues(id, kind).Set(boolToFloat(connected))
+				}
+			}
+		}
+	}
+	return registry, nil, http.StatusOK
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
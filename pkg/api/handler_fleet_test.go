@@ -0,0 +1,130 @@
+package api
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+const testKid = "test-key"
+
+// testKeycloak serves the cert of key as the certs of the master realm.
+func testKeycloak(t *testing.T, key *rsa.PrivateKey) string {
+	t.Helper()
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour)}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/auth/realms/master/protocol/openid-connect/certs" {
+			http.NotFound(writer, request)
+			return
+		}
+		_ = json.NewEncoder(writer).Encode(map[string]any{"keys": []map[string]any{{
+			"kid": testKid,
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"x5c": []string{base64.StdEncoding.EncodeToString(cert)},
+		}}})
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// signedToken returns a RS256 token of subject signed with key, expiring at expires.
+func signedToken(t *testing.T, key *rsa.PrivateKey, subject string, expires time.Time) string {
+	t.Helper()
+	encode := func(value any) string {
+		b, _ := json.Marshal(value)
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	content := encode(map[string]any{"alg": "RS256", "typ": "JWT", "kid": testKid}) + "." + encode(map[string]any{"sub": subject, "exp": expires.Unix()})
+	hash := sha256.Sum256([]byte(content))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + content + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestGetFleetMetricsAuth(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forgeryKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keycloak := testKeycloak(t, key)
+	api := newTestApi(t, func(config *configuration.Config) {
+		config.AuthEndpoint = keycloak
+		config.FleetExporterServiceUser = "scraper"
+		config.FleetExporterCacheDuration = "1h"
+	})
+	api.set(testDeviceId, model.State{Time: t0, Connected: true})
+	scrape := func(token string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/metrics/fleet", nil)
+		request.Header.Set("Authorization", token)
+		recorder := httptest.NewRecorder()
+		api.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	resp := scrape(signedToken(t, key, "scraper", time.Now().Add(time.Hour)))
+	if resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), `connection_devices{state="online"} 1`) {
+		t.Fatalf("unexpected response %v %v", resp.Code, resp.Body.String())
+	}
+
+	// the topology is cached, the token is still verified
+	unsigned := strings.Split(signedToken(t, key, "scraper", time.Now().Add(time.Hour)), ".")
+	tests := map[string]struct {
+		token string
+		code  int
+	}{
+		"forged":      {token: signedToken(t, forgeryKey, "scraper", time.Now().Add(time.Hour)), code: http.StatusUnauthorized},
+		"expired":     {token: signedToken(t, key, "scraper", time.Now().Add(-time.Minute)), code: http.StatusUnauthorized},
+		"unsigned":    {token: unsigned[0] + "." + unsigned[1] + ".", code: http.StatusUnauthorized},
+		"missing":     {token: "", code: http.StatusUnauthorized},
+		"other user":  {token: signedToken(t, key, "other", time.Now().Add(time.Hour)), code: http.StatusForbidden},
+		"valid again": {token: signedToken(t, key, "scraper", time.Now().Add(time.Hour)), code: http.StatusOK},
+	}
+	for name, test := range tests {
+		if resp := scrape(test.token); resp.Code != test.code {
+			t.Errorf("%v: expected %v, got %v %v", name, test.code, resp.Code, resp.Body.String())
+		}
+	}
+}
+
+func TestGetFleetMetricsDisabled(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := newTestApi(t, func(config *configuration.Config) {
+		config.AuthEndpoint = "-"
+		config.FleetExporterServiceUser = "scraper"
+	})
+	request := httptest.NewRequest(http.MethodGet, "/metrics/fleet", nil)
+	request.Header.Set("Authorization", signedToken(t, key, "scraper", time.Now().Add(time.Hour)))
+	resp := httptest.NewRecorder()
+	api.router.ServeHTTP(resp, request)
+	if resp.Code != http.StatusForbidden {
+		t.Errorf("unexpected response %v %v", resp.Code, resp.Body.String())
+	}
+}
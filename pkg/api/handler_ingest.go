@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/api/util"
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PostIngestEvents godoc
+// @Summary Ingest connection events
+// @Description Store connection state changes of devices and gateways. Events with an already known id and time are ignored, events older than the current state are only added to the history.
+// @Description Requires write permission for every resource, the result list contains the outcome of each event in request order.
+// @Tags Ingest
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param events body []model.ConnectionEvent true "connection events, time is required"
+// @Success	200 {array} model.IngestResult "results"
+// @Failure	400 {string} string "error message"
+// @Failure	401 {string} string "error message"
+// @Failure	413 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /ingest/events [post]
+func PostIngestEvents(ctrl *controller.Controller, _ deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodPost, "/ingest/events", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		token := util.GetAuthToken(request)
+		if token == "" {
+			http.Error(writer, "missing auth token", http.StatusUnauthorized)
+			return
+		}
+		if limit := ctrl.Config().IngestMaxBodySize; limit > 0 {
+			request.Body = http.MaxBytesReader(writer, request.Body, limit)
+		}
+		var events []model.ConnectionEvent
+		err := json.NewDecoder(request.Body).Decode(&events)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(writer, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err := ctrl.IngestEvents(request.Context(), token, events)
+		if errors.Is(err, controller.ErrReadOnlyStore) {
+			http.Error(writer, err.Error(), http.StatusNotImplemented)
+			return
+		}
+		if errors.Is(err, controller.ErrBatchTooLarge) {
+			http.Error(writer, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err = json.NewEncoder(writer).Encode(res); err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
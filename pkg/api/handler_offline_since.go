@@ -0,0 +1,243 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/api/util"
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/devicerepo"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/julienschmidt/httprouter"
+)
+
+// OfflineSinceHubs godoc
+// @Summary Query offline timestamps of hubs
+// @Description Query offline timestamps of hubs with multiple IDs. If no IDs are provided, all accessible hub IDs will be queried.
+// @Tags Offline List
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param query body model.QueryOfflineSince true "query object, the attribute blacklist is not used for hubs"
+// @Param include-names query boolean false "include hub names in the response"
+// @Success	200 {array} model.OfflineSinceResponse "offline timestamps by hub IDs"
+// @Header	200 {integer} X-Total-Count "number of results without limit and offset"
+// @Failure	400 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Router /offline-since/hubs [post]
+func OfflineSinceHubs(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodPost, "/offline-since/hubs", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		var query model.QueryOfflineSince
+		err := json.NewDecoder(request.Body).Decode(&query)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err = validateOfflineSinceOptions(query.OfflineSinceOptions); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		token := util.GetAuthToken(request)
+		if len(query.IDs) == 0 {
+			query.IDs, err = ctrl.ListIds(request.Context(), token, model.PermGatewayKind)
+		} else {
+			for _, id := range query.IDs {
+				if !strings.HasPrefix(id, models.HUB_PREFIX) {
+					http.Error(writer, "hubs endpoint only handles hubs", http.StatusBadRequest)
+					return
+				}
+			}
+			query.IDs, err = ctrl.PermissionsFilterIDs(request.Context(), token, query.IDs)
+		}
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		states, err := ctrl.GetOfflineSince(request.Context(), query.IDs, model.GatewayKind, query.OfflineSinceFilter)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		states, total, err, code := pageOfflineSince(request.Context(), dr, token, states, query.OfflineSinceOptions, request.URL.Query().Get("include-names") == "true")
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writer.Header().Set("X-Total-Count", strconv.Itoa(total))
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err = json.NewEncoder(writer).Encode(states); err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// OfflineSince godoc
+// @Summary Query offline timestamps of devices and hubs
+// @Description Query offline timestamps of devices and hubs in one list sorted by offline timestamp (supported: devices, hubs, device-groups, locations). If no IDs are provided, all accessible device and hub IDs will be queried. Device-groups and locations will be resolved to their device IDs.
+// @Tags Offline List
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param query body model.QueryOfflineSince true "query object, the attribute blacklist is only applied to devices"
+// @Param include-names query boolean false "include device and hub names in the response"
+// @Success	200 {array} model.OfflineSinceResponse "offline timestamps by device and hub IDs"
+// @Header	200 {integer} X-Total-Count "number of results without limit and offset"
+// @Failure	400 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Router /offline-since [post]
+func OfflineSince(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodPost, "/offline-since", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		var query model.QueryOfflineSince
+		err := json.NewDecoder(request.Body).Decode(&query)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err = validateOfflineSinceOptions(query.OfflineSinceOptions); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		token := util.GetAuthToken(request)
+		if len(query.IDs) == 0 {
+			deviceIds, err := ctrl.ListIds(request.Context(), token, model.PermDeviceKind)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			hubIds, err := ctrl.ListIds(request.Context(), token, model.PermGatewayKind)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			query.IDs = slices.Concat(deviceIds, hubIds)
+		} else {
+			query.IDs, err = ctrl.PermissionsFilterIDs(request.Context(), token, query.IDs)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			query.IDs, _, err = resolveDeviceIds(request.Context(), dr, token, query.IDs)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		query.IDs, err = filterDevices(request.Context(), dr, token, query.IDs, query.DeviceAttributeBlacklist)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		states, err := ctrl.GetOfflineSinceMixed(request.Context(), query.IDs, query.OfflineSinceFilter)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		states, total, err, code := pageOfflineSince(request.Context(), dr, token, states, query.OfflineSinceOptions, request.URL.Query().Get("include-names") == "true")
+		if err != nil {
+			http.Error(writer, err.Error(), code)
+			return
+		}
+		writer.Header().Set("X-Total-Count", strconv.Itoa(total))
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err = json.NewEncoder(writer).Encode(states); err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func validateOfflineSinceOptions(options model.OfflineSinceOptions) error {
+	switch options.SortBy {
+	case "", model.OfflineSinceSortByTime, model.OfflineSinceSortByName, model.OfflineSinceSortByID:
+	default:
+		return fmt.Errorf("invalid sort_by '%s'", options.SortBy)
+	}
+	if options.Limit < 0 || options.Offset < 0 || options.MinOfflineDuration < 0 || options.MaxOfflineDuration < 0 {
+		return errors.New("limit, offset and durations must not be negative")
+	}
+	return nil
+}
+
+// pageOfflineSince sorts states and selects the requested page, total is the number of states before pagination.
+// Names are loaded for all states if sorted by name, otherwise only for the page if includeNames is set.
+func pageOfflineSince(ctx context.Context, dr deviceRepo.Interface, token string, states []model.OfflineSinceResponse, options model.OfflineSinceOptions, includeNames bool) (page []model.OfflineSinceResponse, total int, err error, code int) {
+	sortByName := options.SortBy == model.OfflineSinceSortByName
+	if sortByName {
+		if err, code = addNames(ctx, dr, token, states); err != nil {
+			return nil, 0, err, code
+		}
+	}
+	slices.SortStableFunc(states, func(a, b model.OfflineSinceResponse) int {
+		var c int
+		switch options.SortBy {
+		case model.OfflineSinceSortByName:
+			c = cmp.Or(strings.Compare(a.Name, b.Name), strings.Compare(a.ID, b.ID))
+		case model.OfflineSinceSortByID:
+			c = strings.Compare(a.ID, b.ID)
+		default:
+			c = a.OfflineSince.Compare(b.OfflineSince)
+		}
+		if options.SortDesc {
+			return -c
+		}
+		return c
+	})
+	total = len(states)
+	page = states[min(options.Offset, total):]
+	if options.Limit > 0 {
+		page = page[:min(options.Limit, len(page))]
+	}
+	if includeNames && !sortByName {
+		if err, code = addNames(ctx, dr, token, page); err != nil {
+			return nil, 0, err, code
+		}
+	}
+	if !includeNames && sortByName {
+		for i := range page {
+			page[i].Name = ""
+		}
+	}
+	return page, total, nil, http.StatusOK
+}
+
+// addNames sets the name of device and hub states to the nickname or the name of the resource.
+func addNames(ctx context.Context, dr deviceRepo.Interface, token string, states []model.OfflineSinceResponse) (error, int) {
+	ids := []string{}
+	for _, state := range states {
+		ids = append(ids, state.ID)
+	}
+	names, err, code := deviceNames(ctx, dr, token, ids)
+	if err != nil {
+		return err, code
+	}
+	hubs, err, code := hubNames(ctx, dr, token, ids)
+	if err != nil {
+		return err, code
+	}
+	maps.Copy(names, hubs)
+	for i, state := range states {
+		if name, ok := names[state.ID]; ok {
+			states[i].Name = name
+		}
+	}
+	return nil, http.StatusOK
+}
+
+func deviceNames(ctx context.Context, dr deviceRepo.Interface, token string, ids []string) (map[string]string, error, int) {
+	return controller.DeviceNames(devicerepo.WithContext(ctx, dr), token, ids)
+}
+
+func hubNames(ctx context.Context, dr deviceRepo.Interface, token string, ids []string) (map[string]string, error, int) {
+	return controller.HubNames(devicerepo.WithContext(ctx, dr), token, ids)
+}
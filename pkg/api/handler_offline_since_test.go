@@ -0,0 +1,191 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/models/go/models"
+)
+
+func TestOfflineSince(t *testing.T) {
+	api := newTestApi(t)
+	now := time.Now()
+	d1, d2, d3, d4 := models.DEVICE_PREFIX+"1", models.DEVICE_PREFIX+"2", models.DEVICE_PREFIX+"3", models.DEVICE_PREFIX+"4"
+	h1 := models.HUB_PREFIX + "1"
+	api.set(d1, model.State{Time: now.Add(-5 * time.Hour), Connected: true}, model.State{Time: now.Add(-3 * time.Hour), Connected: false})
+	api.set(d2, model.State{Time: now.Add(-time.Hour), Connected: false})
+	api.set(d3, model.State{Time: now.Add(-2 * time.Hour), Connected: false}, model.State{Time: now.Add(-time.Hour), Connected: true})
+	api.set(d4, model.State{Time: now.Add(-30 * time.Hour), Connected: false})
+	api.set(h1, model.State{Time: now.Add(-2 * time.Hour), Connected: false})
+	api.deviceRepo.names = map[string]string{d1: "Charlie", d2: "Alpha", d4: "Bravo", h1: "Delta"}
+	api.permissions.accessible = map[string][]string{
+		model.PermDeviceKind:  {d1, d2, d3, d4},
+		model.PermGatewayKind: {h1},
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		query         map[string]any
+		expected      []string
+		expectedTotal int
+		expectedNames bool
+	}{
+		{
+			name:          "sorted by offline timestamp",
+			path:          "/offline-since/devices",
+			query:         map[string]any{"ids": []string{d1, d2, d3, d4}},
+			expected:      []string{d4, d1, d2},
+			expectedTotal: 3,
+		},
+		{
+			name:          "sorted descending",
+			path:          "/offline-since/devices",
+			query:         map[string]any{"ids": []string{d1, d2, d3, d4}, "sort_desc": true},
+			expected:      []string{d2, d1, d4},
+			expectedTotal: 3,
+		},
+		{
+			name:          "sorted by id",
+			path:          "/offline-since/devices",
+			query:         map[string]any{"ids": []string{d4, d2, d1}, "sort_by": model.OfflineSinceSortByID},
+			expected:      []string{d1, d2, d4},
+			expectedTotal: 3,
+		},
+		{
+			name:          "sorted by name",
+			path:          "/offline-since/devices",
+			query:         map[string]any{"ids": []string{d1, d2, d4}, "sort_by": model.OfflineSinceSortByName},
+			expected:      []string{d2, d4, d1},
+			expectedTotal: 3,
+		},
+		{
+			name:          "sorted by name with names",
+			path:          "/offline-since/devices?include-names=true",
+			query:         map[string]any{"ids": []string{d1, d2, d4}, "sort_by": model.OfflineSinceSortByName, "sort_desc": true, "limit": 2},
+			expected:      []string{d1, d4},
+			expectedTotal: 3,
+			expectedNames: true,
+		},
+		{
+			name:          "page",
+			path:          "/offline-since/devices",
+			query:         map[string]any{"ids": []string{d1, d2, d3, d4}, "limit": 1, "offset": 1},
+			expected:      []string{d1},
+			expectedTotal: 3,
+		},
+		{
+			name:          "offset after last result",
+			path:          "/offline-since/devices",
+			query:         map[string]any{"ids": []string{d1, d2, d3, d4}, "offset": 5},
+			expected:      []string{},
+			expectedTotal: 3,
+		},
+		{
+			name:          "min offline duration",
+			path:          "/offline-since/devices",
+			query:         map[string]any{"ids": []string{d1, d2, d3, d4}, "min_offline_duration": "2h"},
+			expected:      []string{d4, d1},
+			expectedTotal: 2,
+		},
+		{
+			name:          "max offline duration",
+			path:          "/offline-since/devices",
+			query:         map[string]any{"ids": []string{d1, d2, d3, d4}, "max_offline_duration": "24h"},
+			expected:      []string{d1, d2},
+			expectedTotal: 2,
+		},
+		{
+			name:          "accessible devices",
+			path:          "/offline-since/devices",
+			query:         map[string]any{},
+			expected:      []string{d4, d1, d2},
+			expectedTotal: 3,
+		},
+		{
+			name:          "hubs",
+			path:          "/offline-since/hubs",
+			query:         map[string]any{"ids": []string{h1}},
+			expected:      []string{h1},
+			expectedTotal: 1,
+		},
+		{
+			name:          "devices and hubs",
+			path:          "/offline-since",
+			query:         map[string]any{"ids": []string{d2, h1, d1}},
+			expected:      []string{d1, h1, d2},
+			expectedTotal: 3,
+		},
+		{
+			name:          "accessible devices and hubs with limit",
+			path:          "/offline-since",
+			query:         map[string]any{"limit": 2, "sort_desc": true},
+			expected:      []string{d2, h1},
+			expectedTotal: 4,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := serve(api.router, http.MethodPost, test.path, test.query)
+			result := decode[[]model.OfflineSinceResponse](t, resp)
+			ids := []string{}
+			for _, item := range result {
+				ids = append(ids, item.ID)
+				if (item.Name != "") != test.expectedNames {
+					t.Errorf("unexpected name %q of %v", item.Name, item.ID)
+				}
+			}
+			if !slices.Equal(ids, test.expected) {
+				t.Errorf("\n%v\n%v", ids, test.expected)
+			}
+			if total := resp.Header().Get("X-Total-Count"); total != strconv.Itoa(test.expectedTotal) {
+				t.Errorf("unexpected total %v", total)
+			}
+		})
+	}
+
+	t.Run("include names", func(t *testing.T) {
+		result := decode[[]model.OfflineSinceResponse](t, serve(api.router, http.MethodPost, "/offline-since?include-names=true", map[string]any{"ids": []string{d2, h1}}))
+		names := []string{}
+		for _, item := range result {
+			names = append(names, item.Name)
+		}
+		if !slices.Equal(names, []string{"Delta", "Alpha"}) {
+			t.Errorf("unexpected names %v", names)
+		}
+	})
+
+	for _, query := range []map[string]any{{"sort_by": "unknown"}, {"limit": -1}, {"offset": -1}, {"min_offline_duration": "-1h"}} {
+		resp := serve(api.router, http.MethodPost, "/offline-since/devices", query)
+		if resp.Code != http.StatusBadRequest {
+			t.Errorf("%v: unexpected status %v: %v", query, resp.Code, resp.Body.String())
+		}
+	}
+}
+
+func TestOfflineSinceSortByNameChunks(t *testing.T) {
+	api := newTestApi(t)
+	now := time.Now()
+	ids := []string{}
+	for i := range 250 {
+		id := fmt.Sprintf("%v%03d", models.DEVICE_PREFIX, i)
+		ids = append(ids, id)
+		api.set(id, model.State{Time: now.Add(-time.Hour), Connected: false})
+		api.deviceRepo.names[id] = fmt.Sprintf("name %03d", 249-i)
+	}
+	api.permissions.accessible = map[string][]string{model.PermDeviceKind: ids}
+
+	result := decode[[]model.OfflineSinceResponse](t, serve(api.router, http.MethodPost, "/offline-since/devices", map[string]any{"ids": ids, "sort_by": model.OfflineSinceSortByName, "limit": 2}))
+	if len(result) != 2 || result[0].ID != ids[249] || result[1].ID != ids[248] {
+		t.Errorf("unexpected result %#v", result)
+	}
+	if api.deviceRepo.maxIds > controller.ListChunkSize {
+		t.Errorf("expected at most %v ids per request, got %v", controller.ListChunkSize, api.deviceRepo.maxIds)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/SENERGY-Platform/connection-log/pkg/api/util"
 	"github.com/SENERGY-Platform/connection-log/pkg/controller"
 	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
 	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
 	_ "github.com/influxdata/influxdb1-client/v2"
 	"github.com/julienschmidt/httprouter"
@@ -43,7 +44,7 @@ func PostCheckDeviceOnlineStates(ctrl *controller.Controller, _ deviceRepo.Inter
 				return
 			}
 		}
-		ok, err := ctrl.CheckRightList(util.GetAuthToken(r), ids, "r")
+		ok, err := ctrl.CheckRightList(r.Context(), util.GetAuthToken(r), ids, "r")
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to check device rights", "error", err, "ids", ids, "right", "r")
 			http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -53,7 +54,7 @@ func PostCheckDeviceOnlineStates(ctrl *controller.Controller, _ deviceRepo.Inter
 			http.Error(res, "access denied", http.StatusUnauthorized)
 			return
 		}
-		result, err := ctrl.CheckDeviceOnlineStates(ids)
+		result, err := ctrl.CheckDeviceOnlineStates(r.Context(), ids)
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to check device online states", "error", err, "ids", ids)
 			http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -94,7 +95,7 @@ func PostInternCheckDeviceOnlineStates(ctrl *controller.Controller, _ deviceRepo
 				return
 			}
 		}
-		ok, err := ctrl.CheckRightList(util.GetAuthToken(r), ids, "r")
+		ok, err := ctrl.CheckRightList(r.Context(), util.GetAuthToken(r), ids, "r")
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to check device rights", "error", err, "ids", ids, "right", "r")
 			http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -104,7 +105,7 @@ func PostInternCheckDeviceOnlineStates(ctrl *controller.Controller, _ deviceRepo
 			http.Error(res, "access denied", http.StatusUnauthorized)
 			return
 		}
-		result, err := ctrl.CheckDeviceOnlineStates(ids)
+		result, err := ctrl.CheckDeviceOnlineStates(r.Context(), ids)
 		if err != nil {
 			http.Error(res, err.Error(), http.StatusInternalServerError)
 			return
@@ -145,7 +146,7 @@ func PostInternCheckGatewayOnlineStates(ctrl *controller.Controller, _ deviceRep
 				return
 			}
 		}
-		ok, err := ctrl.CheckRightList(util.GetAuthToken(r), ids, "r")
+		ok, err := ctrl.CheckRightList(r.Context(), util.GetAuthToken(r), ids, "r")
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to check hub rights", "error", err, "ids", ids, "right", "r")
 			http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -155,7 +156,7 @@ func PostInternCheckGatewayOnlineStates(ctrl *controller.Controller, _ deviceRep
 			http.Error(res, "access denied", http.StatusUnauthorized)
 			return
 		}
-		result, err := ctrl.CheckGatewayOnlineStates(ids)
+		result, err := ctrl.CheckGatewayOnlineStates(r.Context(), ids)
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to check gateway online states", "error", err, "ids", ids)
 			http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -182,6 +183,10 @@ func PostInternGetDevicesHistory(ctrl *controller.Controller, _ deviceRepo.Inter
 	return http.MethodPost, "/intern/history/device/:duration", func(res http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		ids := []string{}
 		duration := ps.ByName("duration")
+		if _, err := store.ParseInfluxDuration(duration); err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
 		err := json.NewDecoder(r.Body).Decode(&ids)
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to decode body", "error", err)
@@ -199,7 +204,7 @@ func PostInternGetDevicesHistory(ctrl *controller.Controller, _ deviceRepo.Inter
 				return
 			}
 		}
-		ok, err := ctrl.CheckRightList(util.GetAuthToken(r), ids, "r")
+		ok, err := ctrl.CheckRightList(r.Context(), util.GetAuthToken(r), ids, "r")
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to check device rights", "error", err, "ids", ids, "right", "r")
 			http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -209,7 +214,7 @@ func PostInternGetDevicesHistory(ctrl *controller.Controller, _ deviceRepo.Inter
 			http.Error(res, "access denied", http.StatusUnauthorized)
 			return
 		}
-		result, err := ctrl.GetResourcesHistory(ids, "device", duration)
+		result, err := ctrl.GetResourcesHistory(r.Context(), ids, "device", duration)
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to get devices history", "error", err, "ids", ids, "duration", duration)
 			http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -236,6 +241,10 @@ func PostInternGetGatewaysHistory(ctrl *controller.Controller, _ deviceRepo.Inte
 	return http.MethodPost, "/intern/history/gateway/:duration", func(res http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		ids := []string{}
 		duration := ps.ByName("duration")
+		if _, err := store.ParseInfluxDuration(duration); err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
 		err := json.NewDecoder(r.Body).Decode(&ids)
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to decode body", "error", err)
@@ -253,7 +262,7 @@ func PostInternGetGatewaysHistory(ctrl *controller.Controller, _ deviceRepo.Inte
 				return
 			}
 		}
-		ok, err := ctrl.CheckRightList(util.GetAuthToken(r), ids, "r")
+		ok, err := ctrl.CheckRightList(r.Context(), util.GetAuthToken(r), ids, "r")
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to check hub rights", "error", err, "ids", ids, "right", "r")
 			http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -263,7 +272,7 @@ func PostInternGetGatewaysHistory(ctrl *controller.Controller, _ deviceRepo.Inte
 			http.Error(res, "access denied", http.StatusUnauthorized)
 			return
 		}
-		result, err := ctrl.GetResourcesHistory(ids, "gateway", duration)
+		result, err := ctrl.GetResourcesHistory(r.Context(), ids, "gateway", duration)
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to get gateways history", "error", err, "ids", ids, "duration", duration)
 			http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -305,7 +314,7 @@ func PostInternGetDevicesLogStart(ctrl *controller.Controller, _ deviceRepo.Inte
 				return
 			}
 		}
-		ok, err := ctrl.CheckRightList(util.GetAuthToken(r), ids, "r")
+		ok, err := ctrl.CheckRightList(r.Context(), util.GetAuthToken(r), ids, "r")
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to check device rights", "error", err, "ids", ids, "right", "r")
 			http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -315,7 +324,7 @@ func PostInternGetDevicesLogStart(ctrl *controller.Controller, _ deviceRepo.Inte
 			http.Error(res, "access denied", http.StatusUnauthorized)
 			return
 		}
-		result, err := ctrl.GetResourcesLogstart(ids, "device")
+		result, err := ctrl.GetResourcesLogstart(r.Context(), ids, "device")
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to get devices logstart", "error", err, "ids", ids)
 			http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -357,7 +366,7 @@ func PostInternGetGatewaysLogStart(ctrl *controller.Controller, _ deviceRepo.Int
 				return
 			}
 		}
-		ok, err := ctrl.CheckRightList(util.GetAuthToken(r), ids, "r")
+		ok, err := ctrl.CheckRightList(r.Context(), util.GetAuthToken(r), ids, "r")
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to check hub rights", "error", err, "ids", ids, "right", "r")
 			http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -367,7 +376,7 @@ func PostInternGetGatewaysLogStart(ctrl *controller.Controller, _ deviceRepo.Int
 			http.Error(res, "access denied", http.StatusUnauthorized)
 			return
 		}
-		result, err := ctrl.GetResourcesLogstart(ids, "gateway")
+		result, err := ctrl.GetResourcesLogstart(r.Context(), ids, "gateway")
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to get gateways logstart", "error", err, "ids", ids)
 			http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -393,6 +402,10 @@ func PostInternGetGatewaysLogStart(ctrl *controller.Controller, _ deviceRepo.Int
 func PostInternGetDevicesLogEdge(ctrl *controller.Controller, _ deviceRepo.Interface) (string, string, httprouter.Handle) {
 	return http.MethodPost, "/intern/logedge/device/:duration", func(res http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		duration := ps.ByName("duration")
+		if _, err := store.ParseInfluxDuration(duration); err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
 		ids := []string{}
 		err := json.NewDecoder(r.Body).Decode(&ids)
 		if err != nil {
@@ -411,7 +424,7 @@ func PostInternGetDevicesLogEdge(ctrl *controller.Controller, _ deviceRepo.Inter
 				return
 			}
 		}
-		ok, err := ctrl.CheckRightList(util.GetAuthToken(r), ids, "r")
+		ok, err := ctrl.CheckRightList(r.Context(), util.GetAuthToken(r), ids, "r")
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to check device rights", "error", err, "ids", ids, "right", "r")
 			http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -421,7 +434,7 @@ func PostInternGetDevicesLogEdge(ctrl *controller.Controller, _ deviceRepo.Inter
 			http.Error(res, "access denied", http.StatusUnauthorized)
 			return
 		}
-		result, err := ctrl.GetResourcesLogEdge(ids, "device", duration)
+		result, err := ctrl.GetResourcesLogEdge(r.Context(), ids, "device", duration)
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to get devices log edge", "error", err, "ids", ids, "duration", duration)
 			http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -447,6 +460,10 @@ func PostInternGetDevicesLogEdge(ctrl *controller.Controller, _ deviceRepo.Inter
 func PostInternGetGatewaysLogEdge(ctrl *controller.Controller, _ deviceRepo.Interface) (string, string, httprouter.Handle) {
 	return http.MethodPost, "/intern/logedge/gateway/:duration", func(res http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		duration := ps.ByName("duration")
+		if _, err := store.ParseInfluxDuration(duration); err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
 		ids := []string{}
 		err := json.NewDecoder(r.Body).Decode(&ids)
 		if err != nil {
@@ -465,7 +482,7 @@ func PostInternGetGatewaysLogEdge(ctrl *controller.Controller, _ deviceRepo.Inte
 				return
 			}
 		}
-		ok, err := ctrl.CheckRightList(util.GetAuthToken(r), ids, "r")
+		ok, err := ctrl.CheckRightList(r.Context(), util.GetAuthToken(r), ids, "r")
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to check hub rights", "error", err, "ids", ids, "right", "r")
 			http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -475,7 +492,7 @@ func PostInternGetGatewaysLogEdge(ctrl *controller.Controller, _ deviceRepo.Inte
 			http.Error(res, "access denied", http.StatusUnauthorized)
 			return
 		}
-		result, err := ctrl.GetResourcesLogEdge(ids, "gateway", duration)
+		result, err := ctrl.GetResourcesLogEdge(r.Context(), ids, "gateway", duration)
 		if err != nil {
 			ctrl.Config().GetLogger().Error("unable to get gateways log edge", "error", err, "ids", ids, "duration", duration)
 			http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/SENERGY-Platform/models/go/models"
+)
+
+func TestHandlerOldInjection(t *testing.T) {
+	ids := func(path string) func(router http.Handler, id string, _ string) *httptest.ResponseRecorder {
+		return func(router http.Handler, id string, _ string) *httptest.ResponseRecorder {
+			return serve(router, http.MethodPost, path, []string{id})
+		}
+	}
+	idsWithDuration := func(path string) func(router http.Handler, id string, duration string) *httptest.ResponseRecorder {
+		return func(router http.Handler, id string, duration string) *httptest.ResponseRecorder {
+			return serve(router, http.MethodPost, path+url.PathEscape(duration), []string{id})
+		}
+	}
+	runInjectionTests(t, []injectionEndpoint{
+		{name: "PostCheckDeviceOnlineStates", prefix: models.DEVICE_PREFIX, request: ids("/state/device/check")},
+		{name: "PostInternCheckDeviceOnlineStates", prefix: models.DEVICE_PREFIX, request: ids("/intern/state/device/check")},
+		{name: "PostInternCheckGatewayOnlineStates", prefix: models.HUB_PREFIX, request: ids("/intern/state/gateway/check")},
+		{name: "PostInternGetDevicesHistory", prefix: models.DEVICE_PREFIX, duration: true, request: idsWithDuration("/intern/history/device/")},
+		{name: "PostInternGetGatewaysHistory", prefix: models.HUB_PREFIX, duration: true, request: idsWithDuration("/intern/history/gateway/")},
+		{name: "PostInternGetDevicesLogStart", prefix: models.DEVICE_PREFIX, request: ids("/intern/logstarts/device")},
+		{name: "PostInternGetGatewaysLogStart", prefix: models.HUB_PREFIX, request: ids("/intern/logstarts/gateway")},
+		{name: "PostInternGetDevicesLogEdge", prefix: models.DEVICE_PREFIX, duration: true, request: idsWithDuration("/intern/logedge/device/")},
+		{name: "PostInternGetGatewaysLogEdge", prefix: models.HUB_PREFIX, duration: true, request: idsWithDuration("/intern/logedge/gateway/")},
+	})
+}
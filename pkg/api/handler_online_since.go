@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/api/util"
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/julienschmidt/httprouter"
+)
+
+// OnlineSinceDevices godoc
+// @Summary Query online timestamps of devices
+// @Description Query the time since online devices are connected, with multiple IDs (supported: devices, device-groups, locations). If no IDs are provided, all accessible device IDs will be queried. Device-groups and locations will be resolved to their device IDs.
+// @Description Offline devices and devices without known transition time are omitted.
+// @Tags Online List
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param query body model.QueryWithAttributeFilter true "query object, attribute value and origin will only be checked if set, otherwise all values or origins will be blacklisted"
+// @Param include-names query boolean false "include device names in the response"
+// @Success	200 {array} model.OnlineSinceResponse "online timestamps by device IDs"
+// @Failure	400 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Router /online-since/devices [post]
+func OnlineSinceDevices(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodPost, "/online-since/devices", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		var query model.QueryWithAttributeFilter
+		err := json.NewDecoder(request.Body).Decode(&query)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		token := util.GetAuthToken(request)
+		if len(query.IDs) == 0 {
+			query.IDs, err = ctrl.ListIds(request.Context(), token, model.PermDeviceKind)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		} else {
+			query.IDs, err = ctrl.PermissionsFilterIDs(request.Context(), token, query.IDs)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			query.IDs, _, err = resolveDeviceIds(request.Context(), dr, token, query.IDs)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		query.IDs, err = filterDevices(request.Context(), dr, token, query.IDs, query.DeviceAttributeBlacklist)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		states, err := ctrl.GetOnlineSince(request.Context(), query.IDs, model.DeviceKind)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if request.URL.Query().Get("include-names") == "true" {
+			ids := []string{}
+			for _, state := range states {
+				ids = append(ids, state.ID)
+			}
+			names, err, code := deviceNames(request.Context(), dr, token, ids)
+			if err != nil {
+				http.Error(writer, err.Error(), code)
+				return
+			}
+			for i, state := range states {
+				states[i].Name = names[state.ID]
+			}
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err = json.NewEncoder(writer).Encode(states); err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
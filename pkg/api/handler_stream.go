@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/api/util"
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetCurrentStream godoc
+// @Summary Stream current state changes
+// @Description Server-sent events stream of connection state changes, with multiple IDs (supported: devices, hubs, device-groups, locations). Device-groups and locations will be resolved to their device IDs.
+// @Description Each change is sent as event "state" with a model.StateChange as data. The event id can be sent as Last-Event-ID header to resume the stream after a reconnect.
+// @Description Comment lines are sent as heartbeat. Permissions and members are checked periodically, the stream is closed if they change.
+// @Tags Current states
+// @Produce	text/event-stream
+// @Security Bearer
+// @Param ids query string true "comma separated list of IDs"
+// @Param Last-Event-ID header string false "id of the last received event"
+// @Success	200 {object} model.StateChange "event stream"
+// @Failure	400 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /current/stream [get]
+func GetCurrentStream(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodGet, "/current/stream", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		ids := []string{}
+		for _, id := range strings.Split(request.URL.Query().Get("ids"), ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			http.Error(writer, "missing ids", http.StatusBadRequest)
+			return
+		}
+		heartbeat, err := time.ParseDuration(ctrl.Config().StreamHeartbeatInterval)
+		if err != nil || heartbeat <= 0 {
+			http.Error(writer, fmt.Sprintf("invalid heartbeat interval '%s'", ctrl.Config().StreamHeartbeatInterval), http.StatusInternalServerError)
+			return
+		}
+		recheck, stopRecheck, err := permissionRecheck(ctrl)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer stopRecheck()
+		token := util.GetAuthToken(request)
+		requested := ids
+		ids, err = resolveStreamIds(request.Context(), ctrl, dr, token, requested)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		changes, err := ctrl.WatchCurrentStates(request.Context(), ids, request.Header.Get("Last-Event-ID"))
+		if err != nil {
+			switch {
+			case errors.Is(err, controller.ErrStreamNotSupported):
+				http.Error(writer, err.Error(), http.StatusNotImplemented)
+			case errors.Is(err, store.ErrInvalidResumeToken):
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+			default:
+				http.Error(writer, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		flusher := http.NewResponseController(writer)
+		writer.Header().Set("Content-Type", "text/event-stream")
+		writer.Header().Set("Cache-Control", "no-cache")
+		writer.Header().Set("X-Accel-Buffering", "no")
+		writer.WriteHeader(http.StatusOK)
+		if err = flusher.Flush(); err != nil {
+			ctrl.Config().GetLogger().Error("unable to flush event stream", "error", err)
+			return
+		}
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case change, ok := <-changes:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(change)
+				if err != nil {
+					ctrl.Config().GetLogger().Error("unable to marshal state change", "error", err)
+					continue
+				}
+				_, err = fmt.Fprintf(writer, "id: %s\nevent: state\ndata: %s\n\n", change.ResumeToken, data)
+				if err != nil {
+					return
+				}
+			case <-ticker.C:
+				_, err = fmt.Fprint(writer, ": heartbeat\n\n")
+				if err != nil {
+					return
+				}
+			case <-recheck:
+				// the client reconnects with the Last-Event-ID and gets a stream of the currently permitted ids
+				current, err := resolveStreamIds(request.Context(), ctrl, dr, token, requested)
+				if err != nil || !slices.Equal(current, ids) {
+					return
+				}
+			case <-request.Context().Done():
+				return
+			}
+			if err = flusher.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// permissionRecheck returns the ticks of StreamPermissionCheckInterval, a nil channel if the check is disabled.
+func permissionRecheck(ctrl *controller.Controller) (recheck <-chan time.Time, stop func(), err error) {
+	interval := ctrl.Config().StreamPermissionCheckInterval
+	if interval == "" || interval == "-" {
+		return nil, func() {}, nil
+	}
+	duration, err := time.ParseDuration(interval)
+	if err != nil || duration <= 0 {
+		return nil, nil, fmt.Errorf("invalid permission check interval '%s'", interval)
+	}
+	ticker := time.NewTicker(duration)
+	return ticker.C, ticker.Stop, nil
+}
+
+// resolveStreamIds returns the sorted device and hub ids of a stream, the result changes if permissions or members change.
+func resolveStreamIds(ctx context.Context, ctrl *controller.Controller, dr deviceRepo.Interface, token string, ids []string) ([]string, error) {
+	ids, err := ctrl.PermissionsFilterIDs(ctx, token, ids)
+	if err != nil {
+		return nil, err
+	}
+	ids, _, err = resolveDeviceIds(ctx, dr, token, ids)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids), nil
+}
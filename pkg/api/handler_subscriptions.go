@@ -0,0 +1,372 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/api/util"
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/net/websocket"
+)
+
+var errAccessDenied = errors.New("access denied")
+var errOriginNotAllowed = errors.New("origin not allowed")
+
+// GetCurrentSubscriptions godoc
+// @Summary Subscribe to current state changes
+// @Description WebSocket endpoint, the watched IDs (supported: devices, hubs, device-groups, locations) can be changed with model.SubscriptionRequest messages of type "subscribe" and "unsubscribe".
+// @Description Permissions are checked on each subscribe and periodically, subscriptions without access are removed with an "error" message. The server answers with a "snapshot" of the current states of the subscribed IDs, followed by a "state" message per change. Device-groups and locations will be resolved to their device IDs.
+// @Description Denied or failed requests are answered with an "error" message, a "heartbeat" is sent periodically. Browser origins have to be in the configured allow-list.
+// @Tags Current states
+// @Security Bearer
+// @Success	101 {object} model.SubscriptionMessage "websocket messages"
+// @Failure	403 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Router /current/ws [get]
+func GetCurrentSubscriptions(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodGet, "/current/ws", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		heartbeat, err := time.ParseDuration(ctrl.Config().StreamHeartbeatInterval)
+		if err != nil || heartbeat <= 0 {
+			http.Error(writer, fmt.Sprintf("invalid heartbeat interval '%s'", ctrl.Config().StreamHeartbeatInterval), http.StatusInternalServerError)
+			return
+		}
+		recheck, stopRecheck, err := permissionRecheck(ctrl)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer stopRecheck()
+		server := websocket.Server{
+			Handshake: func(_ *websocket.Config, request *http.Request) error {
+				return checkOrigin(request, ctrl.Config().WebsocketAllowedOrigins)
+			},
+			Handler: func(conn *websocket.Conn) {
+				ctx, cancel := context.WithCancel(request.Context())
+				defer cancel()
+				session := &subscriptionSession{
+					ctrl:          ctrl,
+					dr:            dr,
+					token:         util.GetAuthToken(request),
+					conn:          conn,
+					ctx:           ctx,
+					subscriptions: map[string][]string{},
+				}
+				go session.heartbeat(heartbeat)
+				session.run(recheck)
+			},
+		}
+		server.ServeHTTP(writer, request)
+	}
+}
+
+// checkOrigin accepts requests without origin, e.g. of non-browser clients, and origins of the allow-list, "*" allows all origins.
+func checkOrigin(request *http.Request, allowed []string) error {
+	origin := request.Header.Get("Origin")
+	if origin == "" || slices.Contains(allowed, "*") || slices.Contains(allowed, origin) {
+		return nil
+	}
+	return fmt.Errorf("%w: '%s'", errOriginNotAllowed, origin)
+}
+
+type subscriptionSession struct {
+	ctrl  *controller.Controller
+	dr    deviceRepo.Interface
+	token string
+	conn  *websocket.Conn
+	ctx   context.Context
+
+	subscriptions map[string][]string // requested id -> sorted device or hub ids, only used by run
+
+	mux         sync.Mutex // guards writes to conn and the fields below
+	resumeToken string     // of the last sent change
+	stopWatch   context.CancelFunc
+}
+
+// run handles the requests of the client and the permission checks of recheck until the connection is closed.
+func (this *subscriptionSession) run(recheck <-chan time.Time) {
+	requests := make(chan model.SubscriptionRequest)
+	go this.receive(requests)
+	for {
+		select {
+		case request, ok := <-requests:
+			if !ok {
+				return
+			}
+			switch request.Type {
+			case model.SubscriptionSubscribe:
+				this.subscribe(request.IDs)
+			case model.SubscriptionUnsubscribe:
+				this.unsubscribe(request.IDs)
+			default:
+				this.send(model.SubscriptionMessage{Type: model.SubscriptionError, Error: fmt.Sprintf("unknown type '%s'", request.Type)})
+			}
+		case <-recheck:
+			if err := this.recheck(); err != nil {
+				// like the event stream, the connection is closed if the permissions can not be checked
+				this.send(model.SubscriptionMessage{Type: model.SubscriptionError, Error: err.Error()})
+				return
+			}
+		case <-this.ctx.Done():
+			return
+		}
+	}
+}
+
+// receive passes the requests of the client to requests, which is closed with the connection.
+func (this *subscriptionSession) receive(requests chan<- model.SubscriptionRequest) {
+	defer close(requests)
+	for {
+		var data []byte
+		err := websocket.Message.Receive(this.conn, &data)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				this.ctrl.Config().GetLogger().Debug("websocket receive failed", "error", err)
+			}
+			return
+		}
+		var request model.SubscriptionRequest
+		if err = json.Unmarshal(data, &request); err != nil {
+			this.send(model.SubscriptionMessage{Type: model.SubscriptionError, Error: err.Error()})
+			continue
+		}
+		select {
+		case requests <- request:
+		case <-this.ctx.Done():
+			return
+		}
+	}
+}
+
+func (this *subscriptionSession) subscribe(ids []string) {
+	allowed, err := this.checkAccess(ids)
+	if err != nil {
+		this.send(model.SubscriptionMessage{Type: model.SubscriptionError, IDs: ids, Error: err.Error()})
+		return
+	}
+	resolved := map[string][]string{}
+	denied := []string{}
+	for _, id := range ids {
+		if !allowed[id] {
+			denied = append(denied, id)
+			continue
+		}
+		resolved[id], err = this.resolve(id)
+		if err != nil {
+			delete(resolved, id)
+			this.send(model.SubscriptionMessage{Type: model.SubscriptionError, IDs: []string{id}, Error: err.Error()})
+		}
+	}
+	if len(denied) > 0 {
+		this.send(model.SubscriptionMessage{Type: model.SubscriptionError, IDs: denied, Error: errAccessDenied.Error()})
+	}
+	if len(resolved) == 0 {
+		return
+	}
+	maps.Copy(this.subscriptions, resolved)
+	this.watchAndSnapshot(resolved)
+}
+
+// watchAndSnapshot restarts the watch for the current subscriptions and sends a snapshot of the states of resolved.
+func (this *subscriptionSession) watchAndSnapshot(resolved map[string][]string) {
+	requested := slices.Collect(maps.Keys(resolved))
+	// the watch is opened before the snapshot is read, so no change gets lost in between
+	forward, watchErr := this.watch()
+	defer forward()
+	snapshotIds := []string{}
+	for _, deviceIds := range resolved {
+		snapshotIds = append(snapshotIds, deviceIds...)
+	}
+	states, err := this.ctrl.QueryBaseStatesMap(this.ctx, model.QueryBase{IDs: snapshotIds})
+
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if watchErr != nil {
+		this.write(model.SubscriptionMessage{Type: model.SubscriptionError, IDs: requested, Error: watchErr.Error()})
+	}
+	if err != nil {
+		this.write(model.SubscriptionMessage{Type: model.SubscriptionError, IDs: requested, Error: err.Error()})
+		return
+	}
+	this.write(model.SubscriptionMessage{Type: model.SubscriptionSnapshot, IDs: requested, States: states})
+}
+
+func (this *subscriptionSession) unsubscribe(ids []string) {
+	for _, id := range ids {
+		delete(this.subscriptions, id)
+	}
+	forward, err := this.watch()
+	defer forward()
+	if err != nil {
+		this.send(model.SubscriptionMessage{Type: model.SubscriptionError, Error: err.Error()})
+	}
+}
+
+// recheck repeats the permission check and the resolution of all subscriptions, see StreamPermissionCheckInterval.
+// Subscriptions without access are removed with an error message, subscriptions with changed members get a new snapshot.
+func (this *subscriptionSession) recheck() error {
+	if len(this.subscriptions) == 0 {
+		return nil
+	}
+	allowed, err := this.checkAccess(slices.Collect(maps.Keys(this.subscriptions)))
+	if err != nil {
+		return err
+	}
+	revoked := []string{}
+	changed := map[string][]string{}
+	for id, ids := range this.subscriptions {
+		if !allowed[id] {
+			revoked = append(revoked, id)
+			continue
+		}
+		current, err := this.resolve(id)
+		if err != nil {
+			return err
+		}
+		if !slices.Equal(current, ids) {
+			changed[id] = current
+		}
+	}
+	for _, id := range revoked {
+		delete(this.subscriptions, id)
+	}
+	maps.Copy(this.subscriptions, changed)
+	if len(revoked) > 0 {
+		slices.Sort(revoked)
+		this.send(model.SubscriptionMessage{Type: model.SubscriptionError, IDs: revoked, Error: errAccessDenied.Error()})
+	}
+	if len(changed) > 0 {
+		this.watchAndSnapshot(changed)
+		return nil
+	}
+	if len(revoked) > 0 {
+		forward, err := this.watch()
+		defer forward()
+		if err != nil {
+			this.send(model.SubscriptionMessage{Type: model.SubscriptionError, Error: err.Error()})
+		}
+	}
+	return nil
+}
+
+// resolve returns the sorted device or hub ids of a requested id.
+func (this *subscriptionSession) resolve(id string) ([]string, error) {
+	ids, _, err := resolveDeviceIds(this.ctx, this.dr, this.token, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids), nil
+}
+
+// checkAccess returns the permission for each of the requested ids, groups and locations included.
+func (this *subscriptionSession) checkAccess(ids []string) (map[string]bool, error) {
+	idsByKind, err := controller.GetIdsByKind(ids, true)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]bool{}
+	for kind, kindIds := range idsByKind {
+		access, err := this.ctrl.CheckAccess(this.ctx, this.token, kind, kindIds)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(result, access)
+	}
+	return result, nil
+}
+
+// watch replaces the running watch with one for the current subscriptions, continuing after the last sent change.
+// The store is called without holding this.mux, the returned function starts forwarding the changes
+// and is called after the snapshot is sent, it must not be called with this.mux locked.
+func (this *subscriptionSession) watch() (forward func(), err error) {
+	forward = func() {}
+	ids := []string{}
+	for _, deviceIds := range this.subscriptions {
+		ids = append(ids, deviceIds...)
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	this.mux.Lock()
+	if this.stopWatch != nil {
+		this.stopWatch()
+		this.stopWatch = nil
+	}
+	resumeToken := this.resumeToken
+	this.mux.Unlock()
+
+	if len(ids) == 0 {
+		return forward, nil
+	}
+	ctx, cancel := context.WithCancel(this.ctx)
+	changes, err := this.ctrl.WatchCurrentStates(ctx, ids, resumeToken)
+	if err != nil && resumeToken != "" {
+		// the token may be outdated, e.g. if the change is no longer in the oplog
+		resumeToken = ""
+		changes, err = this.ctrl.WatchCurrentStates(ctx, ids, "")
+	}
+	if err != nil {
+		cancel()
+		return forward, err
+	}
+
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	// the previous watch is stopped, so no other change updated the resume token in the meantime
+	this.resumeToken = resumeToken
+	this.stopWatch = cancel
+	return func() { go this.forward(ctx, changes) }, nil
+}
+
+func (this *subscriptionSession) forward(ctx context.Context, changes <-chan model.StateChange) {
+	for change := range changes {
+		this.mux.Lock()
+		if ctx.Err() == nil {
+			this.resumeToken = change.ResumeToken
+			this.write(model.SubscriptionMessage{Type: model.SubscriptionState, Change: &change})
+		}
+		this.mux.Unlock()
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if ctx.Err() == nil {
+		this.write(model.SubscriptionMessage{Type: model.SubscriptionError, Error: "state stream closed"})
+	}
+}
+
+func (this *subscriptionSession) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			this.send(model.SubscriptionMessage{Type: model.SubscriptionHeartbeat})
+		case <-this.ctx.Done():
+			return
+		}
+	}
+}
+
+func (this *subscriptionSession) send(msg model.SubscriptionMessage) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.write(msg)
+}
+
+// write sends msg to the client, this.mux has to be locked.
+func (this *subscriptionSession) write(msg model.SubscriptionMessage) {
+	if err := websocket.JSON.Send(this.conn, msg); err != nil {
+		this.ctrl.Config().GetLogger().Debug("websocket send failed", "error", err)
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"golang.org/x/net/websocket"
+)
+
+// nextMessage returns the next message of type messageType, other messages are skipped.
+func nextMessage(t *testing.T, conn *websocket.Conn, messageType string) model.SubscriptionMessage {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg model.SubscriptionMessage
+		if err := websocket.JSON.Receive(conn, &msg); err != nil {
+			t.Fatalf("missing %v message: %v", messageType, err)
+		}
+		if msg.Type == messageType && (messageType != model.SubscriptionError || msg.Error == errAccessDenied.Error()) {
+			return msg
+		}
+	}
+}
+
+func TestGetCurrentSubscriptionsRecheck(t *testing.T) {
+	api := newTestApi(t, func(config *configuration.Config) {
+		config.StreamHeartbeatInterval = "1h"
+		config.StreamPermissionCheckInterval = "10ms"
+		config.WebsocketAllowedOrigins = []string{"http://localhost"}
+	})
+	api.set(testDeviceId, model.State{Time: t0, Connected: true})
+	api.set(testHubId, model.State{Time: t0, Connected: false})
+	server := httptest.NewServer(api.router)
+	defer server.Close()
+	config, err := websocket.NewConfig("ws"+strings.TrimPrefix(server.URL, "http")+"/current/ws", "http://localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Header.Set("Authorization", testToken)
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err = websocket.JSON.Send(conn, model.SubscriptionRequest{Type: model.SubscriptionSubscribe, IDs: []string{testDeviceId, testHubId}}); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := nextMessage(t, conn, model.SubscriptionSnapshot)
+	if len(snapshot.States) != 2 {
+		t.Fatalf("unexpected snapshot %#v", snapshot)
+	}
+
+	api.permissions.Revoke(testDeviceId)
+	if msg := nextMessage(t, conn, model.SubscriptionError); !slices.Equal(msg.IDs, []string{testDeviceId}) {
+		t.Errorf("unexpected ids %v", msg.IDs)
+	}
+	api.permissions.Revoke(testHubId)
+	if msg := nextMessage(t, conn, model.SubscriptionError); !slices.Equal(msg.IDs, []string{testHubId}) {
+		t.Errorf("unexpected ids %v", msg.IDs)
+	}
+}
@@ -0,0 +1,473 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store/memory"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	testToken    = "Bearer test"
+	testDeviceId = models.DEVICE_PREFIX + "victim"
+	testHubId    = models.HUB_PREFIX + "victim"
+
+	testHookSecret = "secret"
+)
+
+// testPermissions is a permissions-v2 service granting access to every resource except the revoked ids, checked ids are recorded.
+type testPermissions struct {
+	mux        sync.Mutex
+	checked    []string
+	accessible map[string][]string
+	revoked    map[string]bool
+}
+
+func (this *testPermissions) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var result any
+	switch {
+	case strings.HasPrefix(request.URL.Path, "/check/"):
+		ids := strings.Split(request.URL.Query().Get("ids"), ",")
+		this.mux.Lock()
+		this.checked = append(this.checked, ids...)
+		access := map[string]bool{}
+		for _, id := range ids {
+			access[id] = !this.revoked[id]
+		}
+		this.mux.Unlock()
+		result = access
+	case strings.HasPrefix(request.URL.Path, "/accessible/"):
+		ids := this.accessible[strings.TrimPrefix(request.URL.Path, "/accessible/")]
+		if ids == nil {
+			ids = []string{}
+		}
+		result = ids
+	default:
+		http.NotFound(writer, request)
+		return
+	}
+	_ = json.NewEncoder(writer).Encode(result)
+}
+
+func (this *testPermissions) Checked() []string {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return slices.Clone(this.checked)
+}
+
+func (this *testPermissions) Revoke(id string) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if this.revoked == nil {
+		this.revoked = map[string]bool{}
+	}
+	this.revoked[id] = true
+}
+
+func (this *testPermissions) Reset() {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.checked = nil
+}
+
+// testDeviceRepo is a device-repository serving the names of devices and hubs, unknown ids are not listed.
+// Devices are read by local id with the keys of localIds, "<owner>/<local id>".
+// The largest number of ids of a list request is recorded in maxIds.
+type testDeviceRepo struct {
+	names    map[string]string
+	localIds map[string]string
+	mux      sync.Mutex
+	maxIds   int
+}
+
+func (this *testDeviceRepo) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	ids := strings.Split(request.URL.Query().Get("ids"), ",")
+	this.mux.Lock()
+	this.maxIds = max(this.maxIds, len(ids))
+	this.mux.Unlock()
+	if localId, ok := strings.CutPrefix(request.URL.Path, "/devices/"); ok && request.URL.Query().Get("as") == "local_id" {
+		id, ok := this.localIds[request.URL.Query().Get("owner_id")+"/"+localId]
+		if !ok {
+			http.NotFound(writer, request)
+			return
+		}
+		_ = json.NewEncoder(writer).Encode(models.Device{Id: id, LocalId: localId})
+		return
+	}
+	switch request.URL.Path {
+	case "/v3/devices":
+		devices := []models.Device{}
+		for _, id := range ids {
+			if name, ok := this.names[id]; ok {
+				devices = append(devices, models.Device{Id: id, Name: name})
+			}
+		}
+		_ = json.NewEncoder(writer).Encode(devices)
+	case "/v3/hubs":
+		hubs := []models.Hub{}
+		for _, id := range ids {
+			if name, ok := this.names[id]; ok {
+				hubs = append(hubs, models.Hub{Id: id, Name: name})
+			}
+		}
+		_ = json.NewEncoder(writer).Encode(hubs)
+	default:
+		http.NotFound(writer, request)
+	}
+}
+
+type testApi struct {
+	router      *httprouter.Router
+	mem         *memory.Memory
+	permissions *testPermissions
+	deviceRepo  *testDeviceRepo
+}
+
+// newTestApi serves all routes with a memory store, a permissions service granting access to every resource and a device-repository without resources.
+// The config can be changed with configure.
+func newTestApi(t *testing.T, configure ...func(config *configuration.Config)) *testApi {
+	t.Helper()
+	api := &testApi{
+		mem: memory.New(),
+		permissions: &testPermissions{accessible: map[string][]string{
+			model.PermDeviceKind:  {testDeviceId},
+			model.PermGatewayKind: {testHubId},
+		}},
+		deviceRepo: &testDeviceRepo{names: map[string]string{}, localIds: map[string]string{}},
+	}
+	permissionsServer := httptest.NewServer(api.permissions)
+	t.Cleanup(permissionsServer.Close)
+	deviceRepoServer := httptest.NewServer(api.deviceRepo)
+	t.Cleanup(deviceRepoServer.Close)
+	config := configuration.Config{
+		PermissionsV2Url:     permissionsServer.URL,
+		DeviceRepoUrl:        deviceRepoServer.URL,
+		HttpClientTimeout:    "10s",
+		FlappingDefaultRange: "24h",
+		VernemqHookSecret:    testHookSecret,
+		DeviceRepoToken:      testToken,
+		LogLevel:             "error",
+	}
+	for _, f := range configure {
+		f(&config)
+	}
+	ctrl := controller.NewWithStores(config, api.mem, api.mem)
+	api.router = httprouter.New()
+	for _, route := range routes {
+		m, p, h := route(ctrl, ctrl.DeviceRepo())
+		api.router.Handle(m, p, h)
+	}
+	return api
+}
+
+// set stores the states of a device or gateway in the memory store.
+func (this *testApi) set(id string, states ...model.State) {
+	kind, _ := controller.GetKindFromId(id, false)
+	for _, state := range states {
+		this.mem.Set(kind, id, state)
+	}
+}
+
+func serve(router http.Handler, method string, path string, body any) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		reader = bytes.NewReader(b)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	request := httptest.NewRequest(method, path, reader)
+	request.Header.Set("Authorization", testToken)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// decode checks the status of resp and decodes its body.
+func decode[T any](t *testing.T, resp *httptest.ResponseRecorder) (result T) {
+	t.Helper()
+	if resp.Code != http.StatusOK {
+		t.Fatalf("unexpected status %v: %v", resp.Code, resp.Body.String())
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+var (
+	t0            = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	flappingId    = models.DEVICE_PREFIX + "flapping"
+	recoveringId  = models.DEVICE_PREFIX + "recovering"
+	historyWindow = map[string]any{"since": t0, "until": t0.Add(8 * time.Hour)}
+)
+
+// seedHistory stores two devices with states around the time frame t0 to t0+8h.
+// flappingId is online for 5h and offline for 3h in two intervals, recoveringId is offline from t0+2h until t0+10h.
+func seedHistory(api *testApi) {
+	at := func(offset time.Duration, connected bool) model.State {
+		return model.State{Time: t0.Add(offset), Connected: connected}
+	}
+	api.set(flappingId, at(-time.Hour, true), at(time.Hour, false), at(3*time.Hour, true), at(5*time.Hour, false), at(6*time.Hour, true))
+	api.set(recoveringId, at(-time.Hour, true), at(2*time.Hour, false), at(10*time.Hour, true))
+}
+
+// historyQuery returns a query of the seeded time frame with the given ids and fields.
+func historyQuery(ids []string, fields map[string]any) map[string]any {
+	query := map[string]any{"ids": ids}
+	maps.Copy(query, historyWindow)
+	maps.Copy(query, fields)
+	return query
+}
+
+func TestPostQueryHistoricalStatesMap(t *testing.T) {
+	api := newTestApi(t)
+	seedHistory(api)
+	at := func(offset time.Duration, connected bool) model.State {
+		return model.State{Time: t0.Add(offset), Connected: connected}
+	}
+	result := decode[map[string]model.HistoricalStates](t, serve(api.router, http.MethodPost, "/historical/query/map", historyQuery([]string{flappingId, recoveringId}, nil)))
+	expected := map[string]model.HistoricalStates{
+		flappingId: {
+			PrevState: &model.State{Time: t0.Add(-time.Hour), Connected: true},
+			States:    []model.State{at(time.Hour, false), at(3*time.Hour, true), at(5*time.Hour, false), at(6*time.Hour, true)},
+		},
+		recoveringId: {
+			PrevState: &model.State{Time: t0.Add(-time.Hour), Connected: true},
+			States:    []model.State{at(2*time.Hour, false)},
+			NextState: &model.State{Time: t0.Add(10 * time.Hour), Connected: true},
+		},
+	}
+	if len(result) != len(expected) {
+		t.Fatalf("\n%#v\n%#v", result, expected)
+	}
+	for id, states := range expected {
+		if !reflect.DeepEqual(normalize(result[id]), normalize(states)) {
+			t.Errorf("%v:\n%#v\n%#v", id, result[id], states)
+		}
+	}
+
+	resp := serve(api.router, http.MethodPost, "/historical/query/map", historyQuery([]string{flappingId}, map[string]any{"until": t0.Add(2 * time.Hour)}))
+	result = decode[map[string]model.HistoricalStates](t, resp)
+	if states := result[flappingId]; states.PrevState == nil || !states.PrevState.Time.Equal(t0.Add(-time.Hour)) || len(states.States) != 1 || states.NextState == nil || !states.NextState.Time.Equal(t0.Add(3*time.Hour)) {
+		t.Errorf("unexpected states %#v", states)
+	}
+}
+
+// normalize converts the state times of states to UTC, decoded times keep the offset of the response.
+func normalize(states model.HistoricalStates) model.HistoricalStates {
+	utc := func(state *model.State) *model.State {
+		if state == nil {
+			return nil
+		}
+		return &model.State{Time: state.Time.UTC(), Connected: state.Connected}
+	}
+	result := model.HistoricalStates{PrevState: utc(states.PrevState), NextState: utc(states.NextState)}
+	for _, state := range states.States {
+		result.States = append(result.States, *utc(&state))
+	}
+	return result
+}
+
+// injectionEndpoint sends id and duration to an endpoint, duration is ignored by endpoints without a duration.
+type injectionEndpoint struct {
+	name     string
+	prefix   string
+	duration bool
+	request  func(router http.Handler, id string, duration string) *httptest.ResponseRecorder
+}
+
+// injectionPayloads are appended to id prefixes and used as kinds and durations.
+// They must not contain '/' or ',', which are separators of paths and of the permission check ids.
+var injectionPayloads = []string{
+	`1'`,
+	`1" OR "1" = "1`,
+	`1' OR '1'='1`,
+	`1'; DROP MEASUREMENT "device"; --`,
+	`1; SELECT * FROM "gateway"`,
+	`1\"; DELETE FROM "device"`,
+	`.*`,
+	`1|.*`,
+	`victim.*`,
+	`^.*$`,
+	`$p0`,
+	`${p0}`,
+	`$where`,
+	`{"$ne": null}`,
+}
+
+// runInjectionTests checks that payloads in ids, kinds and durations never select other resources than the requested ones.
+// The seeded victim resources are only part of a response if they are requested with their exact id.
+func runInjectionTests(t *testing.T, endpoints []injectionEndpoint) {
+	api := newTestApi(t)
+	router, permissions := api.router, api.permissions
+	now := time.Now()
+	for _, id := range []string{testDeviceId, testHubId} {
+		api.set(id, model.State{Time: now.Add(-48 * time.Hour), Connected: true}, model.State{Time: now.Add(-time.Hour), Connected: false})
+	}
+
+	for _, endpoint := range endpoints {
+		t.Run(endpoint.name+"/control", func(t *testing.T) {
+			resp := endpoint.request(router, endpoint.prefix+"victim", "24h")
+			if resp.Code != http.StatusOK {
+				t.Fatalf("unexpected status %v: %v", resp.Code, resp.Body.String())
+			}
+			if !containsVictim(resp.Body.String()) {
+				t.Fatalf("expected victim in response: %v", resp.Body.String())
+			}
+		})
+		for _, payload := range injectionPayloads {
+			t.Run(endpoint.name+"/id/"+payload, func(t *testing.T) {
+				permissions.Reset()
+				id := endpoint.prefix + payload
+				resp := endpoint.request(router, id, "24h")
+				if containsVictim(resp.Body.String()) {
+					t.Fatalf("victim selected by id %q: %v", id, resp.Body.String())
+				}
+				if resp.Code != http.StatusOK && resp.Code != http.StatusInternalServerError {
+					t.Fatalf("unexpected status %v: %v", resp.Code, resp.Body.String())
+				}
+				if checked := permissions.Checked(); !slices.Equal(checked, []string{id}) {
+					t.Fatalf("expected permission check of %q, got %q", id, checked)
+				}
+			})
+			t.Run(endpoint.name+"/kind/"+payload, func(t *testing.T) {
+				permissions.Reset()
+				for _, id := range []string{payload, models.URN_PREFIX + payload + ":victim"} {
+					resp := endpoint.request(router, id, "24h")
+					if resp.Code < 400 {
+						t.Fatalf("expected rejection of %q, got %v: %v", id, resp.Code, resp.Body.String())
+					}
+					if containsVictim(resp.Body.String()) {
+						t.Fatalf("victim selected by kind %q: %v", id, resp.Body.String())
+					}
+				}
+				if checked := permissions.Checked(); len(checked) > 0 {
+					t.Fatalf("unexpected permission check of %q", checked)
+				}
+			})
+			if endpoint.duration {
+				t.Run(endpoint.name+"/duration/"+payload, func(t *testing.T) {
+					for _, duration := range []string{payload, "24h" + payload, payload + "24h"} {
+						resp := endpoint.request(router, endpoint.prefix+"victim", duration)
+						if resp.Code != http.StatusBadRequest {
+							t.Fatalf("expected status 400 for duration %q, got %v: %v", duration, resp.Code, resp.Body.String())
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// containsVictim checks for the exact json encoded ids of the victim resources, requested ids may contain them as prefix.
+func containsVictim(body string) bool {
+	return strings.Contains(body, `"`+testDeviceId+`"`) || strings.Contains(body, `"`+testHubId+`"`)
+}
+
+// historicalQuery builds a json body with the range as raw string, payloads are not valid durations and can not be set as model.Duration.
+func historicalQuery(id string, duration string) map[string]any {
+	return map[string]any{"ids": []string{id}, "range": duration}
+}
+
+func TestHandlerInjection(t *testing.T) {
+	runInjectionTests(t, []injectionEndpoint{
+		{
+			name:   "GetCurrentDeviceState",
+			prefix: models.DEVICE_PREFIX,
+			request: func(router http.Handler, id string, _ string) *httptest.ResponseRecorder {
+				return serve(router, http.MethodGet, "/current/devices/"+url.PathEscape(id), nil)
+			},
+		},
+		{
+			name:   "GetCurrentGatewayState",
+			prefix: models.HUB_PREFIX,
+			request: func(router http.Handler, id string, _ string) *httptest.ResponseRecorder {
+				return serve(router, http.MethodGet, "/current/gateways/"+url.PathEscape(id), nil)
+			},
+		},
+		{
+			name:   "PostQueryBaseStatesMap",
+			prefix: models.DEVICE_PREFIX,
+			request: func(router http.Handler, id string, _ string) *httptest.ResponseRecorder {
+				return serve(router, http.MethodPost, "/current/query/map", model.QueryBase{IDs: []string{id}})
+			},
+		},
+		{
+			name:   "PostQueryBaseStatesList",
+			prefix: models.HUB_PREFIX,
+			request: func(router http.Handler, id string, _ string) *httptest.ResponseRecorder {
+				return serve(router, http.MethodPost, "/current/query/list", model.QueryBase{IDs: []string{id}})
+			},
+		},
+		{
+			name:   "PostQueryWithAttributeFilterMapOriginal",
+			prefix: models.DEVICE_PREFIX,
+			request: func(router http.Handler, id string, _ string) *httptest.ResponseRecorder {
+				return serve(router, http.MethodPost, "/current/query/map-original", model.QueryBase{IDs: []string{id}})
+			},
+		},
+		{
+			name:   "OfflineSinceDevices",
+			prefix: models.DEVICE_PREFIX,
+			request: func(router http.Handler, id string, _ string) *httptest.ResponseRecorder {
+				return serve(router, http.MethodPost, "/offline-since/devices", model.QueryBase{IDs: []string{id}})
+			},
+		},
+		{
+			name:     "GetHistoricalDeviceStates",
+			prefix:   models.DEVICE_PREFIX,
+			duration: true,
+			request: func(router http.Handler, id string, duration string) *httptest.ResponseRecorder {
+				return serve(router, http.MethodGet, "/historical/devices/"+url.PathEscape(id)+"?"+url.Values{queryParamRange: {duration}}.Encode(), nil)
+			},
+		},
+		{
+			name:     "GetHistoricalGatewayStates",
+			prefix:   models.HUB_PREFIX,
+			duration: true,
+			request: func(router http.Handler, id string, duration string) *httptest.ResponseRecorder {
+				return serve(router, http.MethodGet, "/historical/gateways/"+url.PathEscape(id)+"?"+url.Values{queryParamRange: {duration}}.Encode(), nil)
+			},
+		},
+		{
+			name:     "PostQueryHistoricalStatesMap",
+			prefix:   models.DEVICE_PREFIX,
+			duration: true,
+			request: func(router http.Handler, id string, duration string) *httptest.ResponseRecorder {
+				return serve(router, http.MethodPost, "/historical/query/map", historicalQuery(id, duration))
+			},
+		},
+		{
+			name:     "PostQueryHistoricalStatesList",
+			prefix:   models.HUB_PREFIX,
+			duration: true,
+			request: func(router http.Handler, id string, duration string) *httptest.ResponseRecorder {
+				return serve(router, http.MethodPost, "/historical/query/list", historicalQuery(id, duration))
+			},
+		},
+		{
+			name:     "PostQueryHistoricalStatesMapOriginal",
+			prefix:   models.DEVICE_PREFIX,
+			duration: true,
+			request: func(router http.Handler, id string, duration string) *httptest.ResponseRecorder {
+				return serve(router, http.MethodPost, "/historical/query/map-original", historicalQuery(id, duration))
+			},
+		},
+	})
+}
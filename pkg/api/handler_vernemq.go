@@ -0,0 +1,180 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/devicerepo"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	vernemqHookHeader          = "vernemq-hook"
+	vernemqHookOnRegister      = "on_register"
+	vernemqHookOnClientOffline = "on_client_offline"
+	vernemqHookOnClientGone    = "on_client_gone"
+)
+
+const vernemqClientCacheDuration = 10 * time.Minute
+
+type vernemqHookPayload struct {
+	ClientId   string    `json:"client_id"`
+	Mountpoint string    `json:"mountpoint"`
+	Username   string    `json:"username"`
+	Timestamp  time.Time `json:"timestamp"` // optional, the time of the request is used if not set
+}
+
+// PostInternVernemqHook godoc
+// @Summary VerneMQ webhook
+// @Description Receives the VerneMQ webhooks on_register, on_client_offline and on_client_gone (selected by the vernemq-hook header) and records the connection state of the client.
+// @Description Requests authenticate with VernemqHookSecret as basic auth password, e.g. with the endpoint http://vernemq:<secret>@connection-log:8080/intern/vernemq/hook.
+// @Description Client ids are expected to be device or hub ids or local ids of devices owned by the MQTT user, other clients are ignored. The offline hooks carry no username, local ids are resolved with the on_register hook of the client. The response of authenticated requests is always ok, so clients are never rejected because of the connection log.
+// @Tags Ingest
+// @Accept json
+// @Produce	json
+// @Param vernemq-hook header string true "hook name"
+// @Param payload body vernemqHookPayload true "hook payload"
+// @Success	200 {object} map[string]string "result"
+// @Failure	401 {string} string "error message"
+// @Router /intern/vernemq/hook [post]
+func PostInternVernemqHook(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	clients := &vernemqClients{items: map[string]vernemqClient{}, registered: map[string]string{}}
+	return http.MethodPost, "/intern/vernemq/hook", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		secret := ctrl.Config().VernemqHookSecret
+		_, password, _ := request.BasicAuth()
+		if secret == "" || subtle.ConstantTimeCompare([]byte(password), []byte(secret)) != 1 {
+			http.Error(writer, "invalid vernemq hook secret", http.StatusUnauthorized)
+			return
+		}
+		defer func() {
+			writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+			json.NewEncoder(writer).Encode(map[string]string{"result": "ok"})
+		}()
+		hook := request.Header.Get(vernemqHookHeader)
+		var connected bool
+		switch hook {
+		case vernemqHookOnRegister:
+			connected = true
+		case vernemqHookOnClientOffline, vernemqHookOnClientGone:
+			connected = false
+		default:
+			ctrl.Config().GetLogger().Warn("ignore unsupported vernemq hook", "hook", hook)
+			return
+		}
+		var payload vernemqHookPayload
+		err := json.NewDecoder(request.Body).Decode(&payload)
+		if err != nil {
+			ctrl.Config().GetLogger().Error("unable to decode vernemq hook payload", "hook", hook, "error", err)
+			return
+		}
+		id, err := clients.resolve(request.Context(), ctrl, dr, payload)
+		if err != nil {
+			ctrl.Config().GetLogger().Error("unable to look up vernemq client", "hook", hook, "client_id", payload.ClientId, "error", err)
+			return
+		}
+		if id == "" {
+			ctrl.Config().GetLogger().Debug("ignore vernemq client", "hook", hook, "client_id", payload.ClientId)
+			return
+		}
+		if connected {
+			clients.register(payload, id)
+		} else {
+			clients.unregister(payload)
+		}
+		kind, err := controller.GetKindFromId(id, false)
+		if err != nil {
+			ctrl.Config().GetLogger().Error("unexpected id of vernemq client", "hook", hook, "client_id", payload.ClientId, "id", id, "error", err)
+			return
+		}
+		timestamp := payload.Timestamp
+		if timestamp.IsZero() {
+			timestamp = time.Now()
+		}
+		_, err = ctrl.AddState(request.Context(), id, kind, model.State{Time: timestamp, Connected: connected})
+		if err != nil {
+			ctrl.Config().GetLogger().Error("unable to store vernemq hook state", "hook", hook, "client_id", payload.ClientId, "error", err)
+		}
+	}
+}
+
+// vernemqClients caches the device ids of client ids that are looked up in the device-repository, clients without device are cached with an empty id.
+// The offline hooks carry no username, so the device ids of registered clients with local ids are kept by mountpoint and client id until they go offline.
+type vernemqClients struct {
+	mux        sync.Mutex
+	items      map[string]vernemqClient
+	registered map[string]string
+}
+
+type vernemqClient struct {
+	id      string
+	expires time.Time
+}
+
+// resolve returns the device or hub id of the client, or an empty id for unknown clients.
+// Client ids with device or hub prefix are used as they are, other client ids are looked up as local id of a device owned by the MQTT user.
+func (this *vernemqClients) resolve(ctx context.Context, ctrl *controller.Controller, dr deviceRepo.Interface, payload vernemqHookPayload) (string, error) {
+	if _, err := controller.GetKindFromId(payload.ClientId, false); err == nil {
+		return payload.ClientId, nil
+	}
+	if payload.Username == "" {
+		this.mux.Lock()
+		defer this.mux.Unlock()
+		return this.registered[payload.sessionKey()], nil
+	}
+	token := ctrl.Config().DeviceRepoToken
+	if token == "" || payload.ClientId == "" {
+		return "", nil
+	}
+	key := payload.Username + "/" + payload.ClientId
+	this.mux.Lock()
+	item, ok := this.items[key]
+	this.mux.Unlock()
+	if ok && time.Now().Before(item.expires) {
+		return item.id, nil
+	}
+	device, err, code := devicerepo.WithContext(ctx, dr).ReadDeviceByLocalId(payload.Username, payload.ClientId, token, deviceRepo.AuthAction("r"))
+	if err != nil && code != http.StatusNotFound {
+		return "", err
+	}
+	item = vernemqClient{id: device.Id, expires: time.Now().Add(vernemqClientCacheDuration)}
+	if err != nil {
+		item.id = ""
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	for k, v := range this.items {
+		if time.Now().After(v.expires) {
+			delete(this.items, k)
+		}
+	}
+	this.items[key] = item
+	return item.id, nil
+}
+
+// register keeps the device id of a client with local id for the offline hooks.
+func (this *vernemqClients) register(payload vernemqHookPayload, id string) {
+	if id == payload.ClientId {
+		return
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.registered[payload.sessionKey()] = id
+}
+
+func (this *vernemqClients) unregister(payload vernemqHookPayload) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	delete(this.registered, payload.sessionKey())
+}
+
+// sessionKey identifies a client connection, client ids are unique per mountpoint.
+func (this vernemqHookPayload) sessionKey() string {
+	return this.Mountpoint + "/" + this.ClientId
+}
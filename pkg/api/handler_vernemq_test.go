@@ -0,0 +1,69 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/models/go/models"
+)
+
+func vernemqHook(t *testing.T, api *testApi, hook string, payload vernemqHookPayload) {
+	t.Helper()
+	body, _ := json.Marshal(payload)
+	request := httptest.NewRequest(http.MethodPost, "/intern/vernemq/hook", bytes.NewReader(body))
+	request.SetBasicAuth("vernemq", testHookSecret)
+	request.Header.Set(vernemqHookHeader, hook)
+	recorder := httptest.NewRecorder()
+	api.router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("unexpected status %v: %v", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestPostInternVernemqHookLocalId(t *testing.T) {
+	api := newTestApi(t)
+	deviceId := models.DEVICE_PREFIX + "local"
+	api.deviceRepo.localIds["user/client"] = deviceId
+	connected := func() bool {
+		t.Helper()
+		state, err := api.mem.GetCurrentState(context.Background(), deviceId, model.DeviceKind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return state.Connected
+	}
+
+	for i, hook := range []string{vernemqHookOnClientOffline, vernemqHookOnClientGone} {
+		at := t0.Add(time.Duration(i) * time.Hour)
+		vernemqHook(t, api, vernemqHookOnRegister, vernemqHookPayload{ClientId: "client", Mountpoint: "", Username: "user", Timestamp: at})
+		if !connected() {
+			t.Fatalf("%v: expected online device after on_register", hook)
+		}
+		// the offline hooks carry no username
+		vernemqHook(t, api, vernemqHookOnClientOffline, vernemqHookPayload{ClientId: "client", Mountpoint: "other", Timestamp: at.Add(time.Minute)})
+		if !connected() {
+			t.Fatalf("%v: expected online device after hook of other mountpoint", hook)
+		}
+		vernemqHook(t, api, hook, vernemqHookPayload{ClientId: "client", Mountpoint: "", Timestamp: at.Add(2 * time.Minute)})
+		if connected() {
+			t.Fatalf("%v: expected offline device", hook)
+		}
+	}
+
+	// clients with device ids need no lookup
+	vernemqHook(t, api, vernemqHookOnRegister, vernemqHookPayload{ClientId: testDeviceId, Timestamp: t0})
+	vernemqHook(t, api, vernemqHookOnClientGone, vernemqHookPayload{ClientId: testDeviceId, Timestamp: t0.Add(time.Minute)})
+	state, err := api.mem.GetCurrentState(context.Background(), testDeviceId, model.DeviceKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Connected {
+		t.Error("expected offline device")
+	}
+}
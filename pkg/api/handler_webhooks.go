@@ -0,0 +1,245 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/api/util"
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/service-commons/pkg/jwt"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ListWebhooks godoc
+// @Summary List webhooks
+// @Description Lists the webhooks registered by the user of the token.
+// @Tags Webhooks
+// @Produce	json
+// @Security Bearer
+// @Success	200 {array} model.Webhook "webhooks, without secrets"
+// @Failure	401 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /webhooks [get]
+func ListWebhooks(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodGet, "/webhooks", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		owner, err := getUserId(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		webhooks, err := ctrl.ListWebhooks(request.Context(), owner)
+		if err != nil {
+			http.Error(writer, err.Error(), webhookErrorCode(err))
+			return
+		}
+		for i := range webhooks {
+			webhooks[i].Secret = ""
+		}
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(webhooks)
+		if err != nil {
+			ctrl.Config().GetLogger().Error("unable to encode response", "error", err)
+		}
+	}
+}
+
+// GetWebhook godoc
+// @Summary Get webhook
+// @Tags Webhooks
+// @Produce	json
+// @Security Bearer
+// @Param id path string true "webhook id"
+// @Success	200 {object} model.Webhook "webhook, without secret"
+// @Failure	401 {string} string "error message"
+// @Failure	404 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /webhooks/{id} [get]
+func GetWebhook(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodGet, "/webhooks/:id", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		owner, err := getUserId(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		webhook, err := ctrl.GetWebhook(request.Context(), owner, params.ByName("id"))
+		if err != nil {
+			http.Error(writer, err.Error(), webhookErrorCode(err))
+			return
+		}
+		webhook.Secret = ""
+		writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = json.NewEncoder(writer).Encode(webhook)
+		if err != nil {
+			ctrl.Config().GetLogger().Error("unable to encode response", "error", err)
+		}
+	}
+}
+
+// PostWebhook godoc
+// @Summary Register webhook
+// @Description Registers a webhook that is called with a model.WebhookEvent when a resource (supported: devices, hubs, device-groups, locations) is offline longer than the threshold, and again when it is online.
+// @Description The token needs read permission for all IDs. Permissions are checked again and device-groups and locations are resolved to their current device IDs on each evaluation, IDs without permission are skipped.
+// @Description The URL has to resolve to public addresses, private networks have to be allowed by the configuration.
+// @Description Requests are signed with the secret: the header X-Signature-256 contains "sha256=" followed by the hex encoded HMAC-SHA256 of the body.
+// @Tags Webhooks
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param webhook body model.Webhook true "webhook, id and owner are set by the service"
+// @Success	200 {object} model.Webhook "created webhook, without secret"
+// @Failure	400 {string} string "error message"
+// @Failure	401 {string} string "error message"
+// @Failure	403 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /webhooks [post]
+func PostWebhook(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodPost, "/webhooks", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		setWebhook(ctrl, dr, writer, request, uuid.NewString(), true)
+	}
+}
+
+// PutWebhook godoc
+// @Summary Update webhook
+// @Description Replaces the webhook, an empty secret keeps the current secret. Permissions are checked again.
+// @Tags Webhooks
+// @Accept json
+// @Produce	json
+// @Security Bearer
+// @Param id path string true "webhook id"
+// @Param webhook body model.Webhook true "webhook, id and owner are set by the service"
+// @Success	200 {object} model.Webhook "updated webhook, without secret"
+// @Failure	400 {string} string "error message"
+// @Failure	401 {string} string "error message"
+// @Failure	403 {string} string "error message"
+// @Failure	404 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /webhooks/{id} [put]
+func PutWebhook(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodPut, "/webhooks/:id", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		setWebhook(ctrl, dr, writer, request, params.ByName("id"), false)
+	}
+}
+
+// DeleteWebhook godoc
+// @Summary Delete webhook
+// @Tags Webhooks
+// @Security Bearer
+// @Param id path string true "webhook id"
+// @Success	204
+// @Failure	401 {string} string "error message"
+// @Failure	404 {string} string "error message"
+// @Failure	500 {string} string "error message"
+// @Failure	501 {string} string "error message"
+// @Router /webhooks/{id} [delete]
+func DeleteWebhook(ctrl *controller.Controller, dr deviceRepo.Interface) (string, string, httprouter.Handle) {
+	return http.MethodDelete, "/webhooks/:id", func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		owner, err := getUserId(request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		err = ctrl.DeleteWebhook(request.Context(), owner, params.ByName("id"))
+		if err != nil {
+			http.Error(writer, err.Error(), webhookErrorCode(err))
+			return
+		}
+		writer.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func setWebhook(ctrl *controller.Controller, dr deviceRepo.Interface, writer http.ResponseWriter, request *http.Request, id string, create bool) {
+	owner, err := getUserId(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if !create {
+		_, err = ctrl.GetWebhook(request.Context(), owner, id)
+		if err != nil {
+			http.Error(writer, err.Error(), webhookErrorCode(err))
+			return
+		}
+	}
+	var webhook model.Webhook
+	err = json.NewDecoder(request.Body).Decode(&webhook)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	webhook.ID = id
+	webhook.Owner = owner
+	var code int
+	webhook.Resources, err, code = resolvePermittedIds(request.Context(), ctrl, dr, util.GetAuthToken(request), webhook.IDs)
+	if err != nil {
+		http.Error(writer, err.Error(), code)
+		return
+	}
+	err = ctrl.SetWebhook(request.Context(), webhook)
+	if err != nil {
+		http.Error(writer, err.Error(), webhookErrorCode(err))
+		return
+	}
+	webhook.Secret = ""
+	writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(writer).Encode(webhook)
+	if err != nil {
+		ctrl.Config().GetLogger().Error("unable to encode response", "error", err)
+	}
+}
+
+// resolvePermittedIds checks the read permission for all ids and resolves device-groups and locations.
+func resolvePermittedIds(ctx context.Context, ctrl *controller.Controller, dr deviceRepo.Interface, token string, ids []string) ([]string, error, int) {
+	if _, err := controller.GetIdsByKind(ids, true); err != nil {
+		return nil, err, http.StatusBadRequest
+	}
+	permitted, err := ctrl.PermissionsFilterIDs(ctx, token, ids)
+	if err != nil {
+		return nil, err, http.StatusInternalServerError
+	}
+	for _, id := range ids {
+		if !slices.Contains(permitted, id) {
+			return nil, errors.New("access denied for " + id), http.StatusForbidden
+		}
+	}
+	resources, _, err := resolveDeviceIds(ctx, dr, token, ids)
+	if err != nil {
+		return nil, err, http.StatusInternalServerError
+	}
+	slices.Sort(resources)
+	return slices.Compact(resources), nil, http.StatusOK
+}
+
+func getUserId(request *http.Request) (string, error) {
+	token, err := jwt.Parse(util.GetAuthToken(request))
+	if err != nil {
+		return "", err
+	}
+	if token.GetUserId() == "" {
+		return "", errors.New("missing user id in auth token")
+	}
+	return token.GetUserId(), nil
+}
+
+func webhookErrorCode(err error) int {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, controller.ErrInvalidWebhook):
+		return http.StatusBadRequest
+	case errors.Is(err, controller.ErrWebhooksNotSupported):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -32,9 +32,10 @@ func (this *CorsMiddleware) ServeHTTP(res http.ResponseWriter, req *http.Request
 		origin = "*"
 	}
 	res.Header().Set("Access-Control-Allow-Origin", origin)
-	res.Header().Set("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept, authorization, Authorization")
+	res.Header().Set("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept, authorization, Authorization, Last-Event-ID")
 	res.Header().Set("Access-Control-Allow-Credentials", "true")
 	res.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+	res.Header().Set("Access-Control-Expose-Headers", "X-Total-Count")
 
 	if req.Method == "OPTIONS" {
 		res.WriteHeader(http.StatusOK)
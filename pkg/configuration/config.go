@@ -34,11 +34,20 @@ import (
 )
 
 type Config struct {
+	CurrentStateBackend string
+	HistoryBackend      string
+
 	MongoUrl               string `config:"secret"`
 	MongoTable             string
 	MongodbTimeout         int64
 	DeviceStateCollection  string
 	GatewayStateCollection string
+	HistoryCollection      string
+	HistoryKeyRetention    string // keys of the mongodb history backend are removed after this duration, kept if empty or "-"
+	WebhookCollection      string
+	AlertRuleCollection    string
+	AlertCollection        string
+	DigestCollection       string
 
 	ServerPort       string
 	PermissionsV2Url string
@@ -50,10 +59,70 @@ type Config struct {
 	InfluxdbTimeout int64
 	InfluxdbUseUTC  bool
 
-	DeviceRepoUrl string
+	InfluxdbOrg    string
+	InfluxdbBucket string
+	InfluxdbToken  string `config:"secret"`
+
+	PostgresUrl     string `config:"secret"`
+	PostgresTable   string
+	PostgresTimeout int64
+
+	KafkaUrl           string
+	KafkaConsumerGroup string
+	DeviceLogTopic     string
+	GatewayLogTopic    string
+
+	DeviceRepoUrl   string
+	DeviceRepoToken string `config:"secret"` // service token for lookups without user token, e.g. of vernemq client ids
+
+	AuthEndpoint     string // keycloak, user tokens of webhook, alert rule and digest owners are requested with a token exchange, fleet exporter tokens are verified with the realm certs
+	AuthClientId     string
+	AuthClientSecret string `config:"secret"`
+
+	VernemqHookSecret string `config:"secret"` // password of the basic auth of POST /intern/vernemq/hook, the hook is rejected if empty
+
+	FlappingThreshold    float64 // state transitions per hour
+	FlappingDefaultRange string  // time frame of flapping queries without 'since' or 'range'
+	FlappingMaxIds       int64   // ids per flapping query, also if all accessible resources are queried, no limit if 0
+
+	IngestMaxBodySize  int64 // bytes of a POST /ingest/events body, no limit if 0
+	IngestMaxBatchSize int64 // events of a POST /ingest/events batch, no limit if 0
+
+	HistoryChunkSize        int64 // ids per history query, larger id lists are split, 0 disables the split
+	HistoryQueryConcurrency int64 // history queries of one request that run at the same time
+
+	StreamHeartbeatInterval       string
+	StreamPermissionCheckInterval string   // event streams are closed and websocket subscriptions removed if the permitted ids change, not checked if empty or "-"
+	WebsocketAllowedOrigins       []string // browser origins of GET /current/ws, "*" allows all, requests without origin are always allowed
+
+	WebhookInterval        string // evaluation interval, webhooks are disabled if empty or "-"
+	WebhookTimeout         string
+	WebhookMaxAttempts     int64
+	WebhookWorkers         int64    // deliveries of webhooks and alert notifications that run at the same time
+	WebhookQueueSize       int64    // waiting deliveries, evaluations wait while the queue is full
+	WebhookAllowedNetworks []string // CIDRs of private networks webhooks may be sent to, e.g. "10.0.0.0/8", public addresses are always allowed
+
+	AlertInterval string // evaluation interval, alert rules are not evaluated if empty or "-"
+
+	SmtpHost       string // email notifications are disabled if empty or "-"
+	SmtpPort       string
+	SmtpUser       string
+	SmtpPassword   string `config:"secret"`
+	SmtpFrom       string
+	SmtpTimeout    string // deadline of sending an email, including connect
+	DigestInterval string // check interval for due digests
+
+	FleetExporterDeviceStates  bool   // adds a connection_state gauge per device and hub to /metrics/fleet
+	FleetExporterServiceUser   string // user id (token subject) that may scrape /metrics/fleet, the endpoint is disabled if empty or without AuthEndpoint
+	FleetExporterCacheDuration string // locations, device-groups and hubs are read at most once per duration, not cached if empty or "-"
+
+	OtlpEndpoint string // traces are exported over OTLP/HTTP, e.g. "http://otel-collector:4318", disabled if empty or "-"
 
 	HttpClientTimeout string
 
+	RequestTimeout  string            // deadline of requests, disabled if empty or "-", streams have no default deadline
+	RequestTimeouts map[string]string // deadlines per route, e.g. {"POST /historical/query/map": "1m"}, keys have to match a registered route, override RequestTimeout
+
 	LogLevel string       `json:"log_level"`
 	logger   *slog.Logger `json:"-"`
 }
@@ -129,9 +198,13 @@ func handleEnvironmentVars(config *Config) {
 			if configValue.FieldByName(fieldName).Kind() == reflect.Map {
 				value := map[string]string{}
 				for _, element := range strings.Split(envValue, ",") {
-					keyVal := strings.Split(element, ":")
-					key := strings.TrimSpace(keyVal[0])
-					val := strings.TrimSpace(keyVal[1])
+					// keys may contain ':' (e.g. route paths with parameters), values are split at the last one
+					sep := strings.LastIndex(element, ":")
+					if sep < 0 {
+						continue
+					}
+					key := strings.TrimSpace(element[:sep])
+					val := strings.TrimSpace(element[sep+1:])
 					value[key] = val
 				}
 				configValue.FieldByName(fieldName).Set(reflect.ValueOf(value))
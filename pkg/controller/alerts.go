@@ -0,0 +1,268 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+)
+
+var ErrAlertsNotSupported = errors.New("configured current state store does not support alerts")
+var ErrInvalidAlertRule = errors.New("invalid alert rule")
+var ErrAlertResolved = errors.New("alert is resolved")
+
+func (this *Controller) alertStore() (store.AlertStore, error) {
+	alerts, ok := this.current.(store.AlertStore)
+	if !ok {
+		return nil, ErrAlertsNotSupported
+	}
+	return alerts, nil
+}
+
+// CheckAlerts returns ErrAlertsNotSupported if the current state store can not keep alert rules.
+func (this *Controller) CheckAlerts() error {
+	_, err := this.alertStore()
+	return err
+}
+
+// ListAlertRules returns the rules of owner, or all rules if owner is empty.
+func (this *Controller) ListAlertRules(ctx context.Context, owner string) ([]model.AlertRule, error) {
+	alerts, err := this.alertStore()
+	if err != nil {
+		return nil, err
+	}
+	return alerts.ListAlertRules(ctx, owner)
+}
+
+// GetAlertRule returns store.ErrNotFound if the rule does not exist or belongs to another owner.
+func (this *Controller) GetAlertRule(ctx context.Context, owner string, id string) (model.AlertRule, error) {
+	alerts, err := this.alertStore()
+	if err != nil {
+		return model.AlertRule{}, err
+	}
+	rule, err := alerts.GetAlertRule(ctx, id)
+	if err != nil {
+		return model.AlertRule{}, err
+	}
+	if rule.Owner != owner {
+		return model.AlertRule{}, store.ErrNotFound
+	}
+	return rule, nil
+}
+
+// SetAlertRule creates or replaces a rule of rule.Owner, the webhooks of the channels have to belong to the same owner.
+// The resources have to be resolved and permitted by the caller.
+func (this *Controller) SetAlertRule(ctx context.Context, rule model.AlertRule) error {
+	alerts, err := this.alertStore()
+	if err != nil {
+		return err
+	}
+	existing, err := alerts.GetAlertRule(ctx, rule.ID)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+	case err != nil:
+		return err
+	case existing.Owner != rule.Owner:
+		return store.ErrNotFound
+	}
+	if err = ValidateAlertRule(rule); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAlertRule, err)
+	}
+	for _, channel := range rule.Channels {
+		_, err = this.GetWebhook(ctx, rule.Owner, channel.ID)
+		if errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("%w: unknown webhook '%s'", ErrInvalidAlertRule, channel.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return alerts.SetAlertRule(ctx, rule)
+}
+
+// DeleteAlertRule removes the rule and its alerts.
+func (this *Controller) DeleteAlertRule(ctx context.Context, owner string, id string) error {
+	alerts, err := this.alertStore()
+	if err != nil {
+		return err
+	}
+	if _, err = this.GetAlertRule(ctx, owner, id); err != nil {
+		return err
+	}
+	return alerts.DeleteAlertRule(ctx, id)
+}
+
+func (this *Controller) ListAlerts(ctx context.Context, filter model.AlertFilter) ([]model.Alert, error) {
+	alerts, err := this.alertStore()
+	if err != nil {
+		return nil, err
+	}
+	return alerts.ListAlerts(ctx, filter)
+}
+
+func (this *Controller) SetAlert(ctx context.Context, alert model.Alert) error {
+	alerts, err := this.alertStore()
+	if err != nil {
+		return err
+	}
+	return alerts.SetAlert(ctx, alert)
+}
+
+// AcknowledgeAlert marks a firing alert of owner as acknowledged, it stays active until the condition is no longer met.
+// Returns ErrAlertResolved for resolved alerts.
+func (this *Controller) AcknowledgeAlert(ctx context.Context, owner string, id string) (model.Alert, error) {
+	alerts, err := this.alertStore()
+	if err != nil {
+		return model.Alert{}, err
+	}
+	alert, err := alerts.GetAlert(ctx, id)
+	if err != nil {
+		return model.Alert{}, err
+	}
+	if alert.Owner != owner {
+		return model.Alert{}, store.ErrNotFound
+	}
+	switch alert.State {
+	case model.AlertStateResolved:
+		return model.Alert{}, ErrAlertResolved
+	case model.AlertStateAcknowledged:
+		return alert, nil
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	alert.State = model.AlertStateAcknowledged
+	alert.AcknowledgedAt = &now
+	alert.AcknowledgedBy = owner
+	return alert, alerts.SetAlert(ctx, alert)
+}
+
+// EvaluateAlertCondition returns the resources matching the condition. The value is the transitions per hour
+// for flapping and the online percentage for availability conditions, offline conditions have no value.
+func (this *Controller) EvaluateAlertCondition(ctx context.Context, ids []string, condition model.AlertCondition) (map[string]float64, error) {
+	result := map[string]float64{}
+	if len(ids) == 0 {
+		return result, nil
+	}
+	switch condition.Type {
+	case model.AlertConditionOffline:
+		current, err := this.QueryBaseStatesMap(ctx, model.QueryBase{IDs: ids})
+		if err != nil {
+			return nil, err
+		}
+		offline := []string{}
+		for _, id := range ids {
+			if connected, ok := current[id]; ok && !connected {
+				offline = append(offline, id)
+			}
+		}
+		if len(offline) == 0 {
+			return result, nil
+		}
+		// offline for the whole duration: offline before and no online state within
+		states, err := this.QueryHistoricalStatesMap(ctx, model.QueryHistorical{QueryBase: model.QueryBase{IDs: offline}, Range: condition.Duration})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range offline {
+			resource := states[id]
+			if resource.PrevState == nil || resource.PrevState.Connected {
+				continue
+			}
+			if slices.ContainsFunc(resource.States, func(state model.State) bool { return state.Connected }) {
+				continue
+			}
+			result[id] = 0
+		}
+	case model.AlertConditionFlapping, model.AlertConditionAvailability:
+		query := model.QueryHistorical{QueryBase: model.QueryBase{IDs: ids}, Range: condition.Window}
+		states, err := this.QueryHistoricalStatesMap(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		since, until := availabilityWindow(query, time.Now())
+		for _, id := range ids {
+			if condition.Type == model.AlertConditionFlapping {
+				transitions, hours := countTransitions(states[id], since, until)
+				if hours > 0 && float64(transitions)/hours > condition.Transitions {
+					result[id] = float64(transitions) / hours
+				}
+				continue
+			}
+			availability := calcAvailability(states[id], since, until)
+			if availability.Online+availability.Offline == 0 {
+				// no known state within the window
+				continue
+			}
+			if percentage := availability.OnlineRatio * 100; percentage < condition.Availability {
+				result[id] = percentage
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown condition type '%s'", condition.Type)
+	}
+	return result, nil
+}
+
+func ValidateAlertRule(rule model.AlertRule) error {
+	if rule.ID == "" || rule.Owner == "" {
+		return errors.New("missing rule id or owner")
+	}
+	if len(rule.IDs) == 0 {
+		return errors.New("missing ids")
+	}
+	for _, id := range rule.Resources {
+		if _, err := GetKindFromId(id, false); err != nil {
+			return fmt.Errorf("invalid resource '%s': %w", id, err)
+		}
+	}
+	switch rule.Severity {
+	case model.AlertSeverityInfo, model.AlertSeverityWarning, model.AlertSeverityCritical:
+	default:
+		return fmt.Errorf("invalid severity '%s'", rule.Severity)
+	}
+	condition := rule.Condition
+	switch condition.Type {
+	case model.AlertConditionOffline:
+		if condition.Duration <= 0 {
+			return errors.New("offline condition needs a positive duration")
+		}
+	case model.AlertConditionFlapping:
+		if condition.Transitions <= 0 || condition.Window <= 0 {
+			return errors.New("flapping condition needs positive transitions and window")
+		}
+	case model.AlertConditionAvailability:
+		if condition.Availability <= 0 || condition.Availability > 100 || condition.Window <= 0 {
+			return errors.New("availability condition needs an availability between 0 and 100 and a positive window")
+		}
+	default:
+		return fmt.Errorf("unknown condition type '%s'", condition.Type)
+	}
+	for _, channel := range rule.Channels {
+		if channel.Type != model.AlertChannelWebhook {
+			return fmt.Errorf("unknown channel type '%s'", channel.Type)
+		}
+		if channel.ID == "" {
+			return errors.New("missing channel id")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+// QueryAvailabilityMap calculates the online, offline and unknown durations of devices and gateways within the queried time frame.
+// Without 'since' or 'range' the time frame starts with the first known state, it never extends beyond the current time.
+func (this *Controller) QueryAvailabilityMap(ctx context.Context, query model.QueryHistorical) (map[string]model.Availability, error) {
+	now := time.Now()
+	states, err := this.QueryHistoricalStatesMap(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	since, until := availabilityWindow(query, now)
+	result := map[string]model.Availability{}
+	for _, id := range query.IDs {
+		result[id] = calcAvailability(states[id], since, until)
+	}
+	return result, nil
+}
+
+// AggregateAvailability sums the durations of multiple resources, e.g. the members of a device-group.
+func AggregateAvailability(items ...model.Availability) (result model.Availability) {
+	for _, item := range items {
+		result.Online += item.Online
+		result.Offline += item.Offline
+		result.Unknown += item.Unknown
+	}
+	result.OnlineRatio = onlineRatio(result)
+	return result
+}
+
+// availabilityWindow mirrors the time frame selection of the history stores, a zero since means the time frame starts with the first state.
+func availabilityWindow(query model.QueryHistorical, now time.Time) (since time.Time, until time.Time) {
+	rng := time.Duration(query.Range)
+	switch {
+	case !query.Since.IsZero() && !query.Until.IsZero():
+		since, until = query.Since, query.Until
+	case rng > 0 && !query.Until.IsZero():
+		since, until = query.Until.Add(-rng), query.Until
+	case rng > 0 && !query.Since.IsZero():
+		since, until = query.Since, query.Since.Add(rng)
+	case rng > 0:
+		since, until = now.Add(-rng), now
+	case !query.Until.IsZero():
+		until = query.Until
+	case !query.Since.IsZero():
+		since, until = query.Since, now
+	default:
+		until = now
+	}
+	if until.After(now) {
+		until = now
+	}
+	return since, until
+}
+
+func calcAvailability(states model.HistoricalStates, since time.Time, until time.Time) (result model.Availability) {
+	if since.IsZero() {
+		if len(states.States) == 0 {
+			return result
+		}
+		since = states.States[0].Time
+	}
+	if !until.After(since) {
+		return result
+	}
+	var current *bool
+	if states.PrevState != nil {
+		current = &states.PrevState.Connected
+	}
+	cursor := since
+	add := func(end time.Time) {
+		if end.After(until) {
+			end = until
+		}
+		if !end.After(cursor) {
+			return
+		}
+		duration := model.Duration(end.Sub(cursor))
+		switch {
+		case current == nil:
+			result.Unknown += duration
+		case *current:
+			result.Online += duration
+		default:
+			result.Offline += duration
+		}
+		cursor = end
+	}
+	for _, state := range states.States {
+		add(state.Time)
+		current = &state.Connected
+	}
+	add(until)
+	result.OnlineRatio = onlineRatio(result)
+	return result
+}
+
+func onlineRatio(availability model.Availability) float64 {
+	known := availability.Online + availability.Offline
+	if known == 0 {
+		return 0
+	}
+	return float64(availability.Online) / float64(known)
+}
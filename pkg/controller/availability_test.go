@@ -0,0 +1,119 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+var t0 = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func state(offset time.Duration, connected bool) model.State {
+	return model.State{Time: t0.Add(offset), Connected: connected}
+}
+
+func statePtr(offset time.Duration, connected bool) *model.State {
+	s := state(offset, connected)
+	return &s
+}
+
+func TestCalcAvailability(t *testing.T) {
+	tests := []struct {
+		name     string
+		states   model.HistoricalStates
+		since    time.Time
+		until    time.Time
+		expected model.Availability
+	}{
+		{
+			name:  "no states",
+			until: t0.Add(time.Hour),
+		},
+		{
+			name:     "previous state only",
+			states:   model.HistoricalStates{PrevState: statePtr(-time.Hour, true)},
+			since:    t0,
+			until:    t0.Add(2 * time.Hour),
+			expected: model.Availability{OnlineRatio: 1, Online: model.Duration(2 * time.Hour)},
+		},
+		{
+			name: "previous state and states",
+			states: model.HistoricalStates{
+				PrevState: statePtr(-time.Hour, false),
+				States:    []model.State{state(time.Hour, true), state(3*time.Hour, false)},
+			},
+			since:    t0,
+			until:    t0.Add(4 * time.Hour),
+			expected: model.Availability{OnlineRatio: 0.5, Online: model.Duration(2 * time.Hour), Offline: model.Duration(2 * time.Hour)},
+		},
+		{
+			name:     "unknown before first state",
+			states:   model.HistoricalStates{States: []model.State{state(time.Hour, true)}},
+			since:    t0,
+			until:    t0.Add(2 * time.Hour),
+			expected: model.Availability{OnlineRatio: 1, Online: model.Duration(time.Hour), Unknown: model.Duration(time.Hour)},
+		},
+		{
+			name:     "zero since starts with first state",
+			states:   model.HistoricalStates{States: []model.State{state(time.Hour, true), state(2*time.Hour, false)}},
+			until:    t0.Add(4 * time.Hour),
+			expected: model.Availability{OnlineRatio: 1.0 / 3, Online: model.Duration(time.Hour), Offline: model.Duration(2 * time.Hour)},
+		},
+		{
+			name:     "repeated states",
+			states:   model.HistoricalStates{PrevState: statePtr(-time.Hour, true), States: []model.State{state(time.Hour, true), state(2*time.Hour, true)}},
+			since:    t0,
+			until:    t0.Add(4 * time.Hour),
+			expected: model.Availability{OnlineRatio: 1, Online: model.Duration(4 * time.Hour)},
+		},
+		{
+			name:     "states after until are cut",
+			states:   model.HistoricalStates{PrevState: statePtr(-time.Hour, true), States: []model.State{state(3*time.Hour, false)}},
+			since:    t0,
+			until:    t0.Add(2 * time.Hour),
+			expected: model.Availability{OnlineRatio: 1, Online: model.Duration(2 * time.Hour)},
+		},
+		{
+			name:   "until before since",
+			states: model.HistoricalStates{PrevState: statePtr(-time.Hour, true)},
+			since:  t0.Add(time.Hour),
+			until:  t0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := calcAvailability(test.states, test.since, test.until)
+			if result != test.expected {
+				t.Errorf("\n%#v\n%#v", result, test.expected)
+			}
+		})
+	}
+}
+
+func TestAggregateAvailability(t *testing.T) {
+	result := AggregateAvailability(
+		model.Availability{Online: model.Duration(3 * time.Hour), Offline: model.Duration(time.Hour)},
+		model.Availability{Online: model.Duration(time.Hour), Offline: model.Duration(3 * time.Hour), Unknown: model.Duration(time.Hour)},
+	)
+	expected := model.Availability{OnlineRatio: 0.5, Online: model.Duration(4 * time.Hour), Offline: model.Duration(4 * time.Hour), Unknown: model.Duration(time.Hour)}
+	if result != expected {
+		t.Errorf("\n%#v\n%#v", result, expected)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+const maxBuckets = 10000
+
+// QueryBucketsMap reduces the states of devices and gateways to availability buckets within the queried time frame.
+// Buckets are aligned to midnight of the first day in the requested timezone, the first and last bucket may be cut by the time frame.
+func (this *Controller) QueryBucketsMap(ctx context.Context, query model.QueryBuckets) (map[string][]model.Bucket, error) {
+	location, err := ValidateBucketsQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	states, err := this.QueryHistoricalStatesMap(ctx, query.QueryHistorical)
+	if err != nil {
+		return nil, err
+	}
+	since, until := availabilityWindow(query.QueryHistorical, now)
+	result := map[string][]model.Bucket{}
+	for _, id := range query.IDs {
+		resourceSince := since
+		if resourceSince.IsZero() {
+			if len(states[id].States) == 0 {
+				result[id] = []model.Bucket{}
+				continue
+			}
+			resourceSince = states[id].States[0].Time
+		}
+		bounds, err := bucketBounds(resourceSince, until, time.Duration(query.Bucket), location)
+		if err != nil {
+			return nil, err
+		}
+		result[id] = calcBuckets(states[id], bounds)
+	}
+	return result, nil
+}
+
+func ValidateBucketsQuery(query model.QueryBuckets) (*time.Location, error) {
+	if time.Duration(query.Bucket) < time.Second {
+		return nil, errors.New("bucket must be at least 1s")
+	}
+	return time.LoadLocation(query.Timezone)
+}
+
+// bucketBounds returns the bucket boundaries from since to until, the first and last bound are since and until.
+func bucketBounds(since time.Time, until time.Time, bucket time.Duration, location *time.Location) ([]time.Time, error) {
+	if !until.After(since) {
+		return []time.Time{}, nil
+	}
+	local := since.In(location)
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+	days := int(bucket / (24 * time.Hour))
+	next := func(t time.Time) time.Time {
+		if bucket%(24*time.Hour) == 0 {
+			return t.AddDate(0, 0, days)
+		}
+		return t.Add(bucket)
+	}
+	for !next(start).After(since) {
+		start = next(start)
+	}
+	bounds := []time.Time{since.In(location)}
+	for t := next(start); t.Before(until); t = next(t) {
+		if len(bounds) >= maxBuckets {
+			return nil, errors.New("too many buckets, use a larger bucket or a shorter time frame")
+		}
+		bounds = append(bounds, t)
+	}
+	return append(bounds, until.In(location)), nil
+}
+
+func calcBuckets(states model.HistoricalStates, bounds []time.Time) []model.Bucket {
+	result := []model.Bucket{}
+	prev := states.PrevState
+	i := 0
+	for b := 0; b+1 < len(bounds); b++ {
+		start, end := bounds[b], bounds[b+1]
+		for i < len(states.States) && states.States[i].Time.Before(start) {
+			prev = &states.States[i]
+			i++
+		}
+		j := i
+		for j < len(states.States) && states.States[j].Time.Before(end) {
+			j++
+		}
+		sub := model.HistoricalStates{PrevState: prev, States: states.States[i:j]}
+		bucket := model.Bucket{
+			Start:       start,
+			End:         end,
+			OnlineRatio: calcAvailability(sub, start, end).OnlineRatio,
+		}
+		bucket.Transitions, _ = countTransitions(sub, start, end)
+		if prev != nil {
+			connected := prev.Connected
+			bucket.StartState = &connected
+		}
+		if j > i && states.States[i].Time.Equal(start) {
+			connected := states.States[i].Connected
+			bucket.StartState = &connected
+		}
+		result = append(result, bucket)
+	}
+	return result
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+func TestBucketBounds(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		since    time.Time
+		until    time.Time
+		bucket   time.Duration
+		location *time.Location
+		expected []time.Time
+	}{
+		{
+			name:     "hours",
+			since:    t0.Add(10*time.Hour + 30*time.Minute),
+			until:    t0.Add(13 * time.Hour),
+			bucket:   time.Hour,
+			location: time.UTC,
+			expected: []time.Time{t0.Add(10*time.Hour + 30*time.Minute), t0.Add(11 * time.Hour), t0.Add(12 * time.Hour), t0.Add(13 * time.Hour)},
+		},
+		{
+			name:     "aligned to midnight",
+			since:    t0.Add(5 * time.Hour),
+			until:    t0.Add(12 * time.Hour),
+			bucket:   4 * time.Hour,
+			location: time.UTC,
+			expected: []time.Time{t0.Add(5 * time.Hour), t0.Add(8 * time.Hour), t0.Add(12 * time.Hour)},
+		},
+		{
+			name:     "calendar days across daylight saving time",
+			since:    time.Date(2026, 3, 28, 12, 0, 0, 0, berlin),
+			until:    time.Date(2026, 3, 30, 12, 0, 0, 0, berlin),
+			bucket:   24 * time.Hour,
+			location: berlin,
+			expected: []time.Time{
+				time.Date(2026, 3, 28, 12, 0, 0, 0, berlin),
+				time.Date(2026, 3, 29, 0, 0, 0, 0, berlin),
+				time.Date(2026, 3, 30, 0, 0, 0, 0, berlin),
+				time.Date(2026, 3, 30, 12, 0, 0, 0, berlin),
+			},
+		},
+		{
+			name:     "timezone of the bounds",
+			since:    time.Date(2026, 1, 1, 22, 0, 0, 0, time.UTC),
+			until:    time.Date(2026, 1, 2, 1, 0, 0, 0, time.UTC),
+			bucket:   24 * time.Hour,
+			location: berlin,
+			expected: []time.Time{
+				time.Date(2026, 1, 1, 23, 0, 0, 0, berlin),
+				time.Date(2026, 1, 2, 0, 0, 0, 0, berlin),
+				time.Date(2026, 1, 2, 2, 0, 0, 0, berlin),
+			},
+		},
+		{
+			name:     "until before since",
+			since:    t0.Add(time.Hour),
+			until:    t0,
+			bucket:   time.Hour,
+			location: time.UTC,
+			expected: []time.Time{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := bucketBounds(test.since, test.until, test.bucket, test.location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result) != len(test.expected) {
+				t.Fatalf("\n%v\n%v", result, test.expected)
+			}
+			for i := range result {
+				if !result[i].Equal(test.expected[i]) || result[i].Location() != test.location {
+					t.Fatalf("\n%v\n%v", result, test.expected)
+				}
+			}
+		})
+	}
+
+	_, err = bucketBounds(t0, t0.AddDate(1, 0, 0), time.Second, time.UTC)
+	if err == nil {
+		t.Error("expected error for too many buckets")
+	}
+}
+
+func TestCalcBuckets(t *testing.T) {
+	online, offline := true, false
+	bounds := []time.Time{t0, t0.Add(time.Hour), t0.Add(2 * time.Hour), t0.Add(3 * time.Hour)}
+	tests := []struct {
+		name     string
+		states   model.HistoricalStates
+		expected []model.Bucket
+	}{
+		{
+			name: "no states",
+			expected: []model.Bucket{
+				{Start: bounds[0], End: bounds[1]},
+				{Start: bounds[1], End: bounds[2]},
+				{Start: bounds[2], End: bounds[3]},
+			},
+		},
+		{
+			name: "states within and on bounds",
+			states: model.HistoricalStates{
+				PrevState: statePtr(-time.Hour, false),
+				States:    []model.State{state(30*time.Minute, true), state(time.Hour, false), state(90*time.Minute, true)},
+			},
+			expected: []model.Bucket{
+				{Start: bounds[0], End: bounds[1], OnlineRatio: 0.5, Transitions: 1, StartState: &offline},
+				{Start: bounds[1], End: bounds[2], OnlineRatio: 0.5, Transitions: 2, StartState: &offline},
+				{Start: bounds[2], End: bounds[3], OnlineRatio: 1, Transitions: 0, StartState: &online},
+			},
+		},
+		{
+			name:   "unknown start state",
+			states: model.HistoricalStates{States: []model.State{state(90*time.Minute, false)}},
+			expected: []model.Bucket{
+				{Start: bounds[0], End: bounds[1]},
+				{Start: bounds[1], End: bounds[2], OnlineRatio: 0, Transitions: 0},
+				{Start: bounds[2], End: bounds[3], OnlineRatio: 0, Transitions: 0, StartState: &offline},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := calcBuckets(test.states, bounds)
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("\n%#v\n%#v", result, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+type historyChunk struct {
+	kind string
+	ids  []string
+}
+
+// historyChunks splits the ids of each kind into chunks of at most HistoryChunkSize ids.
+func (this *Controller) historyChunks(idsByKind map[string][]string) []historyChunk {
+	size := int(this.config.HistoryChunkSize)
+	result := []historyChunk{}
+	for kind, ids := range idsByKind {
+		if len(ids) == 0 {
+			continue
+		}
+		if size <= 0 {
+			result = append(result, historyChunk{kind: kind, ids: ids})
+			continue
+		}
+		for chunk := range slices.Chunk(ids, size) {
+			result = append(result, historyChunk{kind: kind, ids: chunk})
+		}
+	}
+	return result
+}
+
+// queryHistoryChunks calls query for each chunk of idsByKind, at most HistoryQueryConcurrency queries run at the same time.
+// Results are passed to merge, which is never called concurrently. The first error cancels the context of the remaining queries and is returned.
+func queryHistoryChunks[T any](ctx context.Context, this *Controller, idsByKind map[string][]string, query func(ctx context.Context, kind string, ids []string) (T, error), merge func(T)) error {
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(max(int(this.config.HistoryQueryConcurrency), 1))
+	mux := sync.Mutex{}
+	for _, chunk := range this.historyChunks(idsByKind) {
+		group.Go(func() error {
+			result, err := query(ctx, chunk.kind, chunk.ids)
+			if err != nil {
+				return err
+			}
+			mux.Lock()
+			defer mux.Unlock()
+			merge(result)
+			return nil
+		})
+	}
+	return group.Wait()
+}
@@ -1,18 +1,16 @@
 package controller
 
 import (
+	"cmp"
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
 	"maps"
+	"slices"
+	"strings"
 	"time"
 
-	"log/slog"
-
 	"github.com/SENERGY-Platform/connection-log/pkg/model"
-	"github.com/influxdata/influxdb1-client/models"
-	influx "github.com/influxdata/influxdb1-client/v2"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
 )
 
 func (this *Controller) GetHistoricalStates(ctx context.Context, id, kind string, rng time.Duration, since, until time.Time) (model.ResourceHistoricalStates, error) {
@@ -52,233 +50,163 @@ func (this *Controller) QueryHistoricalStatesSlice(ctx context.Context, query mo
 	return sl, nil
 }
 
-func (this *Controller) QueryHistoricalStatesMap(_ context.Context, query model.QueryHistorical) (map[string]model.HistoricalStates, error) {
+func (this *Controller) QueryHistoricalStatesMap(ctx context.Context, query model.QueryHistorical) (map[string]model.HistoricalStates, error) {
 	idsBykind, err := GetIdsByKind(query.IDs, false)
 	if err != nil {
 		return nil, err
 	}
-	resMap := map[string]model.HistoricalStates{}
-	for kind, ids := range idsBykind {
-		query.IDs = ids
+	for kind := range idsBykind {
 		if err := validateKind(kind); err != nil {
 			return nil, err
 		}
-		if len(query.IDs) == 0 {
-			return nil, nil
-		}
-		statement, prevID, seriesID, nextID, err := this.buildStatement(query, kind)
-		if err != nil {
-			return nil, err
-		}
-		resp, err := this.influx.Query(influx.NewQuery(statement, this.config.InfluxdbDb, "s"))
-		if err != nil {
-			return nil, err
-		}
-		if err = resp.Error(); err != nil {
-			return nil, err
-		}
-		subResMap, err := handleResults(resp.Results, kind, prevID, seriesID, nextID)
-		if err != nil {
-			return nil, err
-		}
+	}
+	resMap := map[string]model.HistoricalStates{}
+	err = queryHistoryChunks(ctx, this, idsBykind, func(ctx context.Context, kind string, ids []string) (map[string]model.HistoricalStates, error) {
+		chunkQuery := query
+		chunkQuery.IDs = ids
+		storeCtx, call := this.startHistory(ctx, "QueryHistoricalStates")
+		subResMap, err := this.history.QueryHistoricalStates(storeCtx, chunkQuery, kind)
+		call.end(err)
+		return subResMap, err
+	}, func(subResMap map[string]model.HistoricalStates) {
 		maps.Copy(resMap, subResMap)
+	})
+	if err != nil {
+		return nil, err
 	}
 	return resMap, nil
 }
 
-func handleResults(results []influx.Result, kind string, prevID, seriesID, nextID int) (map[string]model.HistoricalStates, error) {
-	if len(results) == 0 {
-		return nil, errors.New("no results")
+// getFirstOnlineStates returns the first online state with time >= after of each id.
+func (this *Controller) getFirstOnlineStates(ctx context.Context, ids []string, after time.Time) (map[string]model.State, error) {
+	idsByKind, err := GetIdsByKind(ids, false)
+	if err != nil {
+		return nil, err
 	}
-	resMap := make(map[string]model.HistoricalStates)
-	for _, result := range results {
-		if result.Err != "" {
-			return nil, errors.New(result.Err)
-		}
-		switch result.StatementId {
-		case seriesID:
-			handleSeries(resMap, kind, result.Series, 0)
-		case prevID:
-			handleSeries(resMap, kind, result.Series, 1)
-		case nextID:
-			handleSeries(resMap, kind, result.Series, 2)
-		default:
-			return nil, fmt.Errorf("unknown statement id: %d", result.StatementId)
-		}
+	result := map[string]model.State{}
+	err = queryHistoryChunks(ctx, this, idsByKind, func(ctx context.Context, kind string, ids []string) (map[string]model.State, error) {
+		storeCtx, call := this.startHistory(ctx, "GetFirstOnlineStates")
+		states, err := this.history.GetFirstOnlineStates(storeCtx, ids, kind, after)
+		call.end(err)
+		return states, err
+	}, func(states map[string]model.State) {
+		maps.Copy(result, states)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
+// GetOfflineSince returns the offline timestamps of the given resources, sorted by offline timestamp.
+func (this *Controller) GetOfflineSince(ctx context.Context, ids []string, kind string, filter model.OfflineSinceFilter) ([]model.OfflineSinceResponse, error) {
+	if err := validateKind(kind); err != nil {
+		return nil, err
 	}
-	return resMap, nil
+	return this.getOfflineSince(ctx, map[string][]string{kind: ids}, filter)
 }
 
-func handleSeries(resMap map[string]model.HistoricalStates, kind string, series []models.Row, resType int) {
-	for _, row := range series {
-		if len(row.Values) == 0 {
-			continue
-		}
-		key, ok := row.Tags[kind]
-		if !ok {
-			continue
-		}
-		if err := handleRow(resMap, row.Values, key, resType); err != nil {
-			slog.Error("unable to handle row", "error", err)
-			continue
-		}
+// GetOfflineSinceMixed returns the offline timestamps of devices and gateways in one list, sorted by offline timestamp.
+func (this *Controller) GetOfflineSinceMixed(ctx context.Context, ids []string, filter model.OfflineSinceFilter) ([]model.OfflineSinceResponse, error) {
+	idsByKind, err := GetIdsByKind(ids, false)
+	if err != nil {
+		return nil, err
 	}
+	return this.getOfflineSince(ctx, idsByKind, filter)
 }
 
-func handleRow(resMap map[string]model.HistoricalStates, rowValues [][]any, key string, resType int) error {
-	resource := resMap[key]
-	if resType > 0 {
-		state, err := rowItemToState(rowValues[0])
-		if err != nil {
-			return err
-		}
-		switch resType {
-		case 1:
-			resource.PrevState = &state
-		case 2:
-			resource.NextState = &state
-		}
-	} else {
-		for _, item := range rowValues {
-			state, err := rowItemToState(item)
-			if err != nil {
-				slog.Error("unable to transform row item to state", "error", err)
-				continue
-			}
-			resource.States = append(resource.States, state)
-		}
+// getOfflineSince queries the chunks of all kinds and sorts the merged result by offline timestamp.
+// The maximum duration is passed to the history store, the minimum duration would hide newer online states from the store queries.
+func (this *Controller) getOfflineSince(ctx context.Context, idsByKind map[string][]string, filter model.OfflineSinceFilter) ([]model.OfflineSinceResponse, error) {
+	now := time.Now()
+	var notBefore time.Time
+	if filter.MaxOfflineDuration > 0 {
+		notBefore = now.Add(-time.Duration(filter.MaxOfflineDuration))
+	}
+	result := []model.OfflineSinceResponse{}
+	err := queryHistoryChunks(ctx, this, idsByKind, func(ctx context.Context, kind string, ids []string) ([]model.OfflineSinceResponse, error) {
+		storeCtx, call := this.startHistory(ctx, "GetOfflineSince")
+		states, err := this.history.GetOfflineSince(storeCtx, ids, kind, notBefore)
+		call.end(err)
+		return states, err
+	}, func(states []model.OfflineSinceResponse) {
+		result = append(result, states...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if filter.MinOfflineDuration > 0 {
+		threshold := now.Add(-time.Duration(filter.MinOfflineDuration))
+		result = slices.DeleteFunc(result, func(state model.OfflineSinceResponse) bool {
+			return state.OfflineSince.After(threshold)
+		})
 	}
-	resMap[key] = resource
-	return nil
+	slices.SortFunc(result, func(a, b model.OfflineSinceResponse) int {
+		return cmp.Or(a.OfflineSince.Compare(b.OfflineSince), strings.Compare(a.ID, b.ID))
+	})
+	return result, nil
 }
 
-func rowItemToState(item []any) (model.State, error) {
-	if len(item) < 2 {
-		return model.State{}, errors.New("invalid length")
+// GetResourcesHistory expects duration in influxdb format https://docs.influxdata.com/influxdb/v1.5/query_language/spec/#durations
+func (this *Controller) GetResourcesHistory(ctx context.Context, ids []string, kind string, duration string) (result interface{}, err error) {
+	if len(ids) == 0 {
+		return []interface{}{map[string]interface{}{"Series": []interface{}{}}}, nil
 	}
-	timeVal, ok := item[0].(json.Number)
-	if !ok {
-		return model.State{}, fmt.Errorf("invalid type: time=%t", item[0])
+	if err := validateResourcesQuery(kind, duration); err != nil {
+		return nil, err
 	}
-	timeInt, err := timeVal.Int64()
-	if err != nil {
-		return model.State{}, fmt.Errorf("time conversion failed: %s", err)
+	storeCtx, call := this.startHistory(ctx, "GetResourcesHistory")
+	result, err = this.history.GetResourcesHistory(storeCtx, ids, kind, duration)
+	call.end(err)
+	return result, err
+}
+
+func (this *Controller) GetResourcesLogstart(ctx context.Context, ids []string, kind string) (result map[string]float64, err error) {
+	if err := validateKind(kind); err != nil {
+		return nil, err
 	}
-	connected, ok := item[1].(bool)
-	if !ok {
-		return model.State{}, fmt.Errorf("invalid type: connected=%t", item[1])
+	result = map[string]float64{}
+	err = queryHistoryChunks(ctx, this, map[string][]string{kind: ids}, func(ctx context.Context, kind string, ids []string) (map[string]float64, error) {
+		storeCtx, call := this.startHistory(ctx, "GetResourcesLogstart")
+		chunkResult, err := this.history.GetResourcesLogstart(storeCtx, ids, kind)
+		call.end(err)
+		return chunkResult, err
+	}, func(chunkResult map[string]float64) {
+		maps.Copy(result, chunkResult)
+	})
+	if err != nil {
+		return nil, err
 	}
-	return model.State{
-		Time:      time.Unix(timeInt, 0).UTC(),
-		Connected: connected,
-	}, nil
+	return result, nil
 }
 
-func (this *Controller) buildStatement(query model.QueryHistorical, kind string) (string, int, int, int, error) {
-	hasRange := query.Range > 0
-	hasSince := !query.Since.IsZero()
-	hasUntil := !query.Until.IsZero()
-	switch {
-	case hasSince && hasUntil:
-		// Since && Until: time >= timestamp AND time <= timestamp
-		// include prev and next
-		prevQ, err := this.queries.StatePrevQuery(query.IDs, kind, query.Since)
-		if err != nil {
-			return "", 0, 0, 0, err
-		}
-		seriesQ, err := this.queries.StatesTimeGrtEqLesEqQuery(query.IDs, kind, query.Since, query.Until)
-		if err != nil {
-			return "", 0, 0, 0, err
-		}
-		nextQ, err := this.queries.StateNextQuery(query.IDs, kind, query.Until)
-		if err != nil {
-			return "", 0, 0, 0, err
-		}
-		return prevQ + seriesQ + nextQ, 0, 1, 2, nil
-	case hasRange && hasUntil:
-		// Range && Until: time >= (timestamp - duration) AND time <= timestamp
-		// include prev and next
-		since := query.Until.Add(time.Duration(query.Range) * -1)
-		prevQ, err := this.queries.StatePrevQuery(query.IDs, kind, since)
-		if err != nil {
-			return "", 0, 0, 0, err
-		}
-		seriesQ, err := this.queries.StatesTimeGrtEqLesEqQuery(query.IDs, kind, since, query.Until)
-		if err != nil {
-			return "", 0, 0, 0, err
-		}
-		nextQ, err := this.queries.StateNextQuery(query.IDs, kind, query.Until)
-		if err != nil {
-			return "", 0, 0, 0, err
-		}
-		return prevQ + seriesQ + nextQ, 0, 1, 2, nil
-	case hasRange && hasSince:
-		// Range && Since: time >= timestamp AND time <= (timestamp + duration)
-		// include prev and next
-		until := query.Since.Add(time.Duration(query.Range))
-		prevQ, err := this.queries.StatePrevQuery(query.IDs, kind, query.Since)
-		if err != nil {
-			return "", 0, 0, 0, err
-		}
-		seriesQ, err := this.queries.StatesTimeGrtEqLesEqQuery(query.IDs, kind, query.Since, until)
-		if err != nil {
-			return "", 0, 0, 0, err
-		}
-		nextQ, err := this.queries.StateNextQuery(query.IDs, kind, until)
-		if err != nil {
-			return "", 0, 0, 0, err
-		}
-		return prevQ + seriesQ + nextQ, 0, 1, 2, nil
-	case hasRange:
-		// Range: time >= (now - duration)
-		// include prev
-		timestamp := getCurrentTime(this.config.InfluxdbUseUTC).Add(time.Duration(query.Range) * -1)
-		prevQ, err := this.queries.StatePrevQuery(query.IDs, kind, timestamp)
-		if err != nil {
-			return "", 0, 0, 0, err
-		}
-		seriesQ, err := this.queries.StatesTimeGrtEqQuery(query.IDs, kind, timestamp)
-		if err != nil {
-			return "", 0, 0, 0, err
-		}
-		return prevQ + seriesQ, 0, 1, -1, nil
-	case hasUntil:
-		// Until: time <= timestamp
-		// include next
-		seriesQ, err := this.queries.StatesTimeLesEqQuery(query.IDs, kind, query.Until)
-		if err != nil {
-			return "", 0, 0, 0, err
-		}
-		nextQ, err := this.queries.StateNextQuery(query.IDs, kind, query.Until)
-		if err != nil {
-			return "", 0, 0, 0, err
-		}
-		return seriesQ + nextQ, -1, 0, 1, nil
-	case hasSince:
-		// Since: time >= timestamp
-		// include prev
-		prevQ, err := this.queries.StatePrevQuery(query.IDs, kind, query.Since)
-		if err != nil {
-			return "", 0, 0, 0, err
-		}
-		seriesQ, err := this.queries.StatesTimeGrtEqQuery(query.IDs, kind, query.Since)
-		if err != nil {
-			return "", 0, 0, 0, err
-		}
-		return prevQ + seriesQ, 0, 1, -1, nil
-	default:
-		seriesQ, err := this.queries.StatesTimeLesEqQuery(query.IDs, kind, getCurrentTime(this.config.InfluxdbUseUTC))
-		if err != nil {
-			return "", 0, 0, 0, err
-		}
-		return seriesQ, -1, 0, -1, nil
+func (this *Controller) GetResourcesLogEdge(ctx context.Context, ids []string, kind string, duration string) (result map[string]interface{}, err error) {
+	if len(ids) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	if err := validateResourcesQuery(kind, duration); err != nil {
+		return nil, err
+	}
+	result = map[string]interface{}{}
+	err = queryHistoryChunks(ctx, this, map[string][]string{kind: ids}, func(ctx context.Context, kind string, ids []string) (map[string]interface{}, error) {
+		storeCtx, call := this.startHistory(ctx, "GetResourcesLogEdge")
+		chunkResult, err := this.history.GetResourcesLogEdge(storeCtx, ids, kind, duration)
+		call.end(err)
+		return chunkResult, err
+	}, func(chunkResult map[string]interface{}) {
+		maps.Copy(result, chunkResult)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return result, nil
 }
 
-func getCurrentTime(utc bool) time.Time {
-	if utc {
-		return time.Now().UTC()
+// validateResourcesQuery checks the kind and duration of the old api, the history stores use both in their queries.
+func validateResourcesQuery(kind string, duration string) error {
+	if err := validateKind(kind); err != nil {
+		return err
 	}
-	return time.Now()
+	_, err := store.ParseInfluxDuration(duration)
+	return err
 }
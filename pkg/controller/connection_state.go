@@ -3,49 +3,109 @@ package controller
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
-	"time"
 
 	"github.com/SENERGY-Platform/connection-log/pkg/model"
 	"github.com/SENERGY-Platform/models/go/models"
-	"go.mongodb.org/mongo-driver/bson"
 )
 
 func (this *Controller) GetCurrentState(ctx context.Context, id, kind string) (model.ResourceCurrentState, error) {
 	if err := validateKind(kind); err != nil {
 		return model.ResourceCurrentState{}, err
 	}
-	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
-	defer cf()
-	res := this.getMongoDBCollection(kind).FindOne(ctxWt, bson.M{kind: id})
-	if err := res.Err(); err != nil {
-		return model.ResourceCurrentState{}, err
-	}
-	var item State
-	if err := res.Decode(&item); err != nil {
-		return model.ResourceCurrentState{}, err
+	storeCtx, call := this.startCurrent(ctx, "GetCurrentState")
+	state, err := this.current.GetCurrentState(storeCtx, id, kind)
+	call.end(err)
+	if err != nil {
+		return state, err
 	}
-	return model.ResourceCurrentState{
-		ID:        id,
-		Connected: item.Online,
-	}, nil
+	states := []model.ResourceCurrentState{state}
+	this.fillSince(ctx, states, kind)
+	return states[0], nil
 }
 
 func (this *Controller) QueryBaseStatesSlice(ctx context.Context, query model.QueryBase) ([]model.ResourceCurrentState, error) {
-	resMap, err := this.QueryBaseStatesMap(ctx, query)
+	idsBykind, err := GetIdsByKind(query.IDs, false)
 	if err != nil {
 		return nil, err
 	}
-	sl := make([]model.ResourceCurrentState, 0, len(resMap))
-	for id, state := range resMap {
-		sl = append(sl, model.ResourceCurrentState{
-			ID:        id,
-			Connected: state,
-		})
+	sl := []model.ResourceCurrentState{}
+	for kind, ids := range idsBykind {
+		states, err := this.listCurrentStates(ctx, ids, kind)
+		if err != nil {
+			return nil, err
+		}
+		sl = append(sl, states...)
 	}
 	return sl, nil
 }
 
+// GetOnlineSince returns the time of the last transition of all online resources, sorted by that time.
+// Resources without known transition time are omitted.
+func (this *Controller) GetOnlineSince(ctx context.Context, ids []string, kind string) ([]model.OnlineSinceResponse, error) {
+	states, err := this.listCurrentStates(ctx, ids, kind)
+	if err != nil {
+		return nil, err
+	}
+	result := []model.OnlineSinceResponse{}
+	for _, state := range states {
+		if state.Connected && state.Since != nil {
+			result = append(result, model.OnlineSinceResponse{ID: state.ID, OnlineSince: *state.Since})
+		}
+	}
+	slices.SortFunc(result, func(a, b model.OnlineSinceResponse) int {
+		return a.OnlineSince.Compare(b.OnlineSince)
+	})
+	return result, nil
+}
+
+func (this *Controller) listCurrentStates(ctx context.Context, ids []string, kind string) ([]model.ResourceCurrentState, error) {
+	if err := validateKind(kind); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []model.ResourceCurrentState{}, nil
+	}
+	storeCtx, call := this.startCurrent(ctx, "ListCurrentStates")
+	states, err := this.current.ListCurrentStates(storeCtx, ids, kind)
+	call.end(err)
+	if err != nil {
+		return nil, err
+	}
+	this.fillSince(ctx, states, kind)
+	return states, nil
+}
+
+// fillSince sets the missing transition times to the time of the latest history state, if it matches the current state.
+// Current states written by the connectionlog-worker do not contain the transition time.
+// The transition times are optional, errors of the history store are logged and leave them unset.
+func (this *Controller) fillSince(ctx context.Context, states []model.ResourceCurrentState, kind string) {
+	ids := []string{}
+	for _, state := range states {
+		if state.Since == nil {
+			ids = append(ids, state.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	storeCtx, call := this.startHistory(ctx, "GetLastStates")
+	last, err := this.history.GetLastStates(storeCtx, ids, kind)
+	call.end(err)
+	if err != nil {
+		this.config.GetLogger().Error("unable to get transition times", "kind", kind, "error", err)
+		return
+	}
+	for i, state := range states {
+		if l, ok := last[state.ID]; ok && state.Since == nil && l.Connected == state.Connected {
+			since := l.Time
+			states[i].Since = &since
+		}
+	}
+}
+
 func (this *Controller) QueryBaseStatesMap(ctx context.Context, query model.QueryBase) (map[string]bool, error) {
 	idsBykind, err := GetIdsByKind(query.IDs, false)
 	if err != nil {
@@ -53,35 +113,34 @@ func (this *Controller) QueryBaseStatesMap(ctx context.Context, query model.Quer
 	}
 	states := map[string]bool{}
 	for kind, ids := range idsBykind {
-		query.IDs = ids
-
 		if err := validateKind(kind); err != nil {
 			return nil, err
 		}
-		ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
-		defer cf()
-		cursor, err := this.getMongoDBCollection(kind).Find(ctxWt, bson.M{kind: bson.M{"$in": query.IDs}})
+		storeCtx, call := this.startCurrent(ctx, "QueryCurrentStates")
+		subStates, err := this.current.QueryCurrentStates(storeCtx, ids, kind)
+		call.end(err)
 		if err != nil {
 			return nil, err
 		}
-		for cursor.Next(ctx) {
-			var item State
-			if err = cursor.Decode(&item); err != nil {
-				return nil, err
-			}
-			if kind == model.GatewayKind {
-				states[item.GatewayID] = item.Online
-			} else {
-				states[item.DeviceID] = item.Online
-			}
-		}
-		if err = cursor.Err(); err != nil {
-			return nil, err
-		}
+		maps.Copy(states, subStates)
 	}
 	return states, nil
 }
 
+func (this *Controller) CheckDeviceOnlineStates(ctx context.Context, ids []string) (result map[string]bool, err error) {
+	storeCtx, call := this.startCurrent(ctx, "QueryCurrentStates")
+	result, err = this.current.QueryCurrentStates(storeCtx, ids, model.DeviceKind)
+	call.end(err)
+	return result, err
+}
+
+func (this *Controller) CheckGatewayOnlineStates(ctx context.Context, ids []string) (result map[string]bool, err error) {
+	storeCtx, call := this.startCurrent(ctx, "QueryCurrentStates")
+	result, err = this.current.QueryCurrentStates(storeCtx, ids, model.GatewayKind)
+	call.end(err)
+	return result, err
+}
+
 var permKindMap = map[string]string{
 	model.DeviceKind:  model.PermDeviceKind,
 	model.GatewayKind: model.PermGatewayKind,
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store/memory"
+	"github.com/SENERGY-Platform/models/go/models"
+)
+
+// testCurrent returns current states without transition time, like those written by the connectionlog-worker.
+type testCurrent struct {
+	*memory.Memory
+}
+
+func (this testCurrent) GetCurrentState(ctx context.Context, id, kind string) (model.ResourceCurrentState, error) {
+	state, err := this.Memory.GetCurrentState(ctx, id, kind)
+	state.Since = nil
+	return state, err
+}
+
+func (this testCurrent) ListCurrentStates(ctx context.Context, ids []string, kind string) ([]model.ResourceCurrentState, error) {
+	states, err := this.Memory.ListCurrentStates(ctx, ids, kind)
+	for i := range states {
+		states[i].Since = nil
+	}
+	return states, err
+}
+
+// testHistory fails GetLastStates with err, if set.
+type testHistory struct {
+	*memory.Memory
+	err error
+}
+
+func (this testHistory) GetLastStates(ctx context.Context, ids []string, kind string) (map[string]model.State, error) {
+	if this.err != nil {
+		return nil, this.err
+	}
+	return this.Memory.GetLastStates(ctx, ids, kind)
+}
+
+func TestFillSince(t *testing.T) {
+	id := models.DEVICE_PREFIX + "a"
+	mem := memory.New()
+	mem.Set(model.DeviceKind, id, state(time.Hour, true))
+	for _, historyErr := range []error{nil, errors.New("unavailable")} {
+		ctrl := NewWithStores(configuration.Config{LogLevel: "error"}, testCurrent{mem}, testHistory{Memory: mem, err: historyErr})
+		current, err := ctrl.GetCurrentState(context.Background(), id, model.DeviceKind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		states, err := ctrl.listCurrentStates(context.Background(), []string{id}, model.DeviceKind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(states) != 1 {
+			t.Fatalf("unexpected states %#v", states)
+		}
+		for _, result := range []model.ResourceCurrentState{current, states[0]} {
+			if !result.Connected {
+				t.Errorf("%v: expected online state", historyErr)
+			}
+			switch {
+			case historyErr != nil && result.Since != nil:
+				t.Errorf("%v: expected unknown transition time, got %v", historyErr, result.Since)
+			case historyErr == nil && (result.Since == nil || !result.Since.Equal(t0.Add(time.Hour))):
+				t.Errorf("%v: unexpected transition time %v", historyErr, result.Since)
+			}
+		}
+	}
+}
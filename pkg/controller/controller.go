@@ -2,59 +2,156 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
-	influx "github.com/influxdata/influxdb1-client/v2"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"github.com/SENERGY-Platform/connection-log/pkg/devicerepo"
+	"github.com/SENERGY-Platform/connection-log/pkg/metrics"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+	"github.com/SENERGY-Platform/connection-log/pkg/store/influxdb"
+	"github.com/SENERGY-Platform/connection-log/pkg/store/influxdb2"
+	"github.com/SENERGY-Platform/connection-log/pkg/store/memory"
+	"github.com/SENERGY-Platform/connection-log/pkg/store/mongodb"
+	"github.com/SENERGY-Platform/connection-log/pkg/store/postgres"
+	"github.com/SENERGY-Platform/connection-log/pkg/tracing"
+	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	BackendMongodb   = "mongodb"
+	BackendInfluxdb  = "influxdb"
+	BackendInfluxdb2 = "influxdb2"
+	BackendPostgres  = "postgres"
+	BackendMemory    = "memory"
 )
 
 type Controller struct {
-	config  configuration.Config
-	mongo   *mongo.Client
-	influx  influx.Client
-	queries *queryTemplates
+	config         configuration.Config
+	current        store.CurrentStateStore
+	history        store.HistoryStore
+	currentBackend string
+	historyBackend string
+	deviceRepo     deviceRepo.Interface
+	httpClient     *http.Client // of permission requests
+	userTokens     *tokenCache
 }
 
 func New(config configuration.Config) (ctrl *Controller, err error) {
-	qt, err := newQueryTemplates()
-	if err != nil {
-		return
+	var mem *memory.Memory
+	getMemory := func() *memory.Memory {
+		if mem == nil {
+			mem = memory.New()
+		}
+		return mem
 	}
-	ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
-	mongoClient, err := mongo.Connect(ctx, options.Client().ApplyURI(config.MongoUrl))
-	if err != nil {
-		return nil, err
+
+	var mongo *mongodb.Mongo
+	getMongo := func() (_ *mongodb.Mongo, err error) {
+		if mongo == nil {
+			mongo, err = mongodb.New(config)
+		}
+		return mongo, err
+	}
+
+	var current store.CurrentStateStore
+	switch config.CurrentStateBackend {
+	case "", BackendMongodb:
+		current, err = getMongo()
+	case BackendMemory:
+		current = getMemory()
+	default:
+		err = fmt.Errorf("unknown current state backend '%s'", config.CurrentStateBackend)
 	}
-	err = createIndexes(config, mongoClient)
 	if err != nil {
-		mongoClient.Disconnect(context.Background())
 		return nil, err
 	}
 
-	influxClient, err := influx.NewHTTPClient(influx.HTTPConfig{
-		Addr:     config.InfluxdbUrl,
-		Username: config.InfluxdbUser,
-		Password: config.InfluxdbPw,
-		Timeout:  time.Duration(config.InfluxdbTimeout) * time.Second,
-	})
+	var history store.HistoryStore
+	switch config.HistoryBackend {
+	case "", BackendInfluxdb:
+		history, err = influxdb.New(config)
+	case BackendInfluxdb2:
+		history, err = influxdb2.New(config)
+	case BackendPostgres:
+		history, err = postgres.New(config)
+	case BackendMongodb:
+		history, err = getMongo()
+		if err == nil {
+			err = mongo.CreateHistoryCollection()
+		}
+	case BackendMemory:
+		history = getMemory()
+	default:
+		err = fmt.Errorf("unknown history backend '%s'", config.HistoryBackend)
+	}
 	if err != nil {
-		mongoClient.Disconnect(context.Background())
+		current.Close()
 		return nil, err
 	}
 
+	return NewWithStores(config, current, history), nil
+}
+
+// NewWithStores creates a controller with the given backends, e.g. memory.New() for tests and local development.
+func NewWithStores(config configuration.Config, current store.CurrentStateStore, history store.HistoryStore) *Controller {
+	// an invalid timeout is reported by configuration.Load
+	timeout, _ := time.ParseDuration(config.HttpClientTimeout)
 	return &Controller{
-		mongo:   mongoClient,
-		influx:  influxClient,
-		config:  config,
-		queries: qt,
-	}, nil
+		config:         config,
+		current:        current,
+		history:        history,
+		currentBackend: backendName(config.CurrentStateBackend, BackendMongodb),
+		historyBackend: backendName(config.HistoryBackend, BackendInfluxdb),
+		deviceRepo:     devicerepo.New(config.DeviceRepoUrl, timeout),
+		httpClient:     &http.Client{Timeout: timeout, Transport: tracing.Transport(nil)},
+		userTokens:     &tokenCache{},
+	}
+}
+
+func backendName(configured string, defaultName string) string {
+	if configured == "" {
+		return defaultName
+	}
+	return configured
+}
+
+// storeCall records the duration and the result of a store query as metric and as span.
+type storeCall struct {
+	backend   string
+	operation string
+	start     time.Time
+	span      trace.Span
+}
+
+// startCurrent starts the measurement of a current state store query, the returned context contains the span of the query.
+func (this *Controller) startCurrent(ctx context.Context, operation string) (context.Context, *storeCall) {
+	return startStoreCall(ctx, this.currentBackend, operation)
+}
+
+// startHistory starts the measurement of a history store query, the returned context contains the span of the query.
+func (this *Controller) startHistory(ctx context.Context, operation string) (context.Context, *storeCall) {
+	return startStoreCall(ctx, this.historyBackend, operation)
+}
+
+func startStoreCall(ctx context.Context, backend string, operation string) (context.Context, *storeCall) {
+	ctx, span := tracing.Start(ctx, backend+" "+operation, attribute.String("db.system", backend), attribute.String("db.operation", operation))
+	return ctx, &storeCall{backend: backend, operation: operation, start: time.Now(), span: span}
+}
+
+func (this *storeCall) end(err error) {
+	metrics.ObserveStoreQuery(this.backend, this.operation, this.start, err)
+	tracing.End(this.span, err)
 }
 
 func (this *Controller) Close() {
-	this.config.GetLogger().Info("close mongo connection", "result", this.mongo.Disconnect(nil))
-	this.config.GetLogger().Info("close influx connection", "result", this.influx.Close())
+	this.config.GetLogger().Info("close current state store", "result", this.current.Close())
+	if any(this.history) != any(this.current) {
+		this.config.GetLogger().Info("close history store", "result", this.history.Close())
+	}
 }
 
 func (this *Controller) Config() *configuration.Config {
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+)
+
+var ErrDigestsNotSupported = errors.New("configured current state store does not support digests")
+var ErrInvalidDigestSubscription = errors.New("invalid digest subscription")
+
+func (this *Controller) digestStore() (store.DigestStore, error) {
+	digests, ok := this.current.(store.DigestStore)
+	if !ok {
+		return nil, ErrDigestsNotSupported
+	}
+	return digests, nil
+}
+
+// CheckDigests returns ErrDigestsNotSupported if the current state store can not keep digest subscriptions.
+func (this *Controller) CheckDigests() error {
+	_, err := this.digestStore()
+	return err
+}
+
+// ListDigestSubscriptions returns the subscriptions of owner, or all subscriptions if owner is empty.
+func (this *Controller) ListDigestSubscriptions(ctx context.Context, owner string) ([]model.DigestSubscription, error) {
+	digests, err := this.digestStore()
+	if err != nil {
+		return nil, err
+	}
+	return digests.ListDigestSubscriptions(ctx, owner)
+}
+
+// GetDigestSubscription returns store.ErrNotFound if the subscription does not exist or belongs to another owner.
+func (this *Controller) GetDigestSubscription(ctx context.Context, owner string, id string) (model.DigestSubscription, error) {
+	digests, err := this.digestStore()
+	if err != nil {
+		return model.DigestSubscription{}, err
+	}
+	subscription, err := digests.GetDigestSubscription(ctx, id)
+	if err != nil {
+		return model.DigestSubscription{}, err
+	}
+	if subscription.Owner != owner {
+		return model.DigestSubscription{}, store.ErrNotFound
+	}
+	return subscription, nil
+}
+
+// SetDigestSubscription creates or replaces a subscription of subscription.Owner.
+// New subscriptions get the first digest at the next scheduled time, replaced subscriptions keep their last sent time.
+// The members have to be resolved and permitted by the caller.
+func (this *Controller) SetDigestSubscription(ctx context.Context, subscription model.DigestSubscription) error {
+	digests, err := this.digestStore()
+	if err != nil {
+		return err
+	}
+	existing, err := digests.GetDigestSubscription(ctx, subscription.ID)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		now := time.Now().UTC().Truncate(time.Second)
+		subscription.LastSent = &now
+	case err != nil:
+		return err
+	case existing.Owner != subscription.Owner:
+		return store.ErrNotFound
+	default:
+		subscription.LastSent = existing.LastSent
+	}
+	if err = ValidateDigestSubscription(subscription); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidDigestSubscription, err)
+	}
+	return digests.SetDigestSubscription(ctx, subscription)
+}
+
+func (this *Controller) DeleteDigestSubscription(ctx context.Context, owner string, id string) error {
+	digests, err := this.digestStore()
+	if err != nil {
+		return err
+	}
+	if _, err = this.GetDigestSubscription(ctx, owner, id); err != nil {
+		return err
+	}
+	return digests.DeleteDigestSubscription(ctx, id)
+}
+
+func (this *Controller) SetDigestSent(ctx context.Context, id string, sent time.Time) error {
+	digests, err := this.digestStore()
+	if err != nil {
+		return err
+	}
+	return digests.SetDigestSent(ctx, id, sent)
+}
+
+func ValidateDigestSubscription(subscription model.DigestSubscription) error {
+	if subscription.ID == "" || subscription.Owner == "" {
+		return errors.New("missing subscription id or owner")
+	}
+	address, err := mail.ParseAddress(subscription.Email)
+	if err != nil {
+		return fmt.Errorf("invalid email: %w", err)
+	}
+	if address.Address != subscription.Email {
+		// the email is used as address and header of the digests
+		return fmt.Errorf("invalid email '%s', expected a plain address like '%s'", subscription.Email, address.Address)
+	}
+	if subscription.Frequency != model.DigestDaily && subscription.Frequency != model.DigestWeekly {
+		return fmt.Errorf("invalid frequency '%s'", subscription.Frequency)
+	}
+	if subscription.Hour < 0 || subscription.Hour > 23 {
+		return errors.New("hour must be between 0 and 23")
+	}
+	if subscription.Weekday < 0 || subscription.Weekday > 6 {
+		return errors.New("weekday must be between 0 and 6")
+	}
+	if _, err := time.LoadLocation(subscription.Timezone); err != nil {
+		return err
+	}
+	if len(subscription.IDs) == 0 {
+		return errors.New("missing ids")
+	}
+	return nil
+}
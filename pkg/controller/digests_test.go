@@ -0,0 +1,41 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"testing"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+func TestValidateDigestSubscriptionEmail(t *testing.T) {
+	subscription := model.DigestSubscription{ID: "id", Owner: "owner", Frequency: model.DigestDaily, IDs: []string{"device"}}
+	tests := map[string]bool{
+		"user@example.com":                       true,
+		"User <user@example.com>":                false,
+		"<user@example.com>":                     false,
+		"user@example.com (comment)":             false,
+		"user@example.com\r\nBcc: a@example.com": false,
+		"invalid":                                false,
+	}
+	for email, valid := range tests {
+		subscription.Email = email
+		if err := ValidateDigestSubscription(subscription); (err == nil) != valid {
+			t.Errorf("%q: unexpected error %v", email, err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+func ValidateDowntimesQuery(query model.QueryDowntimes) error {
+	if query.SortBy != "" && query.SortBy != model.DowntimeSortByStart && query.SortBy != model.DowntimeSortByDuration {
+		return fmt.Errorf("invalid sort_by '%s'", query.SortBy)
+	}
+	if query.Limit < 0 || query.Offset < 0 {
+		return fmt.Errorf("limit and offset must not be negative")
+	}
+	return nil
+}
+
+// QueryDowntimesMap lists the offline intervals of devices and gateways overlapping the queried time frame.
+func (this *Controller) QueryDowntimesMap(ctx context.Context, query model.QueryDowntimes) (map[string][]model.Downtime, error) {
+	if err := ValidateDowntimesQuery(query); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	states, err := this.QueryHistoricalStatesMap(ctx, query.QueryHistorical)
+	if err != nil {
+		return nil, err
+	}
+	recoveries, err := this.queryRecoveries(ctx, states)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string][]model.Downtime{}
+	for _, id := range query.IDs {
+		downtimes := []model.Downtime{}
+		for _, downtime := range calcDowntimes(states[id], recoveries[id], now) {
+			if downtime.Duration >= query.MinDuration {
+				downtimes = append(downtimes, downtime)
+			}
+		}
+		slices.SortStableFunc(downtimes, func(a, b model.Downtime) int {
+			var c int
+			if query.SortBy == model.DowntimeSortByDuration {
+				c = cmp.Compare(a.Duration, b.Duration)
+			} else {
+				c = a.Start.Compare(b.Start)
+			}
+			if query.SortDesc {
+				return -c
+			}
+			return c
+		})
+		downtimes = downtimes[min(query.Offset, len(downtimes)):]
+		if query.Limit > 0 {
+			downtimes = downtimes[:min(query.Limit, len(downtimes))]
+		}
+		result[id] = downtimes
+	}
+	return result, nil
+}
+
+// queryRecoveries looks up the first online state after the time frame for resources whose next state is offline,
+// as such a next state does not tell whether the interval is still ongoing.
+// The lookup starts at the earliest next state for all resources: the next state of a resource is its first state after the time frame,
+// so no resource has a state between the earliest next state and its own.
+func (this *Controller) queryRecoveries(ctx context.Context, states map[string]model.HistoricalStates) (map[string]time.Time, error) {
+	ids := []string{}
+	var after time.Time
+	for id, s := range states {
+		if s.NextState == nil || s.NextState.Connected {
+			continue
+		}
+		ids = append(ids, id)
+		if after.IsZero() || s.NextState.Time.Before(after) {
+			after = s.NextState.Time
+		}
+	}
+	result := map[string]time.Time{}
+	if len(ids) == 0 {
+		return result, nil
+	}
+	recoveries, err := this.getFirstOnlineStates(ctx, ids, after)
+	if err != nil {
+		return nil, err
+	}
+	for id, state := range recoveries {
+		result[id] = state.Time
+	}
+	return result, nil
+}
+
+// calcDowntimes merges consecutive offline states to intervals, ended by the next online state.
+// The previous and next state are used to find the real start and end of intervals crossing the time frame.
+// If the next state is offline as well, the interval ends with the recovery, the first online state after it.
+// Intervals are only reported as ongoing if no online state follows them.
+func calcDowntimes(states model.HistoricalStates, recovery time.Time, now time.Time) []model.Downtime {
+	all := []model.State{}
+	if states.PrevState != nil {
+		all = append(all, *states.PrevState)
+	}
+	all = append(all, states.States...)
+	result := []model.Downtime{}
+	var start *time.Time
+	closeAt := func(end time.Time) {
+		result = append(result, model.Downtime{Start: *start, End: &end, Duration: model.Duration(end.Sub(*start))})
+		start = nil
+	}
+	for i, state := range all {
+		switch {
+		case !state.Connected && start == nil:
+			start = &all[i].Time
+		case state.Connected && start != nil:
+			closeAt(state.Time)
+		}
+	}
+	if start == nil {
+		return result
+	}
+	if states.NextState != nil && states.NextState.Connected {
+		closeAt(states.NextState.Time)
+		return result
+	}
+	if states.NextState != nil && !recovery.IsZero() {
+		closeAt(recovery)
+		return result
+	}
+	return append(result, model.Downtime{Start: *start, Duration: model.Duration(now.Sub(*start)), Ongoing: true})
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+func TestCalcDowntimes(t *testing.T) {
+	now := t0.Add(10 * time.Hour)
+	end := func(offset time.Duration) *time.Time {
+		result := t0.Add(offset)
+		return &result
+	}
+	tests := []struct {
+		name     string
+		states   model.HistoricalStates
+		recovery time.Time
+		expected []model.Downtime
+	}{
+		{
+			name:     "no states",
+			expected: []model.Downtime{},
+		},
+		{
+			name:     "online",
+			states:   model.HistoricalStates{PrevState: statePtr(-time.Hour, true), States: []model.State{state(time.Hour, true)}},
+			expected: []model.Downtime{},
+		},
+		{
+			name:     "started before time frame",
+			states:   model.HistoricalStates{PrevState: statePtr(-time.Hour, false), States: []model.State{state(time.Hour, true)}},
+			expected: []model.Downtime{{Start: t0.Add(-time.Hour), End: end(time.Hour), Duration: model.Duration(2 * time.Hour)}},
+		},
+		{
+			name:     "consecutive offline states are merged",
+			states:   model.HistoricalStates{States: []model.State{state(time.Hour, false), state(2*time.Hour, false), state(3*time.Hour, true)}},
+			expected: []model.Downtime{{Start: t0.Add(time.Hour), End: end(3 * time.Hour), Duration: model.Duration(2 * time.Hour)}},
+		},
+		{
+			name: "multiple intervals",
+			states: model.HistoricalStates{States: []model.State{
+				state(time.Hour, false), state(2*time.Hour, true), state(4*time.Hour, false), state(7*time.Hour, true),
+			}},
+			expected: []model.Downtime{
+				{Start: t0.Add(time.Hour), End: end(2 * time.Hour), Duration: model.Duration(time.Hour)},
+				{Start: t0.Add(4 * time.Hour), End: end(7 * time.Hour), Duration: model.Duration(3 * time.Hour)},
+			},
+		},
+		{
+			name:     "ongoing",
+			states:   model.HistoricalStates{States: []model.State{state(time.Hour, true), state(2*time.Hour, false)}},
+			expected: []model.Downtime{{Start: t0.Add(2 * time.Hour), Duration: model.Duration(8 * time.Hour), Ongoing: true}},
+		},
+		{
+			name:     "ended by online next state",
+			states:   model.HistoricalStates{States: []model.State{state(2*time.Hour, false)}, NextState: statePtr(5*time.Hour, true)},
+			expected: []model.Downtime{{Start: t0.Add(2 * time.Hour), End: end(5 * time.Hour), Duration: model.Duration(3 * time.Hour)}},
+		},
+		{
+			name:     "offline next state ended by recovery",
+			states:   model.HistoricalStates{States: []model.State{state(2*time.Hour, false)}, NextState: statePtr(5*time.Hour, false)},
+			recovery: t0.Add(6 * time.Hour),
+			expected: []model.Downtime{{Start: t0.Add(2 * time.Hour), End: end(6 * time.Hour), Duration: model.Duration(4 * time.Hour)}},
+		},
+		{
+			name:     "offline next state without recovery",
+			states:   model.HistoricalStates{States: []model.State{state(2*time.Hour, false)}, NextState: statePtr(5*time.Hour, false)},
+			expected: []model.Downtime{{Start: t0.Add(2 * time.Hour), Duration: model.Duration(8 * time.Hour), Ongoing: true}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := calcDowntimes(test.states, test.recovery, now)
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("\n%#v\n%#v", result, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+var ErrTooManyIds = errors.New("too many ids")
+
+// QueryFlapping counts the transitions between online and offline of devices and gateways within the queried time frame.
+// Results are sorted by rate, worst offenders first.
+// Without 'since' or 'range' the configured default range is used, so a query never scans the whole history.
+func (this *Controller) QueryFlapping(ctx context.Context, query model.QueryFlapping) ([]model.Flapping, error) {
+	if query.Threshold < 0 || query.Limit < 0 {
+		return nil, errors.New("threshold and limit must not be negative")
+	}
+	if this.config.FlappingMaxIds > 0 && int64(len(query.IDs)) > this.config.FlappingMaxIds {
+		return nil, fmt.Errorf("%w: at most %d ids per flapping query", ErrTooManyIds, this.config.FlappingMaxIds)
+	}
+	if query.Since.IsZero() && query.Range == 0 {
+		rng, err := time.ParseDuration(this.config.FlappingDefaultRange)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FlappingDefaultRange: %w", err)
+		}
+		query.Range = model.Duration(rng)
+	}
+	threshold := query.Threshold
+	if threshold == 0 {
+		threshold = this.config.FlappingThreshold
+	}
+	now := time.Now()
+	states, err := this.QueryHistoricalStatesMap(ctx, query.QueryHistorical)
+	if err != nil {
+		return nil, err
+	}
+	since, until := availabilityWindow(query.QueryHistorical, now)
+	result := []model.Flapping{}
+	for _, id := range query.IDs {
+		transitions, hours := countTransitions(states[id], since, until)
+		item := model.Flapping{ID: id, Transitions: transitions}
+		if hours > 0 {
+			item.Rate = float64(transitions) / hours
+		}
+		item.Flapping = item.Rate > threshold
+		if query.OnlyFlapping && !item.Flapping {
+			continue
+		}
+		result = append(result, item)
+	}
+	slices.SortFunc(result, func(a, b model.Flapping) int {
+		return cmp.Or(cmp.Compare(b.Rate, a.Rate), cmp.Compare(b.Transitions, a.Transitions), strings.Compare(a.ID, b.ID))
+	})
+	if query.Limit > 0 {
+		result = result[:min(query.Limit, len(result))]
+	}
+	return result, nil
+}
+
+// countTransitions returns the number of state changes and the length of the time frame in hours.
+// The previous state is the starting point, repeated states are no transitions.
+func countTransitions(states model.HistoricalStates, since time.Time, until time.Time) (transitions int, hours float64) {
+	if since.IsZero() {
+		if len(states.States) == 0 {
+			return 0, 0
+		}
+		since = states.States[0].Time
+	}
+	var last *bool
+	if states.PrevState != nil {
+		last = &states.PrevState.Connected
+	}
+	for _, state := range states.States {
+		if last != nil && *last != state.Connected {
+			transitions++
+		}
+		last = &state.Connected
+	}
+	return transitions, max(until.Sub(since).Hours(), 0)
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+func TestCountTransitions(t *testing.T) {
+	tests := []struct {
+		name        string
+		states      model.HistoricalStates
+		since       time.Time
+		until       time.Time
+		transitions int
+		hours       float64
+	}{
+		{
+			name:  "no states",
+			since: t0,
+			until: t0.Add(2 * time.Hour),
+			hours: 2,
+		},
+		{
+			name:  "no states and zero since",
+			until: t0.Add(2 * time.Hour),
+		},
+		{
+			name: "previous state is the starting point",
+			states: model.HistoricalStates{
+				PrevState: statePtr(-time.Hour, true),
+				States:    []model.State{state(time.Hour, false), state(2*time.Hour, true), state(3*time.Hour, true), state(4*time.Hour, false)},
+			},
+			since:       t0,
+			until:       t0.Add(4 * time.Hour),
+			transitions: 3,
+			hours:       4,
+		},
+		{
+			name:        "without previous state",
+			states:      model.HistoricalStates{States: []model.State{state(time.Hour, true), state(2*time.Hour, false)}},
+			since:       t0,
+			until:       t0.Add(4 * time.Hour),
+			transitions: 1,
+			hours:       4,
+		},
+		{
+			name:        "repeated states",
+			states:      model.HistoricalStates{PrevState: statePtr(-time.Hour, false), States: []model.State{state(time.Hour, false), state(2*time.Hour, false)}},
+			since:       t0,
+			until:       t0.Add(time.Hour),
+			transitions: 0,
+			hours:       1,
+		},
+		{
+			name:        "zero since starts with first state",
+			states:      model.HistoricalStates{States: []model.State{state(time.Hour, true), state(2*time.Hour, false), state(3*time.Hour, true)}},
+			until:       t0.Add(5 * time.Hour),
+			transitions: 2,
+			hours:       4,
+		},
+		{
+			name:  "until before since",
+			since: t0.Add(time.Hour),
+			until: t0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transitions, hours := countTransitions(test.states, test.since, test.until)
+			if transitions != test.transitions || hours != test.hours {
+				t.Errorf("%v %v != %v %v", transitions, hours, test.transitions, test.hours)
+			}
+		})
+	}
+}
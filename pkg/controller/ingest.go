@@ -0,0 +1,140 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+)
+
+var ErrReadOnlyStore = errors.New("configured store does not support writes")
+var ErrBatchTooLarge = errors.New("too many events")
+
+// AddState records a state change of a device or gateway. The history is written first,
+// so a failed current state update can be repeated without losing the history point.
+// The status is model.IngestStatusDuplicate if the history already contains the state, model.IngestStatusOutdated
+// if the current state is newer than state and model.IngestStatusApplied otherwise.
+// The current state is updated for duplicates too, they may repeat an event whose current state update failed.
+func (this *Controller) AddState(ctx context.Context, id, kind string, state model.State) (status string, err error) {
+	if err = validateKind(kind); err != nil {
+		return "", err
+	}
+	if err = this.CheckWritable(); err != nil {
+		return "", err
+	}
+	storeCtx, call := this.startHistory(ctx, "AddHistoricalState")
+	added, err := this.history.(store.HistoryWriter).AddHistoricalState(storeCtx, id, kind, state)
+	call.end(err)
+	if err != nil {
+		return "", err
+	}
+	storeCtx, call = this.startCurrent(ctx, "SetCurrentState")
+	applied, err := this.current.(store.CurrentStateWriter).SetCurrentState(storeCtx, id, kind, state)
+	call.end(err)
+	switch {
+	case err != nil:
+		return "", err
+	case !added:
+		return model.IngestStatusDuplicate, nil
+	case applied:
+		return model.IngestStatusApplied, nil
+	default:
+		return model.IngestStatusOutdated, nil
+	}
+}
+
+// CheckWritable returns ErrReadOnlyStore if one of the configured stores can not be used by AddState.
+func (this *Controller) CheckWritable() error {
+	if _, ok := this.current.(store.CurrentStateWriter); !ok {
+		return ErrReadOnlyStore
+	}
+	if _, ok := this.history.(store.HistoryWriter); !ok {
+		return ErrReadOnlyStore
+	}
+	return nil
+}
+
+// IngestEvents stores a batch of connection events and reports the outcome per event, in request order.
+// Events need a time to be idempotent: repeating an event with the same id and time does not change the stored data.
+// The token needs write permission for every resource. Batches with more than IngestMaxBatchSize events are rejected with ErrBatchTooLarge.
+func (this *Controller) IngestEvents(ctx context.Context, token string, events []model.ConnectionEvent) ([]model.IngestResult, error) {
+	if err := this.CheckWritable(); err != nil {
+		return nil, err
+	}
+	if this.config.IngestMaxBatchSize > 0 && int64(len(events)) > this.config.IngestMaxBatchSize {
+		return nil, fmt.Errorf("%w: %d events, at most %d are allowed", ErrBatchTooLarge, len(events), this.config.IngestMaxBatchSize)
+	}
+	results := make([]model.IngestResult, len(events))
+	ids := []string{}
+	for i, event := range events {
+		results[i] = model.IngestResult{ID: event.ID, Kind: event.Kind, Time: event.Time}
+		kind, err := GetKindFromId(event.ID, false)
+		switch {
+		case event.ID == "":
+			results[i].Status, results[i].Error = model.IngestStatusInvalid, "missing id"
+		case event.Time.IsZero():
+			results[i].Status, results[i].Error = model.IngestStatusInvalid, "missing time"
+		case err != nil:
+			results[i].Status, results[i].Error = model.IngestStatusInvalid, err.Error()
+		case event.Kind != "" && event.Kind != kind:
+			results[i].Status, results[i].Error = model.IngestStatusInvalid, "kind does not match id"
+		default:
+			results[i].Kind = kind
+			ids = append(ids, event.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return results, nil
+	}
+	writable, err := this.WritableIDs(ctx, token, ids)
+	if err != nil {
+		return nil, err
+	}
+	type key struct {
+		id   string
+		time int64
+	}
+	seen := map[key]bool{}
+	for i := range results {
+		result := &results[i]
+		if result.Status != "" {
+			continue
+		}
+		if !writable[result.ID] {
+			result.Status = model.IngestStatusDenied
+			continue
+		}
+		k := key{id: result.ID, time: result.Time.UnixNano()}
+		if seen[k] {
+			result.Status = model.IngestStatusDuplicate
+			continue
+		}
+		seen[k] = true
+		status, err := this.AddState(ctx, result.ID, result.Kind, model.State{Time: result.Time, Connected: events[i].Connected})
+		if err != nil {
+			this.config.GetLogger().Error("unable to ingest event", "id", result.ID, "kind", result.Kind, "error", err)
+			result.Status, result.Error = model.IngestStatusFailed, err.Error()
+			continue
+		}
+		result.Status = status
+	}
+	return results, nil
+}
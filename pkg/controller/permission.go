@@ -17,16 +17,20 @@
 package controller
 
 import (
+	"context"
+	"maps"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/metrics"
 	"github.com/SENERGY-Platform/permissions-v2/pkg/client"
 )
 
-func (this *Controller) CheckRightList(token string, IDs []string, right string) (ok bool, err error) {
+func (this *Controller) CheckRightList(ctx context.Context, token string, IDs []string, right string) (ok bool, err error) {
 	idsByKind, err := GetIdsByKind(IDs, true)
 	if err != nil {
 		return false, err
 	}
 	for kind, ids := range idsByKind {
-		oks, err := CheckAccess(this.config.PermissionsV2Url, token, kind, ids)
+		oks, err := this.CheckAccess(ctx, token, kind, ids)
 		if err != nil {
 			return false, err
 		}
@@ -39,7 +43,7 @@ func (this *Controller) CheckRightList(token string, IDs []string, right string)
 	return true, err
 }
 
-func (this *Controller) PermissionsFilterIDs(token string, IDs []string) ([]string, error) {
+func (this *Controller) PermissionsFilterIDs(ctx context.Context, token string, IDs []string) ([]string, error) {
 	idsByKind, err := GetIdsByKind(IDs, true)
 	if err != nil {
 		return nil, err
@@ -47,18 +51,21 @@ func (this *Controller) PermissionsFilterIDs(token string, IDs []string) ([]stri
 	var okIDs []string
 	var nOkIDs []string
 	for kind, ids := range idsByKind {
-		result, err := CheckAccess(this.config.PermissionsV2Url, token, kind, ids)
+		result, err := this.CheckAccess(ctx, token, kind, ids)
 		if err != nil {
 			return nil, err
 		}
 
+		denied := 0
 		for id, ok := range result {
 			if ok {
 				okIDs = append(okIDs, id)
 			} else {
 				nOkIDs = append(nOkIDs, id)
+				denied++
 			}
 		}
+		metrics.PermissionDeniedIds.WithLabelValues(kind).Add(float64(denied))
 		if len(nOkIDs) > 0 {
 			this.config.GetLogger().Warn("access denied for IDs", "ids", nOkIDs)
 		}
@@ -66,16 +73,33 @@ func (this *Controller) PermissionsFilterIDs(token string, IDs []string) ([]stri
 	return okIDs, nil
 }
 
-func CheckAccess(permV2Url string, token string, kind string, ids []string) (result map[string]bool, err error) {
-	result, err, _ = client.New(permV2Url).CheckMultiplePermissions(token, kind, ids, client.Execute)
+// WritableIDs returns the permission to write each of the given device and gateway ids.
+func (this *Controller) WritableIDs(ctx context.Context, token string, IDs []string) (map[string]bool, error) {
+	idsByKind, err := GetIdsByKind(IDs, true)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]bool{}
+	for kind, ids := range idsByKind {
+		oks, err := this.checkPermissions(ctx, token, kind, ids, client.Write)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(result, oks)
+	}
+	return result, nil
+}
+
+func (this *Controller) CheckAccess(ctx context.Context, token string, kind string, ids []string) (result map[string]bool, err error) {
+	result, err = this.checkPermissions(ctx, token, kind, ids, client.Execute)
 	if err != nil {
 		return result, err
 	}
 	return result, nil
 }
 
-func (this *Controller) ListIds(token string, kind string) (ids []string, err error) {
-	ids, err, _ = client.New(this.config.PermissionsV2Url).ListAccessibleResourceIds(token, kind, client.ListOptions{}, client.Execute)
+func (this *Controller) ListIds(ctx context.Context, token string, kind string) (ids []string, err error) {
+	ids, err = this.listAccessibleIds(ctx, token, kind, client.Execute)
 	if err != nil {
 		return ids, err
 	}
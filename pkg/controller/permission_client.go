@@ -0,0 +1,56 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/metrics"
+	"github.com/SENERGY-Platform/connection-log/pkg/tracing"
+	"github.com/SENERGY-Platform/permissions-v2/pkg/client"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// checkPermissions is client.CheckMultiplePermissions with ctx.
+func (this *Controller) checkPermissions(ctx context.Context, token string, kind string, ids []string, permissions ...client.Permission) (result map[string]bool, err error) {
+	return permissionsCall(this, ctx, "CheckMultiplePermissions", kind, len(ids), func(permissionsClient client.Client) (map[string]bool, error, int) {
+		return permissionsClient.CheckMultiplePermissions(token, kind, ids, permissions...)
+	})
+}
+
+// listAccessibleIds is client.ListAccessibleResourceIds with ctx and without limit.
+func (this *Controller) listAccessibleIds(ctx context.Context, token string, kind string, permissions ...client.Permission) (ids []string, err error) {
+	return permissionsCall(this, ctx, "ListAccessibleResourceIds", kind, 0, func(permissionsClient client.Client) ([]string, error, int) {
+		return permissionsClient.ListAccessibleResourceIds(token, kind, client.ListOptions{}, permissions...)
+	})
+}
+
+// permissionsCall records a span and the request metrics and passes a permissions-v2 client to f,
+// which sends its requests bound to ctx and with the http client of the controller, so that the trace context is propagated to the permissions service.
+func permissionsCall[T any](this *Controller, ctx context.Context, operation string, kind string, idCount int, f func(permissionsClient client.Client) (T, error, int)) (result T, err error) {
+	ctx, span := tracing.Start(ctx, "permissions-v2 "+operation, attribute.String("kind", kind), attribute.Int("ids", idCount))
+	start := time.Now()
+	defer func() {
+		metrics.ObservePermissionRequest(operation, kind, start, err)
+		tracing.End(span, err)
+	}()
+	boundUrl, unbind := tracing.Bind(ctx, this.httpClient, this.config.PermissionsV2Url)
+	defer unbind()
+	result, err, _ = f(client.New(boundUrl))
+	return result, err
+}
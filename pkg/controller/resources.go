@@ -0,0 +1,304 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/devicerepo"
+	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/SENERGY-Platform/service-commons/pkg/jwt"
+)
+
+var ErrTokenExchangeDisabled = errors.New("missing AuthEndpoint, user tokens can not be requested")
+
+// ListChunkSize is the maximum number of ids per device-repository list request, larger lists exceed common url length limits.
+const ListChunkSize = 100
+
+// DeviceRepo returns the device-repository client of the controller.
+func (this *Controller) DeviceRepo() deviceRepo.Interface {
+	return this.deviceRepo
+}
+
+// ResolveDeviceIds replaces device-groups and locations with their device ids, other ids are kept.
+// deviceIdToInputId maps the device ids to the group or location they are resolved from.
+func ResolveDeviceIds(dr deviceRepo.Interface, token string, originalIds []string) (result []string, deviceIdToInputId map[string]string, err error) {
+	result = []string{}
+	deviceIdToInputId = map[string]string{}
+	for _, id := range originalIds {
+		if strings.HasPrefix(id, models.DEVICE_GROUP_PREFIX) {
+			deviceGroup, err, _ := dr.ReadDeviceGroup(id, token, false)
+			if err != nil {
+				return nil, nil, err
+			}
+			result = append(result, deviceGroup.DeviceIds...)
+			for _, deviceId := range deviceGroup.DeviceIds {
+				deviceIdToInputId[deviceId] = id
+			}
+		} else if strings.HasPrefix(id, models.LOCATION_PREFIX) {
+			location, err, _ := dr.GetLocation(id, token)
+			if err != nil {
+				return nil, nil, err
+			}
+			result = append(result, location.DeviceIds...)
+			for _, deviceId := range location.DeviceIds {
+				deviceIdToInputId[deviceId] = id
+			}
+			for _, deviceGroupId := range location.DeviceGroupIds {
+				deviceGroup, err, _ := dr.ReadDeviceGroup(deviceGroupId, token, false)
+				if err != nil {
+					return nil, nil, err
+				}
+				result = append(result, deviceGroup.DeviceIds...)
+				for _, deviceId := range deviceGroup.DeviceIds {
+					deviceIdToInputId[deviceId] = id
+				}
+			}
+		} else {
+			result = append(result, id)
+		}
+	}
+	return result, deviceIdToInputId, nil
+}
+
+// ResolveMembers maps each input id to its device ids, devices and gateways are mapped to themselves.
+// Unlike ResolveDeviceIds a device may belong to multiple input ids.
+func ResolveMembers(dr deviceRepo.Interface, token string, inputIds []string) (map[string][]string, error) {
+	members := map[string][]string{}
+	for _, id := range inputIds {
+		if !strings.HasPrefix(id, models.DEVICE_GROUP_PREFIX) && !strings.HasPrefix(id, models.LOCATION_PREFIX) {
+			members[id] = []string{id}
+			continue
+		}
+		ids, _, err := ResolveDeviceIds(dr, token, []string{id})
+		if err != nil {
+			return nil, err
+		}
+		slices.Sort(ids)
+		members[id] = slices.Compact(ids)
+	}
+	return members, nil
+}
+
+// ResourceNames maps the input ids of members and their device and hub ids to their names.
+func ResourceNames(dr deviceRepo.Interface, token string, members map[string][]string) (map[string]string, error, int) {
+	ids := []string{}
+	for id, memberIds := range members {
+		ids = append(ids, id)
+		ids = append(ids, memberIds...)
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	names, err, code := DeviceNames(dr, token, ids)
+	if err != nil {
+		return nil, err, code
+	}
+	hubs, err, code := HubNames(dr, token, ids)
+	if err != nil {
+		return nil, err, code
+	}
+	maps.Copy(names, hubs)
+	for id := range members {
+		switch {
+		case strings.HasPrefix(id, models.DEVICE_GROUP_PREFIX):
+			group, err, code := dr.ReadDeviceGroup(id, token, false)
+			if err != nil {
+				return nil, err, code
+			}
+			names[id] = group.Name
+		case strings.HasPrefix(id, models.LOCATION_PREFIX):
+			location, err, code := dr.GetLocation(id, token)
+			if err != nil {
+				return nil, err, code
+			}
+			names[id] = location.Name
+		}
+	}
+	return names, nil, http.StatusOK
+}
+
+// DeviceNames maps the device ids to the nickname or the name of the device, other ids are ignored.
+func DeviceNames(dr deviceRepo.Interface, token string, ids []string) (map[string]string, error, int) {
+	names := map[string]string{}
+	deviceIds := filterPrefix(ids, models.DEVICE_PREFIX)
+	if len(deviceIds) == 0 {
+		return names, nil, http.StatusOK
+	}
+	devices, err, code := ListDevices(dr, token, deviceIds)
+	if err != nil {
+		return nil, err, code
+	}
+	for _, device := range devices {
+		names[device.Id] = Nickname(device.Name, device.Attributes)
+	}
+	return names, nil, http.StatusOK
+}
+
+// HubNames maps the hub ids to the nickname or the name of the hub, other ids are ignored.
+func HubNames(dr deviceRepo.Interface, token string, ids []string) (map[string]string, error, int) {
+	names := map[string]string{}
+	hubIds := filterPrefix(ids, models.HUB_PREFIX)
+	if len(hubIds) == 0 {
+		return names, nil, http.StatusOK
+	}
+	hubs, err, code := ListHubs(dr, token, hubIds)
+	if err != nil {
+		return nil, err, code
+	}
+	for _, hub := range hubs {
+		names[hub.Id] = Nickname(hub.Name, hub.Attributes)
+	}
+	return names, nil, http.StatusOK
+}
+
+// ListDevices lists the devices of ids in requests of at most ListChunkSize ids, the ids are sent in the url.
+func ListDevices(dr deviceRepo.Interface, token string, ids []string) ([]models.Device, error, int) {
+	result := []models.Device{}
+	for chunk := range slices.Chunk(ids, ListChunkSize) {
+		devices, err, code := dr.ListDevices(token, deviceRepo.DeviceListOptions{Ids: chunk, Limit: int64(len(chunk))})
+		if err != nil {
+			return nil, err, code
+		}
+		result = append(result, devices...)
+	}
+	return result, nil, http.StatusOK
+}
+
+// ListHubs lists the hubs of ids in requests of at most ListChunkSize ids, the ids are sent in the url.
+func ListHubs(dr deviceRepo.Interface, token string, ids []string) ([]models.Hub, error, int) {
+	result := []models.Hub{}
+	for chunk := range slices.Chunk(ids, ListChunkSize) {
+		hubs, err, code := dr.ListHubs(token, deviceRepo.HubListOptions{Ids: chunk, Limit: int64(len(chunk))})
+		if err != nil {
+			return nil, err, code
+		}
+		result = append(result, hubs...)
+	}
+	return result, nil, http.StatusOK
+}
+
+func filterPrefix(ids []string, prefix string) []string {
+	result := []string{}
+	for _, id := range ids {
+		if strings.HasPrefix(id, prefix) {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// Nickname returns the "shared/nickname" attribute if set, otherwise name.
+func Nickname(name string, attributes []models.Attribute) string {
+	for _, a := range attributes {
+		if a.Key == "shared/nickname" && a.Value != "" {
+			name = a.Value
+		}
+	}
+	return name
+}
+
+// ResolveOwnerResources resolves ids with the current permissions of owner, as registered by webhooks, alert rules and digests.
+// Ids owner may no longer read are dropped, device-groups and locations are resolved to their current devices.
+func (this *Controller) ResolveOwnerResources(ctx context.Context, owner string, ids []string) ([]string, error) {
+	token, err := this.UserToken(owner)
+	if err != nil {
+		return nil, err
+	}
+	permitted, err := this.PermissionsFilterIDs(ctx, token, ids)
+	if err != nil {
+		return nil, err
+	}
+	resources, _, err := ResolveDeviceIds(devicerepo.WithContext(ctx, this.deviceRepo), token, permitted)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(resources)
+	return slices.Compact(resources), nil
+}
+
+// ResolveOwnerMembers is ResolveMembers with the current permissions of owner, including the names of the ids and members.
+// Ids owner may no longer read are dropped.
+func (this *Controller) ResolveOwnerMembers(ctx context.Context, owner string, ids []string) (members map[string][]string, names map[string]string, err error) {
+	token, err := this.UserToken(owner)
+	if err != nil {
+		return nil, nil, err
+	}
+	permitted, err := this.PermissionsFilterIDs(ctx, token, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	dr := devicerepo.WithContext(ctx, this.deviceRepo)
+	members, err = ResolveMembers(dr, token, permitted)
+	if err != nil {
+		return nil, nil, err
+	}
+	names, err, _ = ResourceNames(dr, token, members)
+	if err != nil {
+		return nil, nil, err
+	}
+	return members, names, nil
+}
+
+// UserToken returns a token of userId for background work without user request, e.g. permission checks of webhooks.
+// Tokens are requested with the token exchange of config.AuthEndpoint and cached until shortly before they expire.
+func (this *Controller) UserToken(userId string) (string, error) {
+	if this.config.AuthEndpoint == "" || this.config.AuthEndpoint == "-" {
+		return "", ErrTokenExchangeDisabled
+	}
+	return this.userTokens.get(userId, func() (string, time.Duration, error) {
+		token, expiration, err := jwt.ExchangeUserToken(this.config.AuthEndpoint, this.config.AuthClientId, this.config.AuthClientSecret, userId)
+		if err != nil {
+			return "", 0, err
+		}
+		return token.Jwt(), expiration, nil
+	})
+}
+
+type tokenCache struct {
+	mux    sync.Mutex
+	tokens map[string]cachedToken
+}
+
+type cachedToken struct {
+	token   string
+	expires time.Time
+}
+
+func (this *tokenCache) get(userId string, request func() (string, time.Duration, error)) (string, error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if cached, ok := this.tokens[userId]; ok && time.Now().Before(cached.expires) {
+		return cached.token, nil
+	}
+	token, expiration, err := request()
+	if err != nil {
+		return "", err
+	}
+	if this.tokens == nil {
+		this.tokens = map[string]cachedToken{}
+	}
+	this.tokens[userId] = cachedToken{token: token, expires: time.Now().Add(expiration)}
+	return token, nil
+}
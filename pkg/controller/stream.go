@@ -0,0 +1,46 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+)
+
+var ErrStreamNotSupported = errors.New("configured current state store does not support streams")
+
+// WatchCurrentStates opens a feed of state changes of the given device and gateway ids.
+// A non-empty resumeToken continues a previous feed after the change with this token.
+func (this *Controller) WatchCurrentStates(ctx context.Context, ids []string, resumeToken string) (<-chan model.StateChange, error) {
+	watcher, ok := this.current.(store.CurrentStateWatcher)
+	if !ok {
+		return nil, ErrStreamNotSupported
+	}
+	idsByKind, err := GetIdsByKind(ids, false)
+	if err != nil {
+		return nil, err
+	}
+	for kind := range idsByKind {
+		if err = validateKind(kind); err != nil {
+			return nil, err
+		}
+	}
+	return watcher.WatchCurrentStates(ctx, idsByKind, resumeToken)
+}
@@ -0,0 +1,214 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+)
+
+var ErrWebhooksNotSupported = errors.New("configured current state store does not support webhooks")
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+func (this *Controller) webhookStore() (store.WebhookStore, error) {
+	webhooks, ok := this.current.(store.WebhookStore)
+	if !ok {
+		return nil, ErrWebhooksNotSupported
+	}
+	return webhooks, nil
+}
+
+// CheckWebhooks returns ErrWebhooksNotSupported if the current state store can not keep webhooks.
+func (this *Controller) CheckWebhooks() error {
+	_, err := this.webhookStore()
+	return err
+}
+
+// ListWebhooks returns the webhooks of owner, or all webhooks if owner is empty.
+func (this *Controller) ListWebhooks(ctx context.Context, owner string) ([]model.Webhook, error) {
+	webhooks, err := this.webhookStore()
+	if err != nil {
+		return nil, err
+	}
+	return webhooks.ListWebhooks(ctx, owner)
+}
+
+// GetWebhook returns store.ErrNotFound if the webhook does not exist or belongs to another owner.
+func (this *Controller) GetWebhook(ctx context.Context, owner string, id string) (model.Webhook, error) {
+	webhooks, err := this.webhookStore()
+	if err != nil {
+		return model.Webhook{}, err
+	}
+	webhook, err := webhooks.GetWebhook(ctx, id)
+	if err != nil {
+		return model.Webhook{}, err
+	}
+	if webhook.Owner != owner {
+		return model.Webhook{}, store.ErrNotFound
+	}
+	return webhook, nil
+}
+
+// SetWebhook creates or replaces a webhook of webhook.Owner, an empty secret keeps the secret of the replaced webhook.
+// The resources have to be resolved and permitted by the caller.
+func (this *Controller) SetWebhook(ctx context.Context, webhook model.Webhook) error {
+	webhooks, err := this.webhookStore()
+	if err != nil {
+		return err
+	}
+	existing, err := webhooks.GetWebhook(ctx, webhook.ID)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+	case err != nil:
+		return err
+	case existing.Owner != webhook.Owner:
+		return store.ErrNotFound
+	case webhook.Secret == "":
+		webhook.Secret = existing.Secret
+	}
+	if err = ValidateWebhook(webhook); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidWebhook, err)
+	}
+	if err = this.CheckWebhookHost(ctx, webhook.URL); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidWebhook, err)
+	}
+	return webhooks.SetWebhook(ctx, webhook)
+}
+
+func (this *Controller) DeleteWebhook(ctx context.Context, owner string, id string) error {
+	webhooks, err := this.webhookStore()
+	if err != nil {
+		return err
+	}
+	if _, err = this.GetWebhook(ctx, owner, id); err != nil {
+		return err
+	}
+	return webhooks.DeleteWebhook(ctx, id)
+}
+
+// UpdateWebhookAlerts records offline events of resources and removes the alerts of resolved resources.
+func (this *Controller) UpdateWebhookAlerts(ctx context.Context, id string, alerts map[string]time.Time, resolved []string) error {
+	webhooks, err := this.webhookStore()
+	if err != nil {
+		return err
+	}
+	return webhooks.UpdateWebhookAlerts(ctx, id, alerts, resolved)
+}
+
+func ValidateWebhook(webhook model.Webhook) error {
+	if webhook.ID == "" || webhook.Owner == "" {
+		return errors.New("missing webhook id or owner")
+	}
+	u, err := url.Parse(webhook.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url '%s', expected absolute http or https url", webhook.URL)
+	}
+	if webhook.Secret == "" {
+		return errors.New("missing secret")
+	}
+	if webhook.Threshold <= 0 {
+		return errors.New("threshold must be positive")
+	}
+	if len(webhook.IDs) == 0 {
+		return errors.New("missing ids")
+	}
+	for _, id := range webhook.Resources {
+		if _, err = GetKindFromId(id, false); err != nil {
+			return fmt.Errorf("invalid resource '%s': %w", id, err)
+		}
+	}
+	return nil
+}
+
+// blockedNetworks are not reachable from the internet but not covered by the net.IP checks, e.g. shared address space of carrier-grade NAT.
+var blockedNetworks = mustParseNetworks("0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "240.0.0.0/4", "64:ff9b:1::/48")
+
+// CheckWebhookHost resolves the host of rawUrl and rejects it if any address is not allowed by CheckWebhookIP.
+// The sender checks the addresses again when it connects, so a changed DNS record can not bypass the check.
+func (this *Controller) CheckWebhookHost(ctx context.Context, rawUrl string) error {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	allowed, err := ParseNetworks(this.config.WebhookAllowedNetworks)
+	if err != nil {
+		return err
+	}
+	addresses, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("unable to resolve host '%s': %w", u.Hostname(), err)
+	}
+	for _, address := range addresses {
+		if err = CheckWebhookIP(net.IP(address.Unmap().AsSlice()), allowed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckWebhookIP rejects loopback, private, link-local and other non-public addresses, unless they are in one of the allowed networks.
+func CheckWebhookIP(ip net.IP, allowed []*net.IPNet) error {
+	if ip == nil {
+		return errors.New("invalid ip address")
+	}
+	for _, network := range allowed {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || ip.Equal(net.IPv4bcast) ||
+		slices.ContainsFunc(blockedNetworks, func(network *net.IPNet) bool { return network.Contains(ip) }) {
+		return fmt.Errorf("address %s is not public", ip)
+	}
+	return nil
+}
+
+// ParseNetworks parses CIDR notations like "10.0.0.0/8", e.g. of config.WebhookAllowedNetworks.
+func ParseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	result := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		if cidr == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network '%s': %w", cidr, err)
+		}
+		result = append(result, network)
+	}
+	return result, nil
+}
+
+func mustParseNetworks(cidrs ...string) []*net.IPNet {
+	result, err := ParseNetworks(cidrs)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
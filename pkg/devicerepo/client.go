@@ -0,0 +1,122 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package devicerepo binds the device-repository client to a context,
+// so that requests are canceled with the context and the trace context reaches the device-repository.
+package devicerepo
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/tracing"
+	deviceRepo "github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/models/go/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Client records a span for the calls of deviceRepo.Interface used by the service and sends them with its context and http client,
+// all other calls are passed to the device-repository client without context.
+type Client struct {
+	deviceRepo.Interface
+	baseUrl string
+	client  *http.Client
+	ctx     context.Context
+}
+
+// New creates a client with its own http client, requests end after timeout, 0 means no timeout.
+func New(baseUrl string, timeout time.Duration) *Client {
+	return &Client{
+		Interface: deviceRepo.NewClient(baseUrl, nil),
+		baseUrl:   baseUrl,
+		client:    &http.Client{Timeout: timeout, Transport: tracing.Transport(nil)},
+		ctx:       context.Background(),
+	}
+}
+
+// WithContext returns a copy of the client that sends its requests with ctx.
+func (this *Client) WithContext(ctx context.Context) deviceRepo.Interface {
+	c := *this
+	c.ctx = ctx
+	return &c
+}
+
+// WithContext binds the requests of dr to ctx, if dr supports it, e.g. the Client of this package.
+func WithContext(ctx context.Context, dr deviceRepo.Interface) deviceRepo.Interface {
+	if c, ok := dr.(interface {
+		WithContext(ctx context.Context) deviceRepo.Interface
+	}); ok {
+		return c.WithContext(ctx)
+	}
+	return dr
+}
+
+func (this *Client) ListDevices(token string, options deviceRepo.DeviceListOptions) ([]models.Device, error, int) {
+	return call(this, "ListDevices", attribute.Int("ids", len(options.Ids)), func(dr deviceRepo.Interface) ([]models.Device, error, int) {
+		return dr.ListDevices(token, options)
+	})
+}
+
+func (this *Client) ListHubs(token string, options deviceRepo.HubListOptions) ([]models.Hub, error, int) {
+	return call(this, "ListHubs", attribute.Int("ids", len(options.Ids)), func(dr deviceRepo.Interface) ([]models.Hub, error, int) {
+		return dr.ListHubs(token, options)
+	})
+}
+
+func (this *Client) ReadDevice(id string, token string, action deviceRepo.AuthAction) (models.Device, error, int) {
+	return call(this, "ReadDevice", attribute.String("id", id), func(dr deviceRepo.Interface) (models.Device, error, int) {
+		return dr.ReadDevice(id, token, action)
+	})
+}
+
+func (this *Client) ReadDeviceByLocalId(ownerId string, localId string, token string, action deviceRepo.AuthAction) (models.Device, error, int) {
+	return call(this, "ReadDeviceByLocalId", attribute.String("local_id", localId), func(dr deviceRepo.Interface) (models.Device, error, int) {
+		return dr.ReadDeviceByLocalId(ownerId, localId, token, action)
+	})
+}
+
+func (this *Client) ReadHub(id string, token string, action deviceRepo.AuthAction) (models.Hub, error, int) {
+	return call(this, "ReadHub", attribute.String("id", id), func(dr deviceRepo.Interface) (models.Hub, error, int) {
+		return dr.ReadHub(id, token, action)
+	})
+}
+
+func (this *Client) ReadDeviceGroup(id string, token string, filterGenericDuplicateCriteria bool) (models.DeviceGroup, error, int) {
+	return call(this, "ReadDeviceGroup", attribute.String("id", id), func(dr deviceRepo.Interface) (models.DeviceGroup, error, int) {
+		return dr.ReadDeviceGroup(id, token, filterGenericDuplicateCriteria)
+	})
+}
+
+func (this *Client) GetLocation(id string, token string) (models.Location, error, int) {
+	return call(this, "GetLocation", attribute.String("id", id), func(dr deviceRepo.Interface) (models.Location, error, int) {
+		return dr.GetLocation(id, token)
+	})
+}
+
+// call records a span and passes a device-repository client bound to the context and http client of this to f.
+// The status code is http.StatusGatewayTimeout if the context ends before the response.
+func call[T any](this *Client, operation string, attr attribute.KeyValue, f func(dr deviceRepo.Interface) (T, error, int)) (result T, err error, code int) {
+	ctx, span := tracing.Start(this.ctx, "device-repository "+operation, attr)
+	defer func() { tracing.End(span, err) }()
+	boundUrl, unbind := tracing.Bind(ctx, this.client, this.baseUrl)
+	defer unbind()
+	result, err, code = f(deviceRepo.NewClient(boundUrl, nil))
+	if err != nil && ctx.Err() != nil {
+		code = http.StatusGatewayTimeout
+	}
+	return result, err, code
+}
@@ -0,0 +1,254 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/notifier"
+)
+
+var ErrSmtpDisabled = errors.New("no smtp host configured")
+
+// Start sends the due digests, checked every config.DigestInterval until ctx is done.
+// Nothing is started if no SMTP host is configured, the current state store does not support digests
+// or no user tokens can be requested to check the permissions of the subscribers.
+func Start(ctx context.Context, config configuration.Config, ctrl *controller.Controller) error {
+	mailer := notifier.NewSMTP(config)
+	if !mailer.Enabled() {
+		return nil
+	}
+	if config.AuthEndpoint == "" || config.AuthEndpoint == "-" {
+		config.GetLogger().Warn("digests disabled", "reason", controller.ErrTokenExchangeDisabled)
+		return nil
+	}
+	if err := ctrl.CheckDigests(); err != nil {
+		if errors.Is(err, controller.ErrDigestsNotSupported) {
+			config.GetLogger().Warn("digests disabled", "reason", err)
+			return nil
+		}
+		return err
+	}
+	interval, err := time.ParseDuration(config.DigestInterval)
+	if err != nil {
+		return fmt.Errorf("invalid digest interval: %w", err)
+	}
+	go run(ctx, config, ctrl, mailer, interval)
+	return nil
+}
+
+func run(ctx context.Context, config configuration.Config, ctrl *controller.Controller, mailer *notifier.SMTP, interval time.Duration) {
+	config.GetLogger().Info("start digest scheduler", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		sendDue(ctx, config, ctrl, mailer, time.Now())
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			config.GetLogger().Info("stop digest scheduler")
+			return
+		}
+	}
+}
+
+func sendDue(ctx context.Context, config configuration.Config, ctrl *controller.Controller, mailer *notifier.SMTP, now time.Time) {
+	subscriptions, err := ctrl.ListDigestSubscriptions(ctx, "")
+	if err != nil {
+		config.GetLogger().Error("unable to list digest subscriptions", "error", err)
+		return
+	}
+	for _, subscription := range subscriptions {
+		location, err := time.LoadLocation(subscription.Timezone)
+		if err != nil {
+			config.GetLogger().Error("invalid digest timezone", "subscription", subscription.ID, "error", err)
+			continue
+		}
+		scheduled := lastSchedule(subscription, location, now)
+		if subscription.LastSent != nil && !subscription.LastSent.Before(scheduled) {
+			continue
+		}
+		// failed digests are repeated with the next check
+		if err = Send(ctx, ctrl, mailer, subscription, scheduled); err != nil {
+			config.GetLogger().Error("unable to send digest", "subscription", subscription.ID, "error", err)
+			continue
+		}
+		if err = ctrl.SetDigestSent(ctx, subscription.ID, scheduled); err != nil {
+			config.GetLogger().Error("unable to store digest sent time", "subscription", subscription.ID, "error", err)
+		}
+	}
+}
+
+// Send generates the digest of the period that ends with until and mails it to the subscriber.
+func Send(ctx context.Context, ctrl *controller.Controller, mailer *notifier.SMTP, subscription model.DigestSubscription, until time.Time) error {
+	if !mailer.Enabled() {
+		return ErrSmtpDisabled
+	}
+	report, err := Generate(ctx, ctrl, subscription, until)
+	if err != nil {
+		return err
+	}
+	subject, text, html, err := Render(report)
+	if err != nil {
+		return err
+	}
+	return mailer.Send(subscription.Email, subject, text, html)
+}
+
+// lastSchedule returns the latest delivery time of the subscription that is not after now.
+func lastSchedule(subscription model.DigestSubscription, location *time.Location, now time.Time) time.Time {
+	local := now.In(location)
+	scheduled := time.Date(local.Year(), local.Month(), local.Day(), subscription.Hour, 0, 0, 0, location)
+	days := 1
+	if subscription.Frequency == model.DigestWeekly {
+		days = 7
+		scheduled = scheduled.AddDate(0, 0, -((int(scheduled.Weekday()) - subscription.Weekday + 7) % 7))
+	}
+	for scheduled.After(now) {
+		scheduled = scheduled.AddDate(0, 0, -days)
+	}
+	return scheduled
+}
+
+type Report struct {
+	Subscription model.DigestSubscription
+	Since        time.Time
+	Until        time.Time
+	Offline      []OfflineItem
+	Availability []AvailabilityItem
+}
+
+type OfflineItem struct {
+	ID           string
+	Name         string
+	OfflineSince time.Time
+	OnlineSince  *time.Time // nil if still offline
+	Duration     time.Duration
+	New          bool // went offline within the report period
+}
+
+type AvailabilityItem struct {
+	ID         string
+	Name       string
+	Members    int
+	Percentage float64
+	Known      bool // false if no state is known within the report period
+	Online     time.Duration
+	Offline    time.Duration
+	Unknown    time.Duration
+}
+
+// Generate collects the offline intervals and the availability of each subscribed id for the day or week that ends with until.
+// The members and names of the subscribed ids are resolved with the current permissions of the subscriber.
+func Generate(ctx context.Context, ctrl *controller.Controller, subscription model.DigestSubscription, until time.Time) (Report, error) {
+	location, err := time.LoadLocation(subscription.Timezone)
+	if err != nil {
+		return Report{}, err
+	}
+	until = until.In(location)
+	since := until.AddDate(0, 0, -1)
+	if subscription.Frequency == model.DigestWeekly {
+		since = until.AddDate(0, 0, -7)
+	}
+	subscription.Members, subscription.Names, err = ctrl.ResolveOwnerMembers(ctx, subscription.Owner, subscription.IDs)
+	if err != nil {
+		return Report{}, err
+	}
+	report := Report{Subscription: subscription, Since: since, Until: until}
+	resources := []string{}
+	for _, members := range subscription.Members {
+		resources = append(resources, members...)
+	}
+	slices.Sort(resources)
+	resources = slices.Compact(resources)
+	if len(resources) == 0 {
+		return report, nil
+	}
+	period := model.QueryHistorical{
+		QueryBase: model.QueryBase{IDs: resources},
+		Since:     since,
+		Until:     until,
+	}
+
+	downtimes, err := ctrl.QueryDowntimesMap(ctx, model.QueryDowntimes{QueryHistorical: period})
+	if err != nil {
+		return Report{}, err
+	}
+	for _, id := range resources {
+		for _, downtime := range downtimes[id] {
+			item := OfflineItem{
+				ID:           id,
+				Name:         name(subscription, id),
+				OfflineSince: downtime.Start.In(location),
+				Duration:     time.Duration(downtime.Duration),
+				New:          !downtime.Start.Before(since),
+			}
+			if downtime.End != nil {
+				end := downtime.End.In(location)
+				item.OnlineSince = &end
+			} else {
+				item.Duration = max(until.Sub(downtime.Start), 0)
+			}
+			report.Offline = append(report.Offline, item)
+		}
+	}
+	slices.SortStableFunc(report.Offline, func(a, b OfflineItem) int {
+		return a.OfflineSince.Compare(b.OfflineSince)
+	})
+
+	availability, err := ctrl.QueryAvailabilityMap(ctx, period)
+	if err != nil {
+		return Report{}, err
+	}
+	for _, id := range subscription.IDs {
+		members, ok := subscription.Members[id]
+		if !ok {
+			// no longer permitted
+			continue
+		}
+		items := []model.Availability{}
+		for _, member := range members {
+			items = append(items, availability[member])
+		}
+		sum := controller.AggregateAvailability(items...)
+		report.Availability = append(report.Availability, AvailabilityItem{
+			ID:         id,
+			Name:       name(subscription, id),
+			Members:    len(members),
+			Percentage: sum.OnlineRatio * 100,
+			Known:      sum.Online+sum.Offline > 0,
+			Online:     time.Duration(sum.Online),
+			Offline:    time.Duration(sum.Offline),
+			Unknown:    time.Duration(sum.Unknown),
+		})
+	}
+	return report, nil
+}
+
+func name(subscription model.DigestSubscription, id string) string {
+	if name := subscription.Names[id]; name != "" {
+		return name
+	}
+	return id
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+const textStr = `Connection report {{date .Since}} - {{date .Until}}
+
+Offline ({{len .Offline}})
+{{range .Offline}}- {{.Name}}: offline {{date .OfflineSince}} - {{if .OnlineSince}}{{date .OnlineSince}}{{else}}still offline{{end}} ({{duration .Duration}}){{if .New}}, new{{end}}
+{{else}}No devices or hubs were offline.
+{{end}}
+Availability
+{{range .Availability}}- {{.Name}}: {{if .Known}}{{percent .Percentage}} online{{else}}no data{{end}}, offline {{duration .Offline}}{{if gt .Members 1}}, {{.Members}} devices{{end}}
+{{end}}`
+
+const htmlStr = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif">
+<h2>Connection report {{date .Since}} - {{date .Until}}</h2>
+<h3>Offline ({{len .Offline}})</h3>
+{{if .Offline}}<table cellpadding="4">
+<tr><th align="left">Name</th><th align="left">Offline since</th><th align="left">Online since</th><th align="left">Duration</th><th></th></tr>
+{{range .Offline}}<tr><td>{{.Name}}</td><td>{{date .OfflineSince}}</td><td>{{if .OnlineSince}}{{date .OnlineSince}}{{else}}still offline{{end}}</td><td>{{duration .Duration}}</td><td>{{if .New}}new{{end}}</td></tr>
+{{end}}</table>{{else}}<p>No devices or hubs were offline.</p>{{end}}
+<h3>Availability</h3>
+<table cellpadding="4">
+<tr><th align="left">Name</th><th align="left">Online</th><th align="left">Offline duration</th><th align="left">Devices</th></tr>
+{{range .Availability}}<tr><td>{{.Name}}</td><td>{{if .Known}}{{percent .Percentage}}{{else}}no data{{end}}</td><td>{{duration .Offline}}</td><td>{{.Members}}</td></tr>
+{{end}}</table>
+</body>
+</html>`
+
+var funcs = map[string]any{
+	"date": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04 MST")
+	},
+	"duration": formatDuration,
+	"percent": func(p float64) string {
+		return fmt.Sprintf("%.1f%%", p)
+	},
+}
+
+var textTemplate = texttemplate.Must(texttemplate.New("text").Funcs(funcs).Parse(textStr))
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(htmlStr))
+
+// formatDuration rounds to minutes, e.g. "2d 3h 15m".
+func formatDuration(d time.Duration) string {
+	minutes := int64(d.Round(time.Minute) / time.Minute)
+	parts := []string{}
+	for _, unit := range []struct {
+		minutes int64
+		suffix  string
+	}{{24 * 60, "d"}, {60, "h"}, {1, "m"}} {
+		if value := minutes / unit.minutes; value > 0 {
+			parts = append(parts, fmt.Sprintf("%d%s", value, unit.suffix))
+			minutes %= unit.minutes
+		}
+	}
+	if len(parts) == 0 {
+		return "0m"
+	}
+	return strings.Join(parts, " ")
+}
+
+// Render returns the subject and the plain text and HTML bodies of the report.
+func Render(report Report) (subject string, text string, html string, err error) {
+	subject = "Daily connection report"
+	if report.Subscription.Frequency == model.DigestWeekly {
+		subject = "Weekly connection report"
+	}
+	offline := map[string]bool{}
+	for _, item := range report.Offline {
+		offline[item.ID] = true
+	}
+	subject += fmt.Sprintf(": %d offline", len(offline))
+	buf := &bytes.Buffer{}
+	if err = textTemplate.Execute(buf, report); err != nil {
+		return "", "", "", err
+	}
+	text = buf.String()
+	buf.Reset()
+	if err = htmlTemplate.Execute(buf, report); err != nil {
+		return "", "", "", err
+	}
+	return subject, text, buf.String(), nil
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/segmentio/kafka-go"
+)
+
+const maxRetryWait = time.Minute
+
+// StartKafka consumes connection events from the device and gateway log topics until ctx is done.
+// Offsets are committed after the event is stored, so events are delivered at least once.
+// Nothing is started if config.KafkaUrl is empty.
+func StartKafka(ctx context.Context, config configuration.Config, ctrl *controller.Controller) error {
+	if config.KafkaUrl == "" || config.KafkaUrl == "-" {
+		return nil
+	}
+	if err := ctrl.CheckWritable(); err != nil {
+		return err
+	}
+	topics := map[string]string{
+		config.DeviceLogTopic:  model.DeviceKind,
+		config.GatewayLogTopic: model.GatewayKind,
+	}
+	for topic, kind := range topics {
+		if topic == "" || topic == "-" {
+			continue
+		}
+		reader := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:        strings.Split(config.KafkaUrl, ","),
+			GroupID:        config.KafkaConsumerGroup,
+			Topic:          topic,
+			CommitInterval: 0,
+			MaxWait:        time.Second,
+			Logger:         kafka.LoggerFunc(func(msg string, args ...any) {}),
+			ErrorLogger: kafka.LoggerFunc(func(msg string, args ...any) {
+				config.GetLogger().Error("kafka reader error", "topic", topic, "message", msg, "args", args)
+			}),
+		})
+		go consume(ctx, config, ctrl, reader, kind)
+	}
+	return nil
+}
+
+func consume(ctx context.Context, config configuration.Config, ctrl *controller.Controller, reader *kafka.Reader, kind string) {
+	defer reader.Close()
+	topic := reader.Config().Topic
+	config.GetLogger().Info("start kafka consumer", "topic", topic, "kind", kind)
+	retry := backoff{}
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if ctx.Err() != nil {
+			config.GetLogger().Info("stop kafka consumer", "topic", topic)
+			return
+		}
+		if err != nil {
+			wait := retry.next()
+			config.GetLogger().Error("unable to fetch kafka message, retry", "topic", topic, "wait", wait.String(), "error", err)
+			_ = sleep(ctx, wait)
+			continue
+		}
+		retry.reset()
+		if err = handle(ctx, config, ctrl, msg, kind); err != nil {
+			// only happens if ctx is done, the message is fetched again by the next consumer
+			return
+		}
+		if err = reader.CommitMessages(ctx, msg); err != nil {
+			config.GetLogger().Error("unable to commit kafka message", "topic", topic, "offset", msg.Offset, "error", err)
+		}
+	}
+}
+
+// handle stores the event of msg, storage errors are retried until ctx is done.
+// Invalid messages are logged and skipped.
+func handle(ctx context.Context, config configuration.Config, ctrl *controller.Controller, msg kafka.Message, kind string) error {
+	event := model.ConnectionEvent{}
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		config.GetLogger().Warn("skip invalid kafka message", "topic", msg.Topic, "offset", msg.Offset, "error", err)
+		return nil
+	}
+	if event.ID == "" {
+		config.GetLogger().Warn("skip kafka message without id", "topic", msg.Topic, "offset", msg.Offset)
+		return nil
+	}
+	if event.Time.IsZero() {
+		event.Time = msg.Time
+	}
+	retry := backoff{}
+	for {
+		_, err := ctrl.AddState(ctx, event.ID, kind, model.State{Time: event.Time, Connected: event.Connected})
+		if err == nil {
+			return nil
+		}
+		wait := retry.next()
+		config.GetLogger().Error("unable to store kafka message, retry", "topic", msg.Topic, "offset", msg.Offset, "wait", wait.String(), "error", err)
+		if err = sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// backoff doubles the wait between retries, starting with one second up to maxRetryWait.
+type backoff struct {
+	wait time.Duration
+}
+
+func (this *backoff) next() time.Duration {
+	this.wait = min(max(this.wait*2, time.Second), maxRetryWait)
+	return this.wait
+}
+
+func (this *backoff) reset() {
+	this.wait = 0
+}
+
+// sleep waits for d or until ctx is done, then it returns the error of ctx.
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "connection_log"
+
+var (
+	HttpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests per route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+
+	StoreQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "store_query_duration_seconds",
+		Help:      "Duration of queries to the current state and history backends.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"backend", "operation"})
+
+	StoreQueryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "store_query_errors_total",
+		Help:      "Failed queries to the current state and history backends.",
+	}, []string{"backend", "operation"})
+
+	PermissionRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "permission_request_duration_seconds",
+		Help:      "Duration of requests to the permissions service.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "kind"})
+
+	PermissionRequestErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "permission_request_errors_total",
+		Help:      "Failed requests to the permissions service.",
+	}, []string{"operation", "kind"})
+
+	PermissionDeniedIds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "permission_denied_ids_total",
+		Help:      "IDs removed from requests because the token has no access.",
+	}, []string{"kind"})
+)
+
+// ObserveStoreQuery records the duration of a backend query started at start and counts it as failed if err is set.
+func ObserveStoreQuery(backend string, operation string, start time.Time, err error) {
+	StoreQueryDuration.WithLabelValues(backend, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		StoreQueryErrors.WithLabelValues(backend, operation).Inc()
+	}
+}
+
+// ObservePermissionRequest records the duration of a permissions service request started at start and counts it as failed if err is set.
+func ObservePermissionRequest(operation string, kind string, start time.Time, err error) {
+	PermissionRequestDuration.WithLabelValues(operation, kind).Observe(time.Since(start).Seconds())
+	if err != nil {
+		PermissionRequestErrors.WithLabelValues(operation, kind).Inc()
+	}
+}
+
+// InstrumentHandler records the request durations of handler with the route label.
+// The returned handler keeps the flush and hijack support of the response writer.
+func InstrumentHandler(route string, handler http.Handler) http.Handler {
+	return promhttp.InstrumentHandlerDuration(HttpRequestDuration.MustCurryWith(prometheus.Labels{"route": route}), handler)
+}
+
+// Handler serves the metrics in the prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -18,8 +18,9 @@ const (
 )
 
 type ResourceCurrentState struct {
-	ID        string `json:"id"`
-	Connected bool   `json:"connected"`
+	ID        string     `json:"id"`
+	Connected bool       `json:"connected"`
+	Since     *time.Time `json:"since,omitempty"` // Time of the last transition in RFC 3339 format, omitted if unknown.
 }
 
 type ResourceHistoricalStates struct {
@@ -59,6 +60,259 @@ type QueryHistorical struct {
 	Until time.Time `json:"until"` // Timestamp in RFC 3339 format, can be combined with 'range' or 'since'.
 }
 
+type ConnectionEvent struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind,omitempty"` // "device" or "gateway", derived from the id if empty.
+	Connected bool      `json:"connected"`
+	Time      time.Time `json:"time"` // Timestamp in RFC 3339 format.
+}
+
+const (
+	IngestStatusApplied   = "applied"   // Stored in the history and set as current state.
+	IngestStatusOutdated  = "outdated"  // Stored in the history, the current state is newer.
+	IngestStatusDuplicate = "duplicate" // Same id and time as a previous event of the batch or an already stored state.
+	IngestStatusInvalid   = "invalid"
+	IngestStatusDenied    = "denied"
+	IngestStatusFailed    = "failed"
+)
+
+type IngestResult struct {
+	ID     string    `json:"id"`
+	Kind   string    `json:"kind"`
+	Time   time.Time `json:"time"`
+	Status string    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+}
+
+type Availability struct {
+	OnlineRatio float64  `json:"online_ratio"` // Online duration divided by the known (online and offline) duration.
+	Online      Duration `json:"online"`
+	Offline     Duration `json:"offline"`
+	Unknown     Duration `json:"unknown"` // Duration without a known state, e.g. before the first state of a resource.
+}
+
+const (
+	DowntimeSortByStart    = "start"
+	DowntimeSortByDuration = "duration"
+)
+
+type QueryDowntimes struct {
+	QueryHistorical
+	MinDuration Duration `json:"min_duration"` // Only intervals with at least this duration, e.g. 5m.
+	SortBy      string   `json:"sort_by"`      // "start" (default) or "duration".
+	SortDesc    bool     `json:"sort_desc"`
+	Limit       int      `json:"limit"` // Maximum number of intervals per ID, 0 means no limit.
+	Offset      int      `json:"offset"`
+}
+
+type Downtime struct {
+	Start    time.Time  `json:"start"`    // Time of the offline state, may precede the selected time frame.
+	End      *time.Time `json:"end"`      // Time of the next online state, null if ongoing.
+	Duration Duration   `json:"duration"` // Up to the current time if ongoing.
+	Ongoing  bool       `json:"ongoing"`
+}
+
+type QueryFlapping struct {
+	QueryHistorical
+	Threshold    float64 `json:"threshold"`     // State transitions per hour, defaults to the configured threshold.
+	OnlyFlapping bool    `json:"only_flapping"` // Only return resources above the threshold.
+	Limit        int     `json:"limit"`         // Maximum number of results, 0 means no limit.
+}
+
+type Flapping struct {
+	ID          string  `json:"id"`
+	Transitions int     `json:"transitions"` // Changes between online and offline within the selected time frame.
+	Rate        float64 `json:"rate"`        // Transitions per hour.
+	Flapping    bool    `json:"flapping"`    // Rate is above the threshold.
+}
+
+type QueryBuckets struct {
+	QueryHistorical
+	Bucket   Duration `json:"bucket"`   // Bucket size e.g. 1h or 24h, multiples of 24h are calendar days in the given timezone.
+	Timezone string   `json:"timezone"` // IANA timezone used to align buckets e.g. Europe/Berlin, defaults to UTC.
+}
+
+type Bucket struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	OnlineRatio float64   `json:"online_ratio"` // Online duration divided by the known duration within the bucket.
+	Transitions int       `json:"transitions"`
+	StartState  *bool     `json:"start_state"` // Connection state at bucket start, null if unknown.
+}
+
+type OnlineSinceResponse struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name,omitempty"`
+	OnlineSince time.Time `json:"online_since"`
+}
+
+const (
+	OfflineSinceSortByTime = "offline_since"
+	OfflineSinceSortByName = "name"
+	OfflineSinceSortByID   = "id"
+)
+
+type OfflineSinceFilter struct {
+	MinOfflineDuration Duration `json:"min_offline_duration"` // Only resources offline for at least this duration, e.g. 1h.
+	MaxOfflineDuration Duration `json:"max_offline_duration"` // Only resources offline for at most this duration, e.g. 720h.
+}
+
+type OfflineSinceOptions struct {
+	OfflineSinceFilter
+	SortBy   string `json:"sort_by"` // "offline_since" (default), "name" or "id".
+	SortDesc bool   `json:"sort_desc"`
+	Limit    int    `json:"limit"` // 0 means no limit, the total count is returned in the X-Total-Count header.
+	Offset   int    `json:"offset"`
+}
+
+type QueryOfflineSince struct {
+	QueryWithAttributeFilter
+	OfflineSinceOptions
+}
+
+type StateChange struct {
+	ID          string    `json:"id"`
+	Kind        string    `json:"kind"` // "device" or "gateway".
+	Connected   bool      `json:"connected"`
+	Time        time.Time `json:"time"` // Timestamp in RFC 3339 format.
+	ResumeToken string    `json:"-"`    // Continues the stream after this change.
+}
+
+const (
+	SubscriptionSubscribe   = "subscribe"   // Client request, adds ids to the watched set.
+	SubscriptionUnsubscribe = "unsubscribe" // Client request, removes ids from the watched set.
+	SubscriptionSnapshot    = "snapshot"    // Current states of newly subscribed ids.
+	SubscriptionState       = "state"       // State change of a watched resource.
+	SubscriptionHeartbeat   = "heartbeat"
+	SubscriptionError       = "error"
+)
+
+type SubscriptionRequest struct {
+	Type string   `json:"type"` // "subscribe" or "unsubscribe".
+	IDs  []string `json:"ids"`  // Device, hub, device-group or location IDs.
+}
+
+type SubscriptionMessage struct {
+	Type   string          `json:"type"`
+	IDs    []string        `json:"ids,omitempty"`    // Requested IDs the message refers to.
+	States map[string]bool `json:"states,omitempty"` // Current states by device or hub id.
+	Change *StateChange    `json:"change,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type Webhook struct {
+	ID        string   `json:"id"`
+	Owner     string   `json:"owner"` // User id of the creator, set by the service.
+	URL       string   `json:"url"`
+	Secret    string   `json:"secret,omitempty"` // Key of the HMAC-SHA256 signature in the X-Signature-256 header, never returned.
+	IDs       []string `json:"ids"`              // Device, hub, device-group or location IDs.
+	Threshold Duration `json:"threshold"`        // Offline duration before the webhook is called, e.g. "15m".
+
+	Resources []string             `json:"-"` // Device and hub ids, resolved from IDs on registration, the evaluation resolves IDs again.
+	Alerts    map[string]time.Time `json:"-"` // Offline since of resources with delivered offline event.
+}
+
+const (
+	WebhookEventOffline = "offline"
+	WebhookEventOnline  = "online"
+)
+
+type WebhookEvent struct {
+	Type         string    `json:"type"` // "offline" or "online".
+	WebhookID    string    `json:"webhook_id"`
+	ID           string    `json:"id"`
+	Kind         string    `json:"kind"`          // "device" or "gateway".
+	OfflineSince time.Time `json:"offline_since"` // Timestamp in RFC 3339 format.
+	Time         time.Time `json:"time"`          // Time of the detection, in RFC 3339 format.
+}
+
+const (
+	AlertConditionOffline      = "offline"
+	AlertConditionFlapping     = "flapping"
+	AlertConditionAvailability = "availability"
+)
+
+const (
+	AlertSeverityInfo     = "info"
+	AlertSeverityWarning  = "warning"
+	AlertSeverityCritical = "critical"
+)
+
+type AlertCondition struct {
+	Type         string   `json:"type"`                   // "offline", "flapping" or "availability".
+	Duration     Duration `json:"duration,omitempty"`     // offline: minimum offline duration, e.g. "15m".
+	Transitions  float64  `json:"transitions,omitempty"`  // flapping: state transitions per hour that have to be exceeded.
+	Availability float64  `json:"availability,omitempty"` // availability: online percentage the resource has to fall below.
+	Window       Duration `json:"window,omitempty"`       // flapping and availability: evaluated time range up to now, e.g. "24h".
+}
+
+const AlertChannelWebhook = "webhook"
+
+type AlertChannel struct {
+	Type string `json:"type"` // "webhook".
+	ID   string `json:"id"`   // Id of a webhook of the rule owner.
+}
+
+type AlertRule struct {
+	ID        string         `json:"id"`
+	Owner     string         `json:"owner"` // User id of the creator, set by the service.
+	Name      string         `json:"name"`
+	IDs       []string       `json:"ids"` // Device, hub, device-group or location IDs.
+	Condition AlertCondition `json:"condition"`
+	Severity  string         `json:"severity"` // "info", "warning" or "critical".
+	Channels  []AlertChannel `json:"channels"` // Notified when an alert fires or is resolved.
+
+	Resources []string `json:"-"` // Device and hub ids, resolved from IDs on registration, the evaluation resolves IDs again.
+}
+
+const (
+	AlertStateFiring       = "firing"
+	AlertStateAcknowledged = "acknowledged" // Still active, the condition is met.
+	AlertStateResolved     = "resolved"
+)
+
+type Alert struct {
+	ID             string     `json:"id"`
+	RuleID         string     `json:"rule_id"`
+	Owner          string     `json:"owner"`
+	ResourceID     string     `json:"resource_id"`
+	Kind           string     `json:"kind"` // "device" or "gateway".
+	Condition      string     `json:"condition"`
+	Severity       string     `json:"severity"`
+	State          string     `json:"state"`           // "firing", "acknowledged" or "resolved".
+	Value          float64    `json:"value,omitempty"` // Transitions per hour for flapping, online percentage for availability.
+	FiredAt        time.Time  `json:"fired_at"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty"`
+}
+
+type AlertFilter struct {
+	Owner  string   // All owners if empty.
+	RuleID string   // All rules if empty.
+	States []string // All states if empty.
+}
+
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+type DigestSubscription struct {
+	ID        string     `json:"id"`
+	Owner     string     `json:"owner"` // User id of the creator, set by the service.
+	Email     string     `json:"email"`
+	Frequency string     `json:"frequency"`           // "daily" or "weekly".
+	Hour      int        `json:"hour"`                // Local hour of the delivery, 0 to 23.
+	Weekday   int        `json:"weekday"`             // Day of weekly deliveries, 0 (Sunday) to 6.
+	Timezone  string     `json:"timezone,omitempty"`  // IANA time zone of hour and weekday, default UTC.
+	IDs       []string   `json:"ids"`                 // Device, hub, device-group or location IDs.
+	LastSent  *time.Time `json:"last_sent,omitempty"` // Set by the service.
+
+	Members map[string][]string `json:"-"` // Device and hub ids of each requested id, resolved on registration and again for each digest.
+	Names   map[string]string   `json:"-"` // Names of the requested ids and their members, resolved on registration and again for each digest.
+}
+
 type OfflineSinceResponse struct {
 	ID           string    `json:"id"`
 	Name         string    `json:"name,omitempty"`
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notifier
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+)
+
+// SMTP sends emails with a plain text and an HTML part.
+// STARTTLS is used if the server supports it, authentication only if config.SmtpUser is set.
+type SMTP struct {
+	config configuration.Config
+}
+
+func NewSMTP(config configuration.Config) *SMTP {
+	return &SMTP{config: config}
+}
+
+// Enabled returns false if no SMTP host is configured.
+func (this *SMTP) Enabled() bool {
+	return this.config.SmtpHost != "" && this.config.SmtpHost != "-"
+}
+
+// Send mails the message to the plain address to, addresses with display names or comments are rejected.
+// The whole SMTP session, including the connect, has to finish within config.SmtpTimeout.
+func (this *SMTP) Send(to string, subject string, text string, html string) error {
+	if err := checkAddress(to); err != nil {
+		return err
+	}
+	from, err := mail.ParseAddress(this.config.SmtpFrom)
+	if err != nil {
+		return fmt.Errorf("invalid smtp from address: %w", err)
+	}
+	timeout, err := time.ParseDuration(this.config.SmtpTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid smtp timeout: %w", err)
+	}
+	msg, err := this.message(to, subject, text, html)
+	if err != nil {
+		return err
+	}
+	conn, err := (&net.Dialer{Timeout: timeout}).Dial("tcp", net.JoinHostPort(this.config.SmtpHost, this.config.SmtpPort))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, this.config.SmtpHost)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: this.config.SmtpHost}); err != nil {
+			return err
+		}
+	}
+	if this.config.SmtpUser != "" {
+		if err = client.Auth(smtp.PlainAuth("", this.config.SmtpUser, this.config.SmtpPassword, this.config.SmtpHost)); err != nil {
+			return err
+		}
+	}
+	if err = client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err = client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// checkAddress returns an error if address is not a plain email address like "user@example.com", it is used as header and SMTP address.
+func checkAddress(address string) error {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return fmt.Errorf("invalid email: %w", err)
+	}
+	if parsed.Address != address {
+		return fmt.Errorf("invalid email '%s', expected a plain address like '%s'", address, parsed.Address)
+	}
+	return nil
+}
+
+func (this *SMTP) message(to string, subject string, text string, html string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	body := multipart.NewWriter(buf)
+	header := []string{
+		"From: " + this.config.SmtpFrom,
+		"To: " + to,
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + body.Boundary(),
+	}
+	buf.WriteString(strings.Join(header, "\r\n") + "\r\n\r\n")
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err = qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err = qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, fmt.Errorf("unable to build email: %w", err)
+	}
+	return buf.Bytes(), nil
+}
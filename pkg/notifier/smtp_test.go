@@ -0,0 +1,164 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notifier
+
+import (
+	"bufio"
+	"mime"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+)
+
+// testMail is a mail received by smtpSink.
+type testMail struct {
+	from string
+	to   []string
+	data string
+}
+
+// smtpSink accepts mails without STARTTLS and authentication, received mails are sent to mails.
+// A silent sink accepts connections without greeting.
+func smtpSink(t *testing.T, silent bool) (config configuration.Config, mails chan testMail) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	mails = make(chan testMail, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			if silent {
+				t.Cleanup(func() { conn.Close() })
+				continue
+			}
+			go serveSmtp(conn, mails)
+		}
+	}()
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	return configuration.Config{SmtpHost: host, SmtpPort: port, SmtpFrom: "Connection Log <connection-log@localhost>", SmtpTimeout: "1s"}, mails
+}
+
+func serveSmtp(conn net.Conn, mails chan<- testMail) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		_, _ = conn.Write([]byte(line + "\r\n"))
+	}
+	reply("220 localhost")
+	current := testMail{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			current.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			reply("250 ok")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			current.to = append(current.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			reply("250 ok")
+		case command == "DATA":
+			reply("354 go ahead")
+			data := strings.Builder{}
+			for {
+				line, err = reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			current.data = data.String()
+			mails <- current
+			current = testMail{}
+			reply("250 ok")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	config, mails := smtpSink(t, false)
+	err := NewSMTP(config).Send("user@example.com", "Connection report ä", "text content", "<p>html content</p>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := <-mails
+	if received.from != "connection-log@localhost" || len(received.to) != 1 || received.to[0] != "user@example.com" {
+		t.Errorf("unexpected envelope %v %v", received.from, received.to)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(received.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if to := msg.Header.Get("To"); to != "user@example.com" {
+		t.Errorf("unexpected To header %q", to)
+	}
+	if subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); err != nil || subject != "Connection report ä" {
+		t.Errorf("unexpected subject %q %v", subject, err)
+	}
+	if !strings.Contains(received.data, "text content") || !strings.Contains(received.data, "<p>html content</p>") {
+		t.Errorf("missing content in %v", received.data)
+	}
+}
+
+func TestSendRejectsAddresses(t *testing.T) {
+	config, mails := smtpSink(t, false)
+	for _, to := range []string{"User <user@example.com>", "user@example.com\r\nBcc: other@example.com", "user@example.com (comment)", "invalid"} {
+		if err := NewSMTP(config).Send(to, "subject", "text", "html"); err == nil {
+			t.Errorf("expected error for %q", to)
+		}
+	}
+	select {
+	case received := <-mails:
+		t.Errorf("unexpected mail %v", received)
+	default:
+	}
+}
+
+func TestSendTimeout(t *testing.T) {
+	config, _ := smtpSink(t, true)
+	config.SmtpTimeout = "100ms"
+	start := time.Now()
+	if err := NewSMTP(config).Send("user@example.com", "subject", "text", "html"); err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if duration := time.Since(start); duration > time.Second {
+		t.Errorf("send took %v", duration)
+	}
+}
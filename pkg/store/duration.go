@@ -0,0 +1,70 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var influxDurationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"u":  time.Microsecond,
+	"µ":  time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+}
+
+var influxDurationPart = regexp.MustCompile(`(\d+)(ns|ms|u|µ|s|m|h|d|w)`)
+
+// ParseInfluxDuration parses a duration literal in influxdb format (e.g. 30m, 1d, 1h30m)
+// https://docs.influxdata.com/influxdb/v1.5/query_language/spec/#durations
+func ParseInfluxDuration(str string) (result time.Duration, err error) {
+	if str == "" {
+		return 0, fmt.Errorf("invalid duration '%s'", str)
+	}
+	consumed := 0
+	for _, match := range influxDurationPart.FindAllStringSubmatchIndex(str, -1) {
+		if match[0] != consumed {
+			return 0, fmt.Errorf("invalid duration '%s'", str)
+		}
+		value, err := strconv.ParseInt(str[match[2]:match[3]], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration '%s': %w", str, err)
+		}
+		result += time.Duration(value) * influxDurationUnits[str[match[4]:match[5]]]
+		consumed = match[1]
+	}
+	if consumed != len(str) {
+		return 0, fmt.Errorf("invalid duration '%s'", str)
+	}
+	return result, nil
+}
+
+// CurrentTime returns the current time in UTC if utc is set (InfluxdbUseUTC), in local time otherwise.
+func CurrentTime(utc bool) time.Time {
+	if utc {
+		return time.Now().UTC()
+	}
+	return time.Now()
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseInfluxDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		err      bool
+	}{
+		{input: "1ns", expected: time.Nanosecond},
+		{input: "5u", expected: 5 * time.Microsecond},
+		{input: "5µ", expected: 5 * time.Microsecond},
+		{input: "10ms", expected: 10 * time.Millisecond},
+		{input: "30s", expected: 30 * time.Second},
+		{input: "30m", expected: 30 * time.Minute},
+		{input: "1h30m", expected: 90 * time.Minute},
+		{input: "1d", expected: 24 * time.Hour},
+		{input: "2w", expected: 14 * 24 * time.Hour},
+		{input: "1w1d1h1m1s", expected: 8*24*time.Hour + time.Hour + time.Minute + time.Second},
+		{input: "0s", expected: 0},
+		{input: "", err: true},
+		{input: "1", err: true},
+		{input: "h", err: true},
+		{input: "1x", err: true},
+		{input: "-1h", err: true},
+		{input: "1.5h", err: true},
+		{input: " 1h", err: true},
+		{input: "1h ", err: true},
+		{input: "1h'", err: true},
+		{input: "1h;", err: true},
+		{input: "1h; DROP DATABASE x", err: true},
+		{input: "1h' OR '1'='1", err: true},
+		{input: "now() - 1h", err: true},
+		{input: "/.*/", err: true},
+		{input: "1h|1d", err: true},
+		{input: "$p0", err: true},
+		{input: "1h$p0", err: true},
+		{input: "99999999999999999999h", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := ParseInfluxDuration(test.input)
+			if test.err {
+				if err == nil {
+					t.Errorf("expected error, got %v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != test.expected {
+				t.Errorf("%v != %v", result, test.expected)
+			}
+		})
+	}
+}
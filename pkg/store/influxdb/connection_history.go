@@ -0,0 +1,211 @@
+package influxdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"log/slog"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+	"github.com/influxdata/influxdb1-client/models"
+	influx "github.com/influxdata/influxdb1-client/v2"
+)
+
+func (this *Influx) QueryHistoricalStates(ctx context.Context, query model.QueryHistorical, kind string) (map[string]model.HistoricalStates, error) {
+	statement, prevID, seriesID, nextID, err := this.buildStatement(query, kind)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := this.query(ctx, statement.Query(this.config.InfluxdbDb))
+	if err != nil {
+		return nil, err
+	}
+	if err = resp.Error(); err != nil {
+		return nil, err
+	}
+	return handleResults(resp.Results, kind, prevID, seriesID, nextID)
+}
+
+func handleResults(results []influx.Result, kind string, prevID, seriesID, nextID int) (map[string]model.HistoricalStates, error) {
+	if len(results) == 0 {
+		return nil, errors.New("no results")
+	}
+	resMap := make(map[string]model.HistoricalStates)
+	for _, result := range results {
+		if result.Err != "" {
+			return nil, errors.New(result.Err)
+		}
+		switch result.StatementId {
+		case seriesID:
+			handleSeries(resMap, kind, result.Series, 0)
+		case prevID:
+			handleSeries(resMap, kind, result.Series, 1)
+		case nextID:
+			handleSeries(resMap, kind, result.Series, 2)
+		default:
+			return nil, fmt.Errorf("unknown statement id: %d", result.StatementId)
+		}
+
+	}
+	return resMap, nil
+}
+
+func handleSeries(resMap map[string]model.HistoricalStates, kind string, series []models.Row, resType int) {
+	for _, row := range series {
+		if len(row.Values) == 0 {
+			continue
+		}
+		key, ok := row.Tags[kind]
+		if !ok {
+			continue
+		}
+		if err := handleRow(resMap, row.Values, key, resType); err != nil {
+			slog.Error("unable to handle row", "error", err)
+			continue
+		}
+	}
+}
+
+func handleRow(resMap map[string]model.HistoricalStates, rowValues [][]any, key string, resType int) error {
+	resource := resMap[key]
+	if resType > 0 {
+		state, err := rowItemToState(rowValues[0])
+		if err != nil {
+			return err
+		}
+		switch resType {
+		case 1:
+			resource.PrevState = &state
+		case 2:
+			resource.NextState = &state
+		}
+	} else {
+		for _, item := range rowValues {
+			state, err := rowItemToState(item)
+			if err != nil {
+				slog.Error("unable to transform row item to state", "error", err)
+				continue
+			}
+			resource.States = append(resource.States, state)
+		}
+	}
+	resMap[key] = resource
+	return nil
+}
+
+func rowItemToState(item []any) (model.State, error) {
+	if len(item) < 2 {
+		return model.State{}, errors.New("invalid length")
+	}
+	timeVal, ok := item[0].(json.Number)
+	if !ok {
+		return model.State{}, fmt.Errorf("invalid type: time=%t", item[0])
+	}
+	timeInt, err := timeVal.Int64()
+	if err != nil {
+		return model.State{}, fmt.Errorf("time conversion failed: %s", err)
+	}
+	connected, ok := item[1].(bool)
+	if !ok {
+		return model.State{}, fmt.Errorf("invalid type: connected=%t", item[1])
+	}
+	return model.State{
+		Time:      time.Unix(timeInt, 0).UTC(),
+		Connected: connected,
+	}, nil
+}
+
+func (this *Influx) buildStatement(query model.QueryHistorical, kind string) (*statement, int, int, int, error) {
+	s, err := newStatement(query.IDs, kind)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+	hasRange := query.Range > 0
+	hasSince := !query.Since.IsZero()
+	hasUntil := !query.Until.IsZero()
+	switch {
+	case hasSince && hasUntil:
+		// Since && Until: time >= timestamp AND time <= timestamp
+		// include prev and next
+		s.StatePrev(query.Since)
+		s.StatesTimeGrtEqLesEq(query.Since, query.Until)
+		s.StateNext(query.Until)
+		return s, 0, 1, 2, nil
+	case hasRange && hasUntil:
+		// Range && Until: time >= (timestamp - duration) AND time <= timestamp
+		// include prev and next
+		since := query.Until.Add(time.Duration(query.Range) * -1)
+		s.StatePrev(since)
+		s.StatesTimeGrtEqLesEq(since, query.Until)
+		s.StateNext(query.Until)
+		return s, 0, 1, 2, nil
+	case hasRange && hasSince:
+		// Range && Since: time >= timestamp AND time <= (timestamp + duration)
+		// include prev and next
+		until := query.Since.Add(time.Duration(query.Range))
+		s.StatePrev(query.Since)
+		s.StatesTimeGrtEqLesEq(query.Since, until)
+		s.StateNext(until)
+		return s, 0, 1, 2, nil
+	case hasRange:
+		// Range: time >= (now - duration)
+		// include prev
+		timestamp := store.CurrentTime(this.config.InfluxdbUseUTC).Add(time.Duration(query.Range) * -1)
+		s.StatePrev(timestamp)
+		s.StatesTimeGrtEq(timestamp)
+		return s, 0, 1, -1, nil
+	case hasUntil:
+		// Until: time <= timestamp
+		// include next
+		s.StatesTimeLesEq(query.Until)
+		s.StateNext(query.Until)
+		return s, -1, 0, 1, nil
+	case hasSince:
+		// Since: time >= timestamp
+		// include prev
+		s.StatePrev(query.Since)
+		s.StatesTimeGrtEq(query.Since)
+		return s, 0, 1, -1, nil
+	default:
+		s.StatesTimeLesEq(store.CurrentTime(this.config.InfluxdbUseUTC))
+		return s, -1, 0, -1, nil
+	}
+}
+
+// AddHistoricalState writes the state unless a state with the same time is stored.
+// Influxdb overwrites points with the same series and time, so concurrent writes of a state do not create duplicates.
+func (this *Influx) AddHistoricalState(ctx context.Context, id, kind string, state model.State) (bool, error) {
+	query, err := newStatement([]string{id}, kind)
+	if err != nil {
+		return false, err
+	}
+	query.add(`SELECT "connected" FROM %[1]s WHERE time = %[3]s AND %[2]s`, query.bind(state.Time.UTC().Format(time.RFC3339Nano)))
+	resp, err := this.query(ctx, query.Query(this.config.InfluxdbDb))
+	if err != nil {
+		return false, err
+	}
+	if err = resp.Error(); err != nil {
+		return false, err
+	}
+	for _, result := range resp.Results {
+		for _, series := range result.Series {
+			if len(series.Values) > 0 {
+				return false, nil
+			}
+		}
+	}
+	bp, err := influx.NewBatchPoints(influx.BatchPointsConfig{Database: this.config.InfluxdbDb})
+	if err != nil {
+		return false, err
+	}
+	point, err := influx.NewPoint(kind, map[string]string{kind: id}, map[string]any{"connected": state.Connected}, state.Time)
+	if err != nil {
+		return false, err
+	}
+	bp.AddPoint(point)
+	return true, this.write(ctx, bp)
+}
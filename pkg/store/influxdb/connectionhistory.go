@@ -0,0 +1,128 @@
+/*
+ * Copyright 2018 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+)
+
+// duration in influxdb format https://docs.influxdata.com/influxdb/v1.5/query_language/spec/#durations
+func (this *Influx) GetResourcesHistory(ctx context.Context, ids []string, kind string, duration string) (result interface{}, err error) {
+	if len(ids) == 0 {
+		return []interface{}{map[string]interface{}{"Series": []interface{}{}}}, nil
+	}
+	_, err = store.ParseInfluxDuration(duration)
+	if err != nil {
+		return result, err
+	}
+	query, err := newStatement(ids, kind)
+	if err != nil {
+		return result, err
+	}
+	query.History(duration)
+	resp, err := this.query(ctx, query.Query(this.config.InfluxdbDb))
+	if err != nil {
+		this.config.GetLogger().Error("unable to get influx query result", "query", query.String(), "error", err)
+		return result, err
+	}
+	err = resp.Error()
+	if err != nil {
+		this.config.GetLogger().Error("unable to get influx query result", "query", query.String(), "error", err)
+	}
+	return resp.Results, err
+}
+
+func (this *Influx) GetResourcesLogstart(ctx context.Context, ids []string, kind string) (result map[string]float64, err error) {
+	result = map[string]float64{}
+	query, err := newStatement(ids, kind)
+	if err != nil {
+		return result, err
+	}
+	query.add(`SELECT FIRST(*) FROM %[1]s WHERE %[2]s GROUP BY %[1]s`)
+	resp, err := this.query(ctx, query.Query(this.config.InfluxdbDb))
+	if err != nil {
+		return result, err
+	}
+	err = resp.Error()
+	if err != nil {
+		return result, err
+	}
+	b, _ := json.Marshal(resp.Results)
+	temp := []store.HistoryResult{}
+	json.Unmarshal(b, &temp)
+	if len(temp) == 0 {
+		err = errors.New("error while interpreting database result (series)")
+		return
+	}
+	for _, series := range temp[0].Series {
+		if len(series.Values) == 0 || len(series.Values[0]) == 0 {
+			err = errors.New("error while interpreting database result (series.Values)")
+			return
+		}
+		var ok bool
+		result[series.Tags[kind]], ok = series.Values[0][0].(float64)
+		if !ok {
+			err = errors.New("error while interpreting database result (cast)" + reflect.TypeOf(series.Values[0][0]).String())
+			return
+		}
+	}
+	return
+}
+
+func (this *Influx) GetResourcesLogEdge(ctx context.Context, ids []string, kind string, duration string) (result map[string]any, err error) {
+	if len(ids) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	result = map[string]interface{}{}
+	_, err = store.ParseInfluxDuration(duration)
+	if err != nil {
+		return result, err
+	}
+	query, err := newStatement(ids, kind)
+	if err != nil {
+		return result, err
+	}
+	query.LogEdge(duration)
+	resp, err := this.query(ctx, query.Query(this.config.InfluxdbDb))
+	if err != nil {
+		return result, err
+	}
+	err = resp.Error()
+	if err != nil {
+		return result, err
+	}
+	b, _ := json.Marshal(resp.Results)
+	temp := []store.HistoryResult{}
+	json.Unmarshal(b, &temp)
+	if len(temp) == 0 {
+		err = errors.New("error while interpreting database result (series)")
+		return
+	}
+	for _, series := range temp[0].Series {
+		if len(series.Values) == 0 {
+			err = errors.New("error while interpreting database result (series.Values)")
+			return
+		}
+		result[series.Tags[kind]] = series.Values[0]
+	}
+	return
+}
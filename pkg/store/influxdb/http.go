@@ -0,0 +1,94 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	influx "github.com/influxdata/influxdb1-client/v2"
+)
+
+// query sends q to the query endpoint like influx.Client.Query, which does not accept a context.
+// Canceling ctx closes the request and lets influxdb abort the running query.
+func (this *Influx) query(ctx context.Context, q influx.Query) (*influx.Response, error) {
+	params, err := json.Marshal(q.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	values := url.Values{}
+	values.Set("q", q.Command)
+	values.Set("db", q.Database)
+	values.Set("params", string(params))
+	if q.Precision != "" {
+		values.Set("epoch", q.Precision)
+	}
+	resp, err := this.do(ctx, "query", values, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	response := influx.Response{}
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err = decoder.Decode(&response); err != nil {
+		return nil, fmt.Errorf("unable to decode influxdb response with status code %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK && response.Error() == nil {
+		return &response, fmt.Errorf("received status code %d from influxdb", resp.StatusCode)
+	}
+	return &response, nil
+}
+
+// write sends the points in line protocol to the write endpoint like influx.Client.Write.
+func (this *Influx) write(ctx context.Context, bp influx.BatchPoints) error {
+	body := bytes.Buffer{}
+	for _, point := range bp.Points() {
+		body.WriteString(point.PrecisionString(bp.Precision()))
+		body.WriteByte('\n')
+	}
+	values := url.Values{}
+	values.Set("db", bp.Database())
+	values.Set("precision", bp.Precision())
+	resp, err := this.do(ctx, "write", values, &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("received status code %d from influxdb: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
+
+func (this *Influx) do(ctx context.Context, endpoint string, values url.Values, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, this.url.JoinPath(endpoint).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = values.Encode()
+	if this.config.InfluxdbUser != "" {
+		req.SetBasicAuth(this.config.InfluxdbUser, this.config.InfluxdbPw)
+	}
+	return this.client.Do(req)
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/tracing"
+)
+
+// Influx queries the http api of influxdb 1.x directly, the influxdb1-client does not accept contexts.
+type Influx struct {
+	config configuration.Config
+	client *http.Client
+	url    *url.URL
+}
+
+func New(config configuration.Config) (*Influx, error) {
+	u, err := url.Parse(config.InfluxdbUrl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported protocol scheme '%s' in InfluxdbUrl", u.Scheme)
+	}
+	return &Influx{
+		config: config,
+		client: &http.Client{
+			Timeout:   time.Duration(config.InfluxdbTimeout) * time.Second,
+			Transport: tracing.Transport(nil),
+		},
+		url: u,
+	}, nil
+}
+
+func (this *Influx) Close() error {
+	this.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,114 @@
+package influxdb
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+func (this *Influx) GetLastStates(ctx context.Context, ids []string, kind string) (map[string]model.State, error) {
+	result := map[string]model.State{}
+	if len(ids) == 0 {
+		return result, nil
+	}
+	query, err := newStatement(ids, kind)
+	if err != nil {
+		return nil, err
+	}
+	query.LastState()
+	resp, err := this.query(ctx, query.Query(this.config.InfluxdbDb))
+	if err != nil {
+		return nil, err
+	}
+	if err = resp.Error(); err != nil {
+		return nil, err
+	}
+	resMap, err := handleResults(resp.Results, kind, 0, -1, -2)
+	if err != nil {
+		return nil, err
+	}
+	for id, states := range resMap {
+		if states.PrevState != nil {
+			result[id] = *states.PrevState
+		}
+	}
+	return result, nil
+}
+
+func (this *Influx) GetFirstOnlineStates(ctx context.Context, ids []string, kind string, after time.Time) (map[string]model.State, error) {
+	result := map[string]model.State{}
+	if len(ids) == 0 {
+		return result, nil
+	}
+	query, err := newStatement(ids, kind)
+	if err != nil {
+		return nil, err
+	}
+	query.FirstOnlineState(after)
+	resp, err := this.query(ctx, query.Query(this.config.InfluxdbDb))
+	if err != nil {
+		return nil, err
+	}
+	if err = resp.Error(); err != nil {
+		return nil, err
+	}
+	resMap, err := handleResults(resp.Results, kind, -1, -2, 0)
+	if err != nil {
+		return nil, err
+	}
+	for id, states := range resMap {
+		if states.NextState != nil {
+			result[id] = *states.NextState
+		}
+	}
+	return result, nil
+}
+
+func (this *Influx) GetOfflineSince(ctx context.Context, ids []string, kind string, notBefore time.Time) ([]model.OfflineSinceResponse, error) {
+	query, err := newStatement(ids, kind)
+	if err != nil {
+		return nil, err
+	}
+	query.OfflineSince(notBefore)
+
+	resp, err := this.query(ctx, query.Query(this.config.InfluxdbDb))
+	if err != nil {
+		return nil, err
+	}
+
+	err = resp.Error()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []model.OfflineSinceResponse{}
+
+	for _, res := range resp.Results {
+		for _, series := range res.Series {
+			for _, row := range series.Values {
+				timestamp, ok := row[0].(json.Number)
+				if !ok {
+					continue
+				}
+				i64, err := timestamp.Int64()
+				if err != nil {
+					continue
+				}
+				parsedTime := time.Unix(i64, 0)
+				id, ok := row[1].(string)
+				if !ok {
+					continue
+				}
+				result = append(result, model.OfflineSinceResponse{ID: id, OfflineSince: parsedTime})
+			}
+		}
+	}
+	slices.SortFunc(result, func(a, b model.OfflineSinceResponse) int {
+		return a.OfflineSince.Compare(b.OfflineSince)
+	})
+	return result, nil
+
+}
@@ -0,0 +1,111 @@
+package influxdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	influx "github.com/influxdata/influxdb1-client/v2"
+)
+
+// statement collects InfluxQL statements for one kind and a list of ids.
+// Ids and timestamps are sent as bound parameters and never written into the query,
+// the kind is used as measurement and tag key and therefore has to be a known kind.
+type statement struct {
+	kind   string
+	ids    string
+	params map[string]any
+	text   strings.Builder
+}
+
+func newStatement(ids []string, kind string) (*statement, error) {
+	if kind != model.DeviceKind && kind != model.GatewayKind {
+		return nil, fmt.Errorf("invalid kind '%s'", kind)
+	}
+	s := &statement{kind: kind, params: map[string]any{}}
+	conditions := make([]string, 0, len(ids))
+	for _, id := range ids {
+		conditions = append(conditions, strconv.Quote(kind)+" = "+s.bind(id))
+	}
+	s.ids = "(" + strings.Join(conditions, " OR ") + ")"
+	return s, nil
+}
+
+// bind adds a parameter and returns its placeholder
+func (this *statement) bind(value any) string {
+	name := "p" + strconv.Itoa(len(this.params))
+	this.params[name] = value
+	return "$" + name
+}
+
+// bindDuration adds a duration parameter, influxdb reads typed parameters like {"duration": "1h"} as duration literals
+func (this *statement) bindDuration(duration string) string {
+	return this.bind(map[string]string{"duration": duration})
+}
+
+// add appends a statement, %[1]s is replaced by the quoted kind and %[2]s by the id condition, placeholders start at %[3]s
+func (this *statement) add(format string, placeholders ...any) {
+	fmt.Fprintf(&this.text, format+";", append([]any{strconv.Quote(this.kind), this.ids}, placeholders...)...)
+}
+
+func (this *statement) Query(db string) influx.Query {
+	return influx.NewQueryWithParameters(this.text.String(), db, "s", this.params)
+}
+
+func (this *statement) String() string {
+	return this.text.String()
+}
+
+func (this *statement) StatePrev(timestamp time.Time) {
+	this.add(`SELECT "time", LAST("connected") AS "connected" FROM %[1]s WHERE time < %[3]s AND %[2]s GROUP BY %[1]s`, this.bind(formatTimestamp(timestamp)))
+}
+
+func (this *statement) StateNext(timestamp time.Time) {
+	this.add(`SELECT "time", FIRST("connected") AS "connected" FROM %[1]s WHERE time > %[3]s AND %[2]s GROUP BY %[1]s`, this.bind(formatTimestamp(timestamp)))
+}
+
+func (this *statement) StatesTimeGrtEq(timestamp time.Time) {
+	this.add(`SELECT "time", "connected" FROM %[1]s WHERE time >= %[3]s AND %[2]s GROUP BY %[1]s`, this.bind(formatTimestamp(timestamp)))
+}
+
+func (this *statement) StatesTimeLesEq(timestamp time.Time) {
+	this.add(`SELECT "time", "connected" FROM %[1]s WHERE time <= %[3]s AND %[2]s GROUP BY %[1]s`, this.bind(formatTimestamp(timestamp)))
+}
+
+func (this *statement) StatesTimeGrtEqLesEq(timestampA, timestampB time.Time) {
+	this.add(`SELECT "time", "connected" FROM %[1]s WHERE time >= %[3]s AND time <= %[4]s AND %[2]s GROUP BY %[1]s`, this.bind(formatTimestamp(timestampA)), this.bind(formatTimestamp(timestampB)))
+}
+
+func (this *statement) FirstOnlineState(timestamp time.Time) {
+	this.add(`SELECT "time", FIRST("connected") AS "connected" FROM %[1]s WHERE time >= %[3]s AND "connected" = true AND %[2]s GROUP BY %[1]s`, this.bind(formatTimestamp(timestamp)))
+}
+
+// History selects the states of the last duration, an influxdb duration literal validated with store.ParseInfluxDuration.
+func (this *statement) History(duration string) {
+	this.add(`SELECT * FROM %[1]s WHERE time > now() - %[3]s AND %[2]s GROUP BY %[1]s`, this.bindDuration(duration))
+}
+
+// LogEdge selects the last state before the last duration.
+func (this *statement) LogEdge(duration string) {
+	this.add(`SELECT LAST(*) FROM %[1]s WHERE time < now() - %[3]s AND %[2]s GROUP BY %[1]s`, this.bindDuration(duration))
+}
+
+func (this *statement) LastState() {
+	this.add(`SELECT "time", LAST("connected") AS "connected" FROM %[1]s WHERE %[2]s GROUP BY %[1]s`)
+}
+
+// OfflineSince selects the last state of ids with a last state at or after notBefore, if not zero.
+// The time condition must only be a lower bound, it restricts the inner LAST() selection.
+func (this *statement) OfflineSince(notBefore time.Time) {
+	if notBefore.IsZero() {
+		this.add(`SELECT * FROM (SELECT LAST("connected") AS last_connected FROM %[1]s WHERE %[2]s GROUP BY %[1]s) WHERE last_connected = false`)
+		return
+	}
+	this.add(`SELECT * FROM (SELECT LAST("connected") AS last_connected FROM %[1]s WHERE %[2]s AND time >= %[3]s GROUP BY %[1]s) WHERE last_connected = false`, this.bind(formatTimestamp(notBefore)))
+}
+
+func formatTimestamp(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,125 @@
+package influxdb
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+var injectionPayloads = []string{
+	`device'`,
+	`device" OR "1" = "1`,
+	`device'; DROP MEASUREMENT "device"; --`,
+	`device\'; SELECT * FROM "gateway"`,
+	`/.*/`,
+	`device =~ /.*/`,
+	`$p0`,
+	`${p1}`,
+	"device\n; DROP DATABASE x",
+}
+
+func TestNewStatementInvalidKind(t *testing.T) {
+	kinds := append([]string{"", "devices", "Device", "hub"}, injectionPayloads...)
+	for _, kind := range kinds {
+		if _, err := newStatement([]string{"id"}, kind); err == nil {
+			t.Errorf("expected error for kind %q", kind)
+		}
+	}
+}
+
+func TestNewStatementBindsIds(t *testing.T) {
+	for _, kind := range []string{model.DeviceKind, model.GatewayKind} {
+		for _, payload := range injectionPayloads {
+			t.Run(kind+"/"+payload, func(t *testing.T) {
+				s, err := newStatement([]string{"a", payload}, kind)
+				if err != nil {
+					t.Fatal(err)
+				}
+				s.StatesTimeGrtEqLesEq(time.Unix(0, 0), time.Unix(60, 0))
+				s.LastState()
+				text := s.String()
+				expected := `SELECT "time", "connected" FROM "` + kind + `" WHERE time >= $p2 AND time <= $p3 AND ("` + kind + `" = $p0 OR "` + kind + `" = $p1) GROUP BY "` + kind + `";` +
+					`SELECT "time", LAST("connected") AS "connected" FROM "` + kind + `" WHERE ("` + kind + `" = $p0 OR "` + kind + `" = $p1) GROUP BY "` + kind + `";`
+				if text != expected {
+					t.Errorf("\n%s\n%s", text, expected)
+				}
+				if s.params["p0"] != "a" || s.params["p1"] != payload {
+					t.Errorf("unexpected params %#v", s.params)
+				}
+				if s.params["p2"] != time.Unix(0, 0).Format(time.RFC3339) {
+					t.Errorf("unexpected timestamp param %#v", s.params["p2"])
+				}
+				query := s.Query("db")
+				if query.Command != text || query.Database != "db" || len(query.Parameters) != 4 {
+					t.Errorf("unexpected query %#v", query)
+				}
+			})
+		}
+	}
+}
+
+func TestNewStatementOfflineSince(t *testing.T) {
+	s, err := newStatement([]string{"a"}, model.DeviceKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.OfflineSince(time.Time{})
+	expected := `SELECT * FROM (SELECT LAST("connected") AS last_connected FROM "device" WHERE ("device" = $p0) GROUP BY "device") WHERE last_connected = false;`
+	if s.String() != expected {
+		t.Errorf("\n%s\n%s", s.String(), expected)
+	}
+	if len(s.params) != 1 {
+		t.Errorf("unexpected params %#v", s.params)
+	}
+
+	s, err = newStatement([]string{"a"}, model.GatewayKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.OfflineSince(time.Unix(60, 0))
+	expected = `SELECT * FROM (SELECT LAST("connected") AS last_connected FROM "gateway" WHERE ("gateway" = $p0) AND time >= $p1 GROUP BY "gateway") WHERE last_connected = false;`
+	if s.String() != expected {
+		t.Errorf("\n%s\n%s", s.String(), expected)
+	}
+	if s.params["p1"] != time.Unix(60, 0).Format(time.RFC3339) {
+		t.Errorf("unexpected params %#v", s.params)
+	}
+}
+
+func TestNewStatementFirstOnlineState(t *testing.T) {
+	s, err := newStatement([]string{"a", "b"}, model.DeviceKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.FirstOnlineState(time.Unix(60, 0))
+	expected := `SELECT "time", FIRST("connected") AS "connected" FROM "device" WHERE time >= $p2 AND "connected" = true AND ("device" = $p0 OR "device" = $p1) GROUP BY "device";`
+	if s.String() != expected {
+		t.Errorf("\n%s\n%s", s.String(), expected)
+	}
+	if s.params["p2"] != time.Unix(60, 0).Format(time.RFC3339) {
+		t.Errorf("unexpected params %#v", s.params)
+	}
+}
+
+func TestNewStatementHistoryBindsDuration(t *testing.T) {
+	s, err := newStatement([]string{"a"}, model.DeviceKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.History("1h30m")
+	s.LogEdge("1h30m")
+	expected := `SELECT * FROM "device" WHERE time > now() - $p1 AND ("device" = $p0) GROUP BY "device";` +
+		`SELECT LAST(*) FROM "device" WHERE time < now() - $p2 AND ("device" = $p0) GROUP BY "device";`
+	if s.String() != expected {
+		t.Errorf("\n%s\n%s", s.String(), expected)
+	}
+	params, err := json.Marshal(s.Query("db").Parameters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"p0":"a","p1":{"duration":"1h30m"},"p2":{"duration":"1h30m"}}`; string(params) != expected {
+		t.Errorf("\n%s\n%s", params, expected)
+	}
+}
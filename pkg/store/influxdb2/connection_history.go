@@ -0,0 +1,200 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb2
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+)
+
+func (this *Influx2) QueryHistoricalStates(ctx context.Context, query model.QueryHistorical, kind string) (map[string]model.HistoricalStates, error) {
+	records, err := this.run(ctx, this.buildQuery(query, kind, store.CurrentTime(this.config.InfluxdbUseUTC)), kind)
+	if err != nil {
+		return nil, err
+	}
+	resMap := map[string]model.HistoricalStates{}
+	for _, rec := range records {
+		resource := resMap[rec.ID]
+		state := model.State{Time: rec.Time.UTC(), Connected: rec.Connected}
+		switch rec.Result {
+		case resultPrev:
+			resource.PrevState = &state
+		case resultNext:
+			resource.NextState = &state
+		case resultStates:
+			resource.States = append(resource.States, state)
+		default:
+			return nil, fmt.Errorf("unknown result name: %s", rec.Result)
+		}
+		resMap[rec.ID] = resource
+	}
+	return resMap, nil
+}
+
+// buildQuery selects the same time frames as the 1.x backend, now is the reference of queries without a timestamp.
+func (this *Influx2) buildQuery(query model.QueryHistorical, kind string, now time.Time) string {
+	window := store.NewHistoryWindow(query, now)
+	var b strings.Builder
+	if window.WithPrev {
+		b.WriteString(this.StatePrevQuery(query.IDs, kind, window.Since))
+	}
+	switch {
+	case !window.Since.IsZero() && !window.Until.IsZero():
+		b.WriteString(this.StatesTimeGrtEqLesEqQuery(query.IDs, kind, window.Since, window.Until))
+	case !window.Since.IsZero():
+		b.WriteString(this.StatesTimeGrtEqQuery(query.IDs, kind, window.Since))
+	default:
+		b.WriteString(this.StatesTimeLesEqQuery(query.IDs, kind, window.Until))
+	}
+	if window.WithNext {
+		b.WriteString(this.StateNextQuery(query.IDs, kind, window.Until))
+	}
+	return b.String()
+}
+
+func (this *Influx2) GetOfflineSince(ctx context.Context, ids []string, kind string, notBefore time.Time) ([]model.OfflineSinceResponse, error) {
+	if len(ids) == 0 {
+		return []model.OfflineSinceResponse{}, nil
+	}
+	start := fluxEpoch
+	if !notBefore.IsZero() {
+		start = bound(notBefore, 0)
+	}
+	records, err := this.run(ctx, this.fluxStatesQuery(ids, kind, start, "", selectLast, resultStates), kind)
+	if err != nil {
+		return nil, err
+	}
+	result := []model.OfflineSinceResponse{}
+	for _, rec := range records {
+		if rec.Connected {
+			continue
+		}
+		result = append(result, model.OfflineSinceResponse{ID: rec.ID, OfflineSince: time.Unix(rec.Time.Unix(), 0)})
+	}
+	slices.SortFunc(result, func(a, b model.OfflineSinceResponse) int {
+		return a.OfflineSince.Compare(b.OfflineSince)
+	})
+	return result, nil
+}
+
+func (this *Influx2) GetLastStates(ctx context.Context, ids []string, kind string) (map[string]model.State, error) {
+	result := map[string]model.State{}
+	if len(ids) == 0 {
+		return result, nil
+	}
+	records, err := this.run(ctx, this.fluxStatesQuery(ids, kind, fluxEpoch, "", selectLast, resultStates), kind)
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range records {
+		result[rec.ID] = model.State{Time: rec.Time.UTC(), Connected: rec.Connected}
+	}
+	return result, nil
+}
+
+func (this *Influx2) GetFirstOnlineStates(ctx context.Context, ids []string, kind string, after time.Time) (map[string]model.State, error) {
+	result := map[string]model.State{}
+	if len(ids) == 0 {
+		return result, nil
+	}
+	records, err := this.run(ctx, this.FirstOnlineStateQuery(ids, kind, after), kind)
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range records {
+		result[rec.ID] = model.State{Time: rec.Time.UTC(), Connected: rec.Connected}
+	}
+	return result, nil
+}
+
+func (this *Influx2) GetResourcesHistory(ctx context.Context, ids []string, kind string, duration string) (any, error) {
+	dur, err := store.ParseInfluxDuration(duration)
+	if err != nil {
+		return nil, err
+	}
+	records, err := this.run(ctx, this.fluxStatesQuery(ids, kind, fluxTime(time.Now().Add(-dur).Add(time.Nanosecond)), "", selectAll, resultStates), kind)
+	if err != nil {
+		return nil, err
+	}
+	result := store.HistoryResult{Series: []store.HistorySeries{}}
+	index := map[string]int{}
+	for _, rec := range records {
+		i, ok := index[rec.ID]
+		if !ok {
+			i = len(result.Series)
+			index[rec.ID] = i
+			result.Series = append(result.Series, store.HistorySeries{
+				Name:    kind,
+				Tags:    map[string]string{kind: rec.ID},
+				Columns: []string{"time", "connected"},
+			})
+		}
+		result.Series[i].Values = append(result.Series[i].Values, []any{rec.Time.Unix(), rec.Connected})
+	}
+	return []store.HistoryResult{result}, nil
+}
+
+func (this *Influx2) GetResourcesLogstart(ctx context.Context, ids []string, kind string) (map[string]float64, error) {
+	if len(ids) == 0 {
+		return map[string]float64{}, nil
+	}
+	records, err := this.run(ctx, this.fluxStatesQuery(ids, kind, fluxEpoch, "", selectFirst, resultStates), kind)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]float64{}
+	for _, rec := range records {
+		result[rec.ID] = float64(rec.Time.Unix())
+	}
+	return result, nil
+}
+
+func (this *Influx2) GetResourcesLogEdge(ctx context.Context, ids []string, kind string, duration string) (map[string]any, error) {
+	dur, err := store.ParseInfluxDuration(duration)
+	if err != nil {
+		return nil, err
+	}
+	records, err := this.run(ctx, this.fluxStatesQuery(ids, kind, fluxEpoch, fluxTime(time.Now().Add(-dur)), selectLast, resultStates), kind)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]any{}
+	for _, rec := range records {
+		result[rec.ID] = []any{rec.Time.Unix(), rec.Connected}
+	}
+	return result, nil
+}
+
+// AddHistoricalState writes the state unless a state with the same time is stored.
+// Influxdb overwrites points with the same series and time, so concurrent writes of a state do not create duplicates.
+func (this *Influx2) AddHistoricalState(ctx context.Context, id, kind string, state model.State) (bool, error) {
+	records, err := this.run(ctx, this.fluxStatesQuery([]string{id}, kind, fluxTime(state.Time), fluxTime(state.Time.Add(time.Nanosecond)), selectAll, resultStates), kind)
+	if err != nil {
+		return false, err
+	}
+	if len(records) > 0 {
+		return false, nil
+	}
+	return true, this.write.WritePoint(ctx, influxdb2.NewPoint(kind, map[string]string{kind: id}, map[string]any{"connected": state.Connected}, state.Time))
+}
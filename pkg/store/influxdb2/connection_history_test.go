@@ -0,0 +1,276 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb2
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+)
+
+// testYield is the range and selector of one yield of a flux query.
+type testYield struct {
+	name     string
+	start    string
+	stop     string
+	selector string
+}
+
+var (
+	testRange    = regexp.MustCompile(`range\(start: ([^,)]+)(?:, stop: ([^)]+))?\)`)
+	testSelector = regexp.MustCompile(`\|> (first\(\)|last\(\))`)
+	testYieldOf  = regexp.MustCompile(`yield\(name: "([^"]+)"\)`)
+)
+
+// parseYields splits query into its from() statements.
+func parseYields(t *testing.T, query string) []testYield {
+	t.Helper()
+	result := []testYield{}
+	for _, part := range strings.Split(query, "from(bucket: ")[1:] {
+		yield := testYield{}
+		match := testRange.FindStringSubmatch(part)
+		if match == nil {
+			t.Fatalf("missing range in %v", part)
+		}
+		yield.start, yield.stop = match[1], match[2]
+		if match = testSelector.FindStringSubmatch(part); match != nil {
+			yield.selector = match[1]
+		}
+		match = testYieldOf.FindStringSubmatch(part)
+		if match == nil {
+			t.Fatalf("missing yield in %v", part)
+		}
+		yield.name = match[1]
+		result = append(result, yield)
+	}
+	return result
+}
+
+// TestBuildQuery checks the bounds of each query shape against the InfluxQL statements of the 1.x backend:
+// prev is the last state with time < since, states are since <= time <= until and next is the first state with time > until.
+// Timestamps are truncated to seconds like the RFC 3339 parameters of the 1.x statements.
+func TestBuildQuery(t *testing.T) {
+	this := &Influx2{config: configuration.Config{InfluxdbBucket: "connectionlog"}}
+	now := time.Date(2026, 1, 2, 0, 0, 0, 500, time.UTC)
+	since := time.Date(2026, 1, 1, 0, 0, 0, int(500*time.Millisecond), time.UTC)
+	until := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	rng := model.Duration(time.Hour)
+	prev := func(timestamp string) testYield {
+		return testYield{name: resultPrev, start: fluxEpoch, stop: timestamp, selector: selectLast}
+	}
+	next := func(timestamp string) testYield {
+		return testYield{name: resultNext, start: timestamp, selector: selectFirst}
+	}
+	states := func(start, stop string) testYield {
+		return testYield{name: resultStates, start: start, stop: stop}
+	}
+	tests := []struct {
+		name     string
+		query    model.QueryHistorical
+		expected []testYield
+	}{
+		{
+			name:  "since and until",
+			query: model.QueryHistorical{Since: since, Until: until},
+			expected: []testYield{
+				prev("2026-01-01T00:00:00Z"),
+				states("2026-01-01T00:00:00Z", "2026-01-01T08:00:00.000000001Z"),
+				next("2026-01-01T08:00:00.000000001Z"),
+			},
+		},
+		{
+			name:  "range and until",
+			query: model.QueryHistorical{Range: rng, Until: until},
+			expected: []testYield{
+				prev("2026-01-01T07:00:00Z"),
+				states("2026-01-01T07:00:00Z", "2026-01-01T08:00:00.000000001Z"),
+				next("2026-01-01T08:00:00.000000001Z"),
+			},
+		},
+		{
+			name:  "range and since",
+			query: model.QueryHistorical{Range: rng, Since: since},
+			expected: []testYield{
+				prev("2026-01-01T00:00:00Z"),
+				states("2026-01-01T00:00:00Z", "2026-01-01T01:00:00.000000001Z"),
+				next("2026-01-01T01:00:00.000000001Z"),
+			},
+		},
+		{
+			name:  "range",
+			query: model.QueryHistorical{Range: rng},
+			expected: []testYield{
+				prev("2026-01-01T23:00:00Z"),
+				states("2026-01-01T23:00:00Z", ""),
+			},
+		},
+		{
+			name:  "until",
+			query: model.QueryHistorical{Until: until},
+			expected: []testYield{
+				states(fluxEpoch, "2026-01-01T08:00:00.000000001Z"),
+				next("2026-01-01T08:00:00.000000001Z"),
+			},
+		},
+		{
+			name:  "since",
+			query: model.QueryHistorical{Since: since},
+			expected: []testYield{
+				prev("2026-01-01T00:00:00Z"),
+				states("2026-01-01T00:00:00Z", ""),
+			},
+		},
+		{
+			name:     "none",
+			query:    model.QueryHistorical{},
+			expected: []testYield{states(fluxEpoch, "2026-01-02T00:00:00.000000001Z")},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.query.IDs = []string{"a"}
+			result := parseYields(t, this.buildQuery(test.query, model.DeviceKind, now))
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("\n%#v\n%#v", result, test.expected)
+			}
+		})
+	}
+}
+
+func TestFirstOnlineStateQuery(t *testing.T) {
+	this := &Influx2{config: configuration.Config{InfluxdbBucket: "connectionlog"}}
+	query := this.FirstOnlineStateQuery([]string{"a"}, model.DeviceKind, time.Date(2026, 1, 1, 0, 0, 0, 500, time.UTC))
+	expected := `from(bucket: "connectionlog")
+  |> range(start: 2026-01-01T00:00:00Z)
+  |> filter(fn: (r) => r._measurement == "device" and r._field == "connected")
+  |> filter(fn: (r) => r["device"] == "a")
+  |> filter(fn: (r) => r._value == true)
+  |> group(columns: ["device"])
+  |> sort(columns: ["_time"])
+  |> first()
+  |> yield(name: "states")
+`
+	if query != expected {
+		t.Errorf("\n%s\n%s", query, expected)
+	}
+}
+
+func TestFluxStatesQueryEscapes(t *testing.T) {
+	this := &Influx2{config: configuration.Config{InfluxdbBucket: `bucket"`}}
+	query := this.fluxStatesQuery([]string{`a" or true or "`, `${x}`, `\`}, model.DeviceKind, fluxEpoch, "", selectAll, resultStates)
+	expected := `from(bucket: "bucket\"")
+  |> range(start: 0)
+  |> filter(fn: (r) => r._measurement == "device" and r._field == "connected")
+  |> filter(fn: (r) => r["device"] == "a\" or true or \"" or r["device"] == "\${x}" or r["device"] == "\\")
+  |> group(columns: ["device"])
+  |> sort(columns: ["_time"])
+  |> yield(name: "states")
+`
+	if query != expected {
+		t.Errorf("\n%s\n%s", query, expected)
+	}
+	if query = this.fluxStatesQuery(nil, model.DeviceKind, fluxEpoch, "", selectAll, resultStates); !strings.Contains(query, "filter(fn: (r) => false)") {
+		t.Errorf("expected empty filter in %s", query)
+	}
+}
+
+// testQueryResult contains the results of a query with all three yields, the rows of "b" are split into two tables.
+// Rows without the kind tag or with a non-boolean value are skipped.
+const testQueryResult = `#datatype,string,long,dateTime:RFC3339Nano,boolean,string,string,string
+#group,false,false,false,false,true,true,true
+#default,prev,,,,,,
+,result,table,_time,_value,_field,_measurement,device
+,,0,2025-12-31T23:00:00.9Z,true,connected,device,a
+
+#datatype,string,long,dateTime:RFC3339Nano,boolean,string,string,string
+#group,false,false,false,false,true,true,true
+#default,states,,,,,,
+,result,table,_time,_value,_field,_measurement,device
+,,0,2026-01-01T01:00:00Z,false,connected,device,a
+,,0,2026-01-01T03:00:00.5Z,true,connected,device,a
+,,1,2026-01-01T02:00:00Z,false,connected,device,b
+,,2,2026-01-01T04:00:00Z,true,connected,device,b
+
+#datatype,string,long,dateTime:RFC3339Nano,boolean,string,string
+#group,false,false,false,false,true,true
+#default,states,,,,,
+,result,table,_time,_value,_field,_measurement
+,,3,2026-01-01T05:00:00Z,true,connected,device
+
+#datatype,string,long,dateTime:RFC3339Nano,string,string,string,string
+#group,false,false,false,false,true,true,true
+#default,states,,,,,,
+,result,table,_time,_value,_field,_measurement,device
+,,4,2026-01-01T05:00:00Z,invalid,connected,device,a
+
+#datatype,string,long,dateTime:RFC3339Nano,boolean,string,string,string
+#group,false,false,false,false,true,true,true
+#default,next,,,,,,
+,result,table,_time,_value,_field,_measurement,device
+,,0,2026-01-01T10:00:00Z,true,connected,device,b
+
+`
+
+func TestRun(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/api/v2/query" {
+			http.NotFound(writer, request)
+			return
+		}
+		body, _ := io.ReadAll(request.Body)
+		received = string(body)
+		writer.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		_, _ = io.WriteString(writer, testQueryResult)
+	}))
+	defer server.Close()
+	client := influxdb2.NewClient(server.URL, "token")
+	defer client.Close()
+	this := &Influx2{config: configuration.Config{InfluxdbBucket: "connectionlog", LogLevel: "error"}, client: client, query: client.QueryAPI("org")}
+
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	result, err := this.QueryHistoricalStates(context.Background(), model.QueryHistorical{QueryBase: model.QueryBase{IDs: []string{"a", "b"}}, Since: since, Until: since.Add(8 * time.Hour)}, model.DeviceKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(received, `yield(name: \"prev\")`) || !strings.Contains(received, `yield(name: \"next\")`) {
+		t.Errorf("unexpected query %v", received)
+	}
+	expected := map[string]model.HistoricalStates{
+		"a": {
+			PrevState: &model.State{Time: since.Add(-time.Hour), Connected: true},
+			States:    []model.State{{Time: since.Add(time.Hour)}, {Time: since.Add(3 * time.Hour), Connected: true}},
+		},
+		"b": {
+			States:    []model.State{{Time: since.Add(2 * time.Hour)}, {Time: since.Add(4 * time.Hour), Connected: true}},
+			NextState: &model.State{Time: since.Add(10 * time.Hour), Connected: true},
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("\n%#v\n%#v", result, expected)
+	}
+}
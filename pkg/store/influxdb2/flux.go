@@ -0,0 +1,127 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb2
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	resultPrev   = "prev"
+	resultStates = "states"
+	resultNext   = "next"
+)
+
+const (
+	selectAll   = ""
+	selectFirst = "first()"
+	selectLast  = "last()"
+)
+
+// fluxEpoch is used as range start for unbounded queries.
+const fluxEpoch = "0"
+
+var fluxStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `${`, `\${`)
+
+func fluxString(s string) string {
+	return `"` + fluxStringEscaper.Replace(s) + `"`
+}
+
+// fluxTagFilter matches the tag against each value with an or chain of equality checks,
+// which influxdb pushes down to the storage layer unlike contains().
+func fluxTagFilter(tag string, values []string) string {
+	if len(values) == 0 {
+		return "false"
+	}
+	conditions := make([]string, 0, len(values))
+	for _, value := range values {
+		conditions = append(conditions, "r["+fluxString(tag)+"] == "+fluxString(value))
+	}
+	return strings.Join(conditions, " or ")
+}
+
+// fluxTime formats t as flux time literal.
+func fluxTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// bound truncates timestamps to seconds like the RFC 3339 timestamps in the 1.x query templates.
+// The offset is applied afterward to turn inclusive bounds into the exclusive bounds of range().
+func bound(t time.Time, offset time.Duration) string {
+	return fluxTime(t.Truncate(time.Second).Add(offset))
+}
+
+// fluxStatesQuery selects the connected field of all given ids within [start, stop).
+// An empty stop defaults to now(). The selector is applied per id, the result is named by yield.
+func (this *Influx2) fluxStatesQuery(ids []string, kind string, start string, stop string, selector string, yield string) string {
+	return this.fluxQuery(ids, kind, start, stop, "", selector, yield)
+}
+
+// fluxQuery is fluxStatesQuery with an optional filter of the rows, e.g. `r._value == true`.
+func (this *Influx2) fluxQuery(ids []string, kind string, start string, stop string, filter string, selector string, yield string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "from(bucket: %s)\n", fluxString(this.config.InfluxdbBucket))
+	if stop == "" {
+		fmt.Fprintf(&b, "  |> range(start: %s)\n", start)
+	} else {
+		fmt.Fprintf(&b, "  |> range(start: %s, stop: %s)\n", start, stop)
+	}
+	fmt.Fprintf(&b, "  |> filter(fn: (r) => r._measurement == %s and r._field == \"connected\")\n", fluxString(kind))
+	fmt.Fprintf(&b, "  |> filter(fn: (r) => %s)\n", fluxTagFilter(kind, ids))
+	if filter != "" {
+		fmt.Fprintf(&b, "  |> filter(fn: (r) => %s)\n", filter)
+	}
+	fmt.Fprintf(&b, "  |> group(columns: [%s])\n", fluxString(kind))
+	b.WriteString("  |> sort(columns: [\"_time\"])\n")
+	if selector != selectAll {
+		fmt.Fprintf(&b, "  |> %s\n", selector)
+	}
+	fmt.Fprintf(&b, "  |> yield(name: %s)\n", fluxString(yield))
+	return b.String()
+}
+
+// StatePrevQuery selects the last state with time < timestamp.
+func (this *Influx2) StatePrevQuery(ids []string, kind string, timestamp time.Time) string {
+	return this.fluxStatesQuery(ids, kind, fluxEpoch, bound(timestamp, 0), selectLast, resultPrev)
+}
+
+// StateNextQuery selects the first state with time > timestamp.
+func (this *Influx2) StateNextQuery(ids []string, kind string, timestamp time.Time) string {
+	return this.fluxStatesQuery(ids, kind, bound(timestamp, time.Nanosecond), "", selectFirst, resultNext)
+}
+
+// FirstOnlineStateQuery selects the first online state with time >= timestamp.
+func (this *Influx2) FirstOnlineStateQuery(ids []string, kind string, timestamp time.Time) string {
+	return this.fluxQuery(ids, kind, bound(timestamp, 0), "", "r._value == true", selectFirst, resultStates)
+}
+
+// StatesTimeGrtEqQuery selects all states with time >= timestamp.
+func (this *Influx2) StatesTimeGrtEqQuery(ids []string, kind string, timestamp time.Time) string {
+	return this.fluxStatesQuery(ids, kind, bound(timestamp, 0), "", selectAll, resultStates)
+}
+
+// StatesTimeLesEqQuery selects all states with time <= timestamp.
+func (this *Influx2) StatesTimeLesEqQuery(ids []string, kind string, timestamp time.Time) string {
+	return this.fluxStatesQuery(ids, kind, fluxEpoch, bound(timestamp, time.Nanosecond), selectAll, resultStates)
+}
+
+// StatesTimeGrtEqLesEqQuery selects all states with timestampA <= time <= timestampB.
+func (this *Influx2) StatesTimeGrtEqLesEqQuery(ids []string, kind string, timestampA, timestampB time.Time) string {
+	return this.fluxStatesQuery(ids, kind, bound(timestampA, 0), bound(timestampB, time.Nanosecond), selectAll, resultStates)
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb2
+
+import (
+	"context"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/tracing"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+)
+
+// Influx2 stores the connection history in an InfluxDB 2.x bucket and reads it with Flux.
+// The data layout matches the 1.x database: one measurement per kind, tagged with the resource id, field "connected".
+type Influx2 struct {
+	config configuration.Config
+	client influxdb2.Client
+	query  api.QueryAPI
+	write  api.WriteAPIBlocking
+}
+
+func New(config configuration.Config) (*Influx2, error) {
+	options := influxdb2.DefaultOptions().SetHTTPRequestTimeout(uint(config.InfluxdbTimeout))
+	httpClient := options.HTTPClient()
+	httpClient.Transport = tracing.Transport(httpClient.Transport)
+	client := influxdb2.NewClientWithOptions(config.InfluxdbUrl, config.InfluxdbToken, options)
+	ctx, cf := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cf()
+	if _, err := client.Ping(ctx); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &Influx2{
+		config: config,
+		client: client,
+		query:  client.QueryAPI(config.InfluxdbOrg),
+		write:  client.WriteAPIBlocking(config.InfluxdbOrg, config.InfluxdbBucket),
+	}, nil
+}
+
+func (this *Influx2) Close() error {
+	this.client.Close()
+	return nil
+}
+
+// record is a single connection state point, Time is truncated to seconds like the 1.x backend queries with precision "s".
+type record struct {
+	Result    string
+	ID        string
+	Time      time.Time
+	Connected bool
+}
+
+func (this *Influx2) run(ctx context.Context, query string, kind string) ([]record, error) {
+	result, err := this.query.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+	records := []record{}
+	for result.Next() {
+		rec := result.Record()
+		id, ok := rec.ValueByKey(kind).(string)
+		if !ok {
+			continue
+		}
+		connected, ok := rec.Value().(bool)
+		if !ok {
+			this.config.GetLogger().Error("unexpected value type in flux result", "value", rec.Value(), "id", id)
+			continue
+		}
+		records = append(records, record{
+			Result:    rec.Result(),
+			ID:        id,
+			Time:      rec.Time().Truncate(time.Second),
+			Connected: connected,
+		})
+	}
+	if err = result.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
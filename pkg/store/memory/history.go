@@ -0,0 +1,156 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+)
+
+func (this *Memory) QueryHistoricalStates(_ context.Context, query model.QueryHistorical, kind string) (map[string]model.HistoricalStates, error) {
+	window := store.NewHistoryWindow(query, time.Now())
+	resMap := map[string]model.HistoricalStates{}
+	for _, id := range query.IDs {
+		var resource model.HistoricalStates
+		found := false
+		for _, state := range this.getStates(kind, id) {
+			switch {
+			case window.WithPrev && state.Time.Before(window.Since):
+				prev := state
+				resource.PrevState = &prev
+				found = true
+			case !window.Until.IsZero() && state.Time.After(window.Until):
+				if window.WithNext && resource.NextState == nil {
+					next := state
+					resource.NextState = &next
+					found = true
+				}
+			default:
+				resource.States = append(resource.States, state)
+				found = true
+			}
+		}
+		if found {
+			resMap[id] = resource
+		}
+	}
+	return resMap, nil
+}
+
+func (this *Memory) GetOfflineSince(_ context.Context, ids []string, kind string, notBefore time.Time) ([]model.OfflineSinceResponse, error) {
+	result := []model.OfflineSinceResponse{}
+	for _, id := range ids {
+		states := this.getStates(kind, id)
+		if len(states) == 0 {
+			continue
+		}
+		last := states[len(states)-1]
+		if last.Connected || last.Time.Before(notBefore.Truncate(time.Second)) {
+			continue
+		}
+		result = append(result, model.OfflineSinceResponse{ID: id, OfflineSince: last.Time})
+	}
+	slices.SortFunc(result, func(a, b model.OfflineSinceResponse) int {
+		return a.OfflineSince.Compare(b.OfflineSince)
+	})
+	return result, nil
+}
+
+func (this *Memory) GetLastStates(_ context.Context, ids []string, kind string) (map[string]model.State, error) {
+	result := map[string]model.State{}
+	for _, id := range ids {
+		if states := this.getStates(kind, id); len(states) > 0 {
+			result[id] = states[len(states)-1]
+		}
+	}
+	return result, nil
+}
+
+func (this *Memory) GetFirstOnlineStates(_ context.Context, ids []string, kind string, after time.Time) (map[string]model.State, error) {
+	result := map[string]model.State{}
+	for _, id := range ids {
+		for _, state := range this.getStates(kind, id) {
+			if state.Connected && !state.Time.Before(after) {
+				result[id] = state
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func (this *Memory) GetResourcesHistory(_ context.Context, ids []string, kind string, duration string) (any, error) {
+	dur, err := store.ParseInfluxDuration(duration)
+	if err != nil {
+		return nil, err
+	}
+	since := time.Now().Add(-dur)
+	result := store.HistoryResult{Series: []store.HistorySeries{}}
+	for _, id := range ids {
+		series := store.HistorySeries{
+			Name:    kind,
+			Tags:    map[string]string{kind: id},
+			Columns: []string{"time", "connected"},
+		}
+		for _, state := range this.getStates(kind, id) {
+			if state.Time.After(since) {
+				series.Values = append(series.Values, []any{state.Time.Unix(), state.Connected})
+			}
+		}
+		if len(series.Values) > 0 {
+			result.Series = append(result.Series, series)
+		}
+	}
+	return []store.HistoryResult{result}, nil
+}
+
+func (this *Memory) GetResourcesLogstart(_ context.Context, ids []string, kind string) (map[string]float64, error) {
+	result := map[string]float64{}
+	for _, id := range ids {
+		states := this.getStates(kind, id)
+		if len(states) > 0 {
+			result[id] = float64(states[0].Time.Unix())
+		}
+	}
+	return result, nil
+}
+
+func (this *Memory) GetResourcesLogEdge(_ context.Context, ids []string, kind string, duration string) (map[string]any, error) {
+	dur, err := store.ParseInfluxDuration(duration)
+	if err != nil {
+		return nil, err
+	}
+	edge := time.Now().Add(-dur)
+	result := map[string]any{}
+	for _, id := range ids {
+		var last *model.State
+		for _, state := range this.getStates(kind, id) {
+			if !state.Time.Before(edge) {
+				break
+			}
+			last = &state
+		}
+		if last != nil {
+			result[id] = []any{last.Time.Unix(), last.Connected}
+		}
+	}
+	return result, nil
+}
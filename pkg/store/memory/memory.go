@@ -0,0 +1,139 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+)
+
+// Memory implements store.CurrentStateStore and store.HistoryStore without a database.
+// It is meant for tests and local development, all data is lost on restart.
+type Memory struct {
+	mux     sync.RWMutex
+	current map[string]map[string]currentState
+	history map[string]map[string][]model.State
+}
+
+type currentState struct {
+	model.State
+	Since time.Time
+}
+
+func (this currentState) ResourceCurrentState(id string) model.ResourceCurrentState {
+	since := this.Since
+	return model.ResourceCurrentState{ID: id, Connected: this.Connected, Since: &since}
+}
+
+func New() *Memory {
+	return &Memory{
+		current: map[string]map[string]currentState{},
+		history: map[string]map[string][]model.State{},
+	}
+}
+
+func (this *Memory) Close() error {
+	return nil
+}
+
+// Set stores state as current state of the resource and appends it to its history.
+func (this *Memory) Set(kind, id string, state model.State) {
+	_, _ = this.AddHistoricalState(context.Background(), id, kind, state)
+	_, _ = this.SetCurrentState(context.Background(), id, kind, state)
+}
+
+func (this *Memory) SetCurrentState(_ context.Context, id, kind string, state model.State) (bool, error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if _, ok := this.current[kind]; !ok {
+		this.current[kind] = map[string]currentState{}
+	}
+	current, ok := this.current[kind][id]
+	if ok && current.Time.After(state.Time) {
+		return false, nil
+	}
+	if !ok || current.Connected != state.Connected {
+		current.Since = state.Time
+	}
+	current.State = state
+	this.current[kind][id] = current
+	return true, nil
+}
+
+func (this *Memory) AddHistoricalState(_ context.Context, id, kind string, state model.State) (bool, error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if _, ok := this.history[kind]; !ok {
+		this.history[kind] = map[string][]model.State{}
+	}
+	states := this.history[kind][id]
+	idx, found := slices.BinarySearchFunc(states, state, func(a, b model.State) int {
+		return a.Time.Compare(b.Time)
+	})
+	if found {
+		states[idx] = state
+		return false, nil
+	}
+	this.history[kind][id] = slices.Insert(states, idx, state)
+	return true, nil
+}
+
+func (this *Memory) GetCurrentState(_ context.Context, id, kind string) (model.ResourceCurrentState, error) {
+	this.mux.RLock()
+	defer this.mux.RUnlock()
+	state, ok := this.current[kind][id]
+	if !ok {
+		return model.ResourceCurrentState{}, store.ErrNotFound
+	}
+	return state.ResourceCurrentState(id), nil
+}
+
+func (this *Memory) QueryCurrentStates(_ context.Context, ids []string, kind string) (map[string]bool, error) {
+	this.mux.RLock()
+	defer this.mux.RUnlock()
+	states := map[string]bool{}
+	for _, id := range ids {
+		if state, ok := this.current[kind][id]; ok {
+			states[id] = state.Connected
+		}
+	}
+	return states, nil
+}
+
+func (this *Memory) ListCurrentStates(_ context.Context, ids []string, kind string) ([]model.ResourceCurrentState, error) {
+	this.mux.RLock()
+	defer this.mux.RUnlock()
+	states := []model.ResourceCurrentState{}
+	for _, id := range ids {
+		if state, ok := this.current[kind][id]; ok {
+			states = append(states, state.ResourceCurrentState(id))
+		}
+	}
+	return states, nil
+}
+
+// getStates returns a copy of the sorted history of a resource.
+func (this *Memory) getStates(kind, id string) []model.State {
+	this.mux.RLock()
+	defer this.mux.RUnlock()
+	return slices.Clone(this.history[kind][id])
+}
@@ -0,0 +1,262 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AlertRule struct {
+	ID        string         `bson:"id"`
+	Owner     string         `bson:"owner"`
+	Name      string         `bson:"name"`
+	IDs       []string       `bson:"ids"`
+	Condition AlertCondition `bson:"condition"`
+	Severity  string         `bson:"severity"`
+	Channels  []AlertChannel `bson:"channels"`
+	Resources []string       `bson:"resources"`
+}
+
+type AlertCondition struct {
+	Type         string  `bson:"type"`
+	Duration     int64   `bson:"duration,omitempty"` // nanoseconds
+	Transitions  float64 `bson:"transitions,omitempty"`
+	Availability float64 `bson:"availability,omitempty"`
+	Window       int64   `bson:"window,omitempty"` // nanoseconds
+}
+
+type AlertChannel struct {
+	Type string `bson:"type"`
+	ID   string `bson:"id"`
+}
+
+func NewAlertRule(rule model.AlertRule) AlertRule {
+	channels := []AlertChannel{}
+	for _, channel := range rule.Channels {
+		channels = append(channels, AlertChannel{Type: channel.Type, ID: channel.ID})
+	}
+	return AlertRule{
+		ID:    rule.ID,
+		Owner: rule.Owner,
+		Name:  rule.Name,
+		IDs:   rule.IDs,
+		Condition: AlertCondition{
+			Type:         rule.Condition.Type,
+			Duration:     int64(rule.Condition.Duration),
+			Transitions:  rule.Condition.Transitions,
+			Availability: rule.Condition.Availability,
+			Window:       int64(rule.Condition.Window),
+		},
+		Severity:  rule.Severity,
+		Channels:  channels,
+		Resources: rule.Resources,
+	}
+}
+
+func (this AlertRule) Model() model.AlertRule {
+	channels := []model.AlertChannel{}
+	for _, channel := range this.Channels {
+		channels = append(channels, model.AlertChannel{Type: channel.Type, ID: channel.ID})
+	}
+	return model.AlertRule{
+		ID:    this.ID,
+		Owner: this.Owner,
+		Name:  this.Name,
+		IDs:   this.IDs,
+		Condition: model.AlertCondition{
+			Type:         this.Condition.Type,
+			Duration:     model.Duration(this.Condition.Duration),
+			Transitions:  this.Condition.Transitions,
+			Availability: this.Condition.Availability,
+			Window:       model.Duration(this.Condition.Window),
+		},
+		Severity:  this.Severity,
+		Channels:  channels,
+		Resources: this.Resources,
+	}
+}
+
+type Alert struct {
+	ID             string     `bson:"id"`
+	RuleID         string     `bson:"rule_id"`
+	Owner          string     `bson:"owner"`
+	ResourceID     string     `bson:"resource_id"`
+	Kind           string     `bson:"kind"`
+	Condition      string     `bson:"condition"`
+	Severity       string     `bson:"severity"`
+	State          string     `bson:"state"`
+	Value          float64    `bson:"value"`
+	FiredAt        time.Time  `bson:"fired_at"`
+	ResolvedAt     *time.Time `bson:"resolved_at,omitempty"`
+	AcknowledgedAt *time.Time `bson:"acknowledged_at,omitempty"`
+	AcknowledgedBy string     `bson:"acknowledged_by,omitempty"`
+}
+
+func createAlertIndexes(config configuration.Config, db *mongo.Client) error {
+	ctx, cf := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cf()
+	_, err := db.Database(config.MongoTable).Collection(config.AlertRuleCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetName("id_1").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "owner", Value: 1}},
+			Options: options.Index().SetName("owner_1"),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = db.Database(config.MongoTable).Collection(config.AlertCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetName("id_1").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "owner", Value: 1}, {Key: "fired_at", Value: -1}},
+			Options: options.Index().SetName("owner_1_fired_at_-1"),
+		},
+		{
+			Keys:    bson.D{{Key: "rule_id", Value: 1}, {Key: "state", Value: 1}},
+			Options: options.Index().SetName("rule_id_1_state_1"),
+		},
+	})
+	return err
+}
+
+func (this *Mongo) getAlertRuleCollection() *mongo.Collection {
+	return this.client.Database(this.config.MongoTable).Collection(this.config.AlertRuleCollection)
+}
+
+func (this *Mongo) getAlertCollection() *mongo.Collection {
+	return this.client.Database(this.config.MongoTable).Collection(this.config.AlertCollection)
+}
+
+func (this *Mongo) ListAlertRules(ctx context.Context, owner string) ([]model.AlertRule, error) {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	filter := bson.M{}
+	if owner != "" {
+		filter["owner"] = owner
+	}
+	cursor, err := this.getAlertRuleCollection().Find(ctxWt, filter, options.Find().SetSort(bson.D{{Key: "id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+	result := []model.AlertRule{}
+	for cursor.Next(ctxWt) {
+		var rule AlertRule
+		if err = cursor.Decode(&rule); err != nil {
+			return nil, err
+		}
+		result = append(result, rule.Model())
+	}
+	return result, cursor.Err()
+}
+
+func (this *Mongo) GetAlertRule(ctx context.Context, id string) (model.AlertRule, error) {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	var rule AlertRule
+	err := this.getAlertRuleCollection().FindOne(ctxWt, bson.M{"id": id}).Decode(&rule)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return model.AlertRule{}, store.ErrNotFound
+	}
+	if err != nil {
+		return model.AlertRule{}, err
+	}
+	return rule.Model(), nil
+}
+
+func (this *Mongo) SetAlertRule(ctx context.Context, rule model.AlertRule) error {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	_, err := this.getAlertRuleCollection().ReplaceOne(ctxWt, bson.M{"id": rule.ID}, NewAlertRule(rule), options.Replace().SetUpsert(true))
+	return err
+}
+
+func (this *Mongo) DeleteAlertRule(ctx context.Context, id string) error {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	_, err := this.getAlertRuleCollection().DeleteOne(ctxWt, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	_, err = this.getAlertCollection().DeleteMany(ctxWt, bson.M{"rule_id": id})
+	return err
+}
+
+func (this *Mongo) ListAlerts(ctx context.Context, filter model.AlertFilter) ([]model.Alert, error) {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	query := bson.M{}
+	if filter.Owner != "" {
+		query["owner"] = filter.Owner
+	}
+	if filter.RuleID != "" {
+		query["rule_id"] = filter.RuleID
+	}
+	if len(filter.States) > 0 {
+		query["state"] = bson.M{"$in": filter.States}
+	}
+	cursor, err := this.getAlertCollection().Find(ctxWt, query, options.Find().SetSort(bson.D{{Key: "fired_at", Value: -1}, {Key: "id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+	result := []model.Alert{}
+	for cursor.Next(ctxWt) {
+		var alert Alert
+		if err = cursor.Decode(&alert); err != nil {
+			return nil, err
+		}
+		result = append(result, model.Alert(alert))
+	}
+	return result, cursor.Err()
+}
+
+func (this *Mongo) GetAlert(ctx context.Context, id string) (model.Alert, error) {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	var alert Alert
+	err := this.getAlertCollection().FindOne(ctxWt, bson.M{"id": id}).Decode(&alert)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return model.Alert{}, store.ErrNotFound
+	}
+	if err != nil {
+		return model.Alert{}, err
+	}
+	return model.Alert(alert), nil
+}
+
+func (this *Mongo) SetAlert(ctx context.Context, alert model.Alert) error {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	_, err := this.getAlertCollection().ReplaceOne(ctxWt, bson.M{"id": alert.ID}, Alert(alert), options.Replace().SetUpsert(true))
+	return err
+}
@@ -0,0 +1,271 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	selectAll   = 0
+	selectFirst = 1
+	selectLast  = -1
+)
+
+// queryHistory reads states of the given ids from the time-series collection, optionally restricted by timeFilter and further conditions.
+// With selectFirst or selectLast only the first or last matching state per id is returned.
+func (this *Mongo) queryHistory(ctx context.Context, ids []string, kind string, selector int, timeFilter bson.M, conditions ...bson.E) ([]HistoryState, error) {
+	filter := bson.M{historyMetaField + ".kind": kind, historyMetaField + ".id": bson.M{"$in": ids}}
+	if len(timeFilter) > 0 {
+		filter[historyTimeField] = timeFilter
+	}
+	for _, condition := range conditions {
+		filter[condition.Key] = condition.Value
+	}
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	var cursor *mongo.Cursor
+	var err error
+	if selector == selectAll {
+		cursor, err = this.getHistoryCollection().Find(ctxWt, filter, options.Find().SetSort(bson.D{
+			{Key: historyMetaField + ".id", Value: 1},
+			{Key: historyTimeField, Value: 1},
+		}))
+	} else {
+		cursor, err = this.getHistoryCollection().Aggregate(ctxWt, mongo.Pipeline{
+			{{Key: "$match", Value: filter}},
+			{{Key: "$sort", Value: bson.D{{Key: historyMetaField + ".id", Value: 1}, {Key: historyTimeField, Value: selector}}}},
+			{{Key: "$group", Value: bson.M{"_id": "$" + historyMetaField + ".id", "doc": bson.M{"$first": "$$ROOT"}}}},
+			{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$doc"}}},
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+	result := []HistoryState{}
+	for cursor.Next(ctxWt) {
+		var item HistoryState
+		if err = cursor.Decode(&item); err != nil {
+			return nil, err
+		}
+		item.Time = item.Time.Truncate(time.Second).UTC()
+		result = append(result, item)
+	}
+	return result, cursor.Err()
+}
+
+// bound truncates timestamps to seconds like the RFC 3339 timestamps in the influxdb query templates.
+func bound(t time.Time) time.Time {
+	return t.Truncate(time.Second)
+}
+
+func (this *Mongo) QueryHistoricalStates(ctx context.Context, query model.QueryHistorical, kind string) (map[string]model.HistoricalStates, error) {
+	window := store.NewHistoryWindow(query, time.Now())
+	resMap := map[string]model.HistoricalStates{}
+	if window.WithPrev {
+		items, err := this.queryHistory(ctx, query.IDs, kind, selectLast, bson.M{"$lt": bound(window.Since)})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			resource := resMap[item.Meta.ID]
+			resource.PrevState = &model.State{Time: item.Time, Connected: item.Connected}
+			resMap[item.Meta.ID] = resource
+		}
+	}
+	timeFilter := bson.M{}
+	if !window.Since.IsZero() {
+		timeFilter["$gte"] = bound(window.Since)
+	}
+	if !window.Until.IsZero() {
+		timeFilter["$lte"] = bound(window.Until)
+	}
+	items, err := this.queryHistory(ctx, query.IDs, kind, selectAll, timeFilter)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		resource := resMap[item.Meta.ID]
+		resource.States = append(resource.States, model.State{Time: item.Time, Connected: item.Connected})
+		resMap[item.Meta.ID] = resource
+	}
+	if window.WithNext {
+		items, err := this.queryHistory(ctx, query.IDs, kind, selectFirst, bson.M{"$gt": bound(window.Until)})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			resource := resMap[item.Meta.ID]
+			resource.NextState = &model.State{Time: item.Time, Connected: item.Connected}
+			resMap[item.Meta.ID] = resource
+		}
+	}
+	return resMap, nil
+}
+
+func (this *Mongo) GetOfflineSince(ctx context.Context, ids []string, kind string, notBefore time.Time) ([]model.OfflineSinceResponse, error) {
+	var timeFilter bson.M
+	if !notBefore.IsZero() {
+		timeFilter = bson.M{"$gte": bound(notBefore)}
+	}
+	items, err := this.queryHistory(ctx, ids, kind, selectLast, timeFilter)
+	if err != nil {
+		return nil, err
+	}
+	result := []model.OfflineSinceResponse{}
+	for _, item := range items {
+		if item.Connected {
+			continue
+		}
+		result = append(result, model.OfflineSinceResponse{ID: item.Meta.ID, OfflineSince: time.Unix(item.Time.Unix(), 0)})
+	}
+	slices.SortFunc(result, func(a, b model.OfflineSinceResponse) int {
+		return a.OfflineSince.Compare(b.OfflineSince)
+	})
+	return result, nil
+}
+
+func (this *Mongo) GetLastStates(ctx context.Context, ids []string, kind string) (map[string]model.State, error) {
+	items, err := this.queryHistory(ctx, ids, kind, selectLast, nil)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]model.State{}
+	for _, item := range items {
+		result[item.Meta.ID] = model.State{Time: item.Time, Connected: item.Connected}
+	}
+	return result, nil
+}
+
+func (this *Mongo) GetFirstOnlineStates(ctx context.Context, ids []string, kind string, after time.Time) (map[string]model.State, error) {
+	items, err := this.queryHistory(ctx, ids, kind, selectFirst, bson.M{"$gte": bound(after)}, bson.E{Key: "connected", Value: true})
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]model.State{}
+	for _, item := range items {
+		result[item.Meta.ID] = model.State{Time: item.Time, Connected: item.Connected}
+	}
+	return result, nil
+}
+
+func (this *Mongo) GetResourcesHistory(ctx context.Context, ids []string, kind string, duration string) (any, error) {
+	dur, err := store.ParseInfluxDuration(duration)
+	if err != nil {
+		return nil, err
+	}
+	items, err := this.queryHistory(ctx, ids, kind, selectAll, bson.M{"$gt": time.Now().Add(-dur)})
+	if err != nil {
+		return nil, err
+	}
+	result := store.HistoryResult{Series: []store.HistorySeries{}}
+	for i, item := range items {
+		if i == 0 || items[i-1].Meta.ID != item.Meta.ID {
+			result.Series = append(result.Series, store.HistorySeries{
+				Name:    kind,
+				Tags:    map[string]string{kind: item.Meta.ID},
+				Columns: []string{"time", "connected"},
+			})
+		}
+		series := &result.Series[len(result.Series)-1]
+		series.Values = append(series.Values, []any{item.Time.Unix(), item.Connected})
+	}
+	return []store.HistoryResult{result}, nil
+}
+
+func (this *Mongo) GetResourcesLogstart(ctx context.Context, ids []string, kind string) (map[string]float64, error) {
+	items, err := this.queryHistory(ctx, ids, kind, selectFirst, nil)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]float64{}
+	for _, item := range items {
+		result[item.Meta.ID] = float64(item.Time.Unix())
+	}
+	return result, nil
+}
+
+func (this *Mongo) GetResourcesLogEdge(ctx context.Context, ids []string, kind string, duration string) (map[string]any, error) {
+	dur, err := store.ParseInfluxDuration(duration)
+	if err != nil {
+		return nil, err
+	}
+	items, err := this.queryHistory(ctx, ids, kind, selectLast, bson.M{"$lt": time.Now().Add(-dur)})
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]any{}
+	for _, item := range items {
+		result[item.Meta.ID] = []any{item.Time.Unix(), item.Connected}
+	}
+	return result, nil
+}
+
+// AddHistoricalState inserts the state unless it is already stored, time-series collections do not support unique indexes.
+// The insert into the key collection is the atomic check: its _id is the key of the state, so concurrent writers of the same state
+// get a duplicate key error. States stored before the key collection existed are found by the count afterward.
+// A key older than the timeout whose state is missing was left by a failed writer and is taken over.
+func (this *Mongo) AddHistoricalState(ctx context.Context, id, kind string, state model.State) (bool, error) {
+	timeout := time.Duration(this.config.MongodbTimeout) * time.Second
+	ctxWt, cf := context.WithTimeout(ctx, timeout)
+	defer cf()
+	key := bson.D{{Key: "kind", Value: kind}, {Key: "id", Value: id}, {Key: "time", Value: state.Time}}
+	now := time.Now()
+	_, err := this.getHistoryKeyCollection().InsertOne(ctxWt, bson.M{"_id": key, historyKeyCreatedField: now})
+	if mongo.IsDuplicateKeyError(err) {
+		err = this.getHistoryKeyCollection().FindOneAndUpdate(ctxWt,
+			bson.M{"_id": key, historyKeyCreatedField: bson.M{"$lt": now.Add(-timeout)}},
+			bson.M{"$set": bson.M{historyKeyCreatedField: now}},
+		).Err()
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return false, nil
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	count, err := this.getHistoryCollection().CountDocuments(ctxWt, bson.M{
+		historyMetaField + ".kind": kind,
+		historyMetaField + ".id":   id,
+		historyTimeField:           state.Time,
+	}, options.Count().SetLimit(1))
+	if err == nil && count == 0 {
+		_, err = this.getHistoryCollection().InsertOne(ctxWt, HistoryState{
+			Time:      state.Time,
+			Meta:      HistoryMeta{Kind: kind, ID: id},
+			Connected: state.Connected,
+		})
+	}
+	if err != nil {
+		// the key is removed again, so a retry can add the state, ctxWt may be expired already
+		cleanupCtx, cleanupCf := context.WithTimeout(context.Background(), timeout)
+		defer cleanupCf()
+		_, _ = this.getHistoryKeyCollection().DeleteOne(cleanupCtx, bson.M{"_id": key})
+		return false, err
+	}
+	return count == 0, nil
+}
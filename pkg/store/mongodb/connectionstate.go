@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (this *Mongo) GetCurrentState(ctx context.Context, id, kind string) (model.ResourceCurrentState, error) {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	res := this.getCollection(kind).FindOne(ctxWt, bson.M{kind: id})
+	if err := res.Err(); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.ResourceCurrentState{}, store.ErrNotFound
+		}
+		return model.ResourceCurrentState{}, err
+	}
+	var item State
+	if err := res.Decode(&item); err != nil {
+		return model.ResourceCurrentState{}, err
+	}
+	return item.ResourceCurrentState(kind), nil
+}
+
+func (this *Mongo) QueryCurrentStates(ctx context.Context, ids []string, kind string) (map[string]bool, error) {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	cursor, err := this.getCollection(kind).Find(ctxWt, bson.M{kind: bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+	states := map[string]bool{}
+	for cursor.Next(ctxWt) {
+		var item State
+		if err = cursor.Decode(&item); err != nil {
+			return nil, err
+		}
+		states[item.ID(kind)] = item.Online
+	}
+	if err = cursor.Err(); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+func (this *Mongo) ListCurrentStates(ctx context.Context, ids []string, kind string) ([]model.ResourceCurrentState, error) {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	cursor, err := this.getCollection(kind).Find(ctxWt, bson.M{kind: bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+	states := []model.ResourceCurrentState{}
+	for cursor.Next(ctxWt) {
+		var item State
+		if err = cursor.Decode(&item); err != nil {
+			return nil, err
+		}
+		states = append(states, item.ResourceCurrentState(kind))
+	}
+	if err = cursor.Err(); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+// SetCurrentState upserts the state document, the stored time decides whether the state is newer than the current one.
+// since is set on transitions; documents of the connectionlog-worker keep a missing since until the next transition.
+func (this *Mongo) SetCurrentState(ctx context.Context, id, kind string, state model.State) (bool, error) {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	outdated := bson.M{"$gt": bson.A{"$time", state.Time}}
+	unchanged := bson.M{"$eq": bson.A{"$online", state.Connected}}
+	res := this.getCollection(kind).FindOneAndUpdate(ctxWt, bson.M{kind: id}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"online": bson.M{"$cond": bson.A{outdated, "$online", state.Connected}},
+			"time":   bson.M{"$cond": bson.A{outdated, "$time", state.Time}},
+			"since":  bson.M{"$cond": bson.A{bson.M{"$or": bson.A{outdated, unchanged}}, "$since", state.Time}},
+		}}},
+	}, options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After))
+	if err := res.Err(); err != nil {
+		return false, err
+	}
+	var item State
+	if err := res.Decode(&item); err != nil {
+		return false, err
+	}
+	return item.Time.Equal(state.Time.Truncate(time.Millisecond)), nil
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type DigestSubscription struct {
+	ID        string              `bson:"id"`
+	Owner     string              `bson:"owner"`
+	Email     string              `bson:"email"`
+	Frequency string              `bson:"frequency"`
+	Hour      int                 `bson:"hour"`
+	Weekday   int                 `bson:"weekday"`
+	Timezone  string              `bson:"timezone"`
+	IDs       []string            `bson:"ids"`
+	LastSent  *time.Time          `bson:"last_sent,omitempty"`
+	Members   map[string][]string `bson:"members"`
+	Names     map[string]string   `bson:"names"`
+}
+
+func createDigestIndexes(config configuration.Config, db *mongo.Client) error {
+	collection := db.Database(config.MongoTable).Collection(config.DigestCollection)
+	ctx, cf := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cf()
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetName("id_1").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "owner", Value: 1}},
+			Options: options.Index().SetName("owner_1"),
+		},
+	})
+	return err
+}
+
+func (this *Mongo) getDigestCollection() *mongo.Collection {
+	return this.client.Database(this.config.MongoTable).Collection(this.config.DigestCollection)
+}
+
+func (this *Mongo) ListDigestSubscriptions(ctx context.Context, owner string) ([]model.DigestSubscription, error) {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	filter := bson.M{}
+	if owner != "" {
+		filter["owner"] = owner
+	}
+	cursor, err := this.getDigestCollection().Find(ctxWt, filter, options.Find().SetSort(bson.D{{Key: "id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+	result := []model.DigestSubscription{}
+	for cursor.Next(ctxWt) {
+		var subscription DigestSubscription
+		if err = cursor.Decode(&subscription); err != nil {
+			return nil, err
+		}
+		result = append(result, model.DigestSubscription(subscription))
+	}
+	return result, cursor.Err()
+}
+
+func (this *Mongo) GetDigestSubscription(ctx context.Context, id string) (model.DigestSubscription, error) {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	var subscription DigestSubscription
+	err := this.getDigestCollection().FindOne(ctxWt, bson.M{"id": id}).Decode(&subscription)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return model.DigestSubscription{}, store.ErrNotFound
+	}
+	if err != nil {
+		return model.DigestSubscription{}, err
+	}
+	return model.DigestSubscription(subscription), nil
+}
+
+func (this *Mongo) SetDigestSubscription(ctx context.Context, subscription model.DigestSubscription) error {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	_, err := this.getDigestCollection().ReplaceOne(ctxWt, bson.M{"id": subscription.ID}, DigestSubscription(subscription), options.Replace().SetUpsert(true))
+	return err
+}
+
+func (this *Mongo) DeleteDigestSubscription(ctx context.Context, id string) error {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	_, err := this.getDigestCollection().DeleteOne(ctxWt, bson.M{"id": id})
+	return err
+}
+
+func (this *Mongo) SetDigestSent(ctx context.Context, id string, sent time.Time) error {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	_, err := this.getDigestCollection().UpdateOne(ctxWt, bson.M{"id": id}, bson.M{"$set": bson.M{"last_sent": sent}})
+	return err
+}
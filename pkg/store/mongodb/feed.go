@@ -0,0 +1,318 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mongodb
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// feedBuffer is the number of changes buffered per watcher of a changeFeed.
+// Watchers that fall further behind are closed and may resume with their last token.
+const feedBuffer = 256
+
+// changeStream is implemented by *mongo.ChangeStream.
+type changeStream interface {
+	TryNext(ctx context.Context) bool
+	Decode(val interface{}) error
+	ResumeToken() bson.Raw
+	Err() error
+	Close(ctx context.Context) error
+}
+
+type changeEvent struct {
+	ID           bson.Raw            `bson:"_id"`
+	ClusterTime  primitive.Timestamp `bson:"clusterTime"`
+	FullDocument *State              `bson:"fullDocument"`
+}
+
+// feedChange is a state change with its position in the change stream, the resume token of the change is not set.
+type feedChange struct {
+	change model.StateChange
+	token  bson.Raw
+	time   primitive.Timestamp
+}
+
+// changeFeed shares one change stream of all documents of a state collection and passes the changes to the watchers of their ids.
+// The stream is opened by the first watcher and closed after the last watcher is gone.
+// Watchers with a resume token read the changes they missed from a dedicated stream of their ids, until it reaches the shared stream.
+type changeFeed struct {
+	kind   string
+	open   func(ctx context.Context, kind string, ids []string, resumeAfter bson.Raw) (changeStream, error)
+	logger *slog.Logger
+
+	mux      sync.Mutex
+	stop     context.CancelFunc // nil if the stream is closed
+	position bson.Raw           // resume token of the shared stream, new watchers start here
+	watchers map[*feedWatcher]bool
+}
+
+type feedWatcher struct {
+	ids  map[string]bool
+	live chan feedChange // closed if the watcher falls behind or the shared stream fails
+}
+
+func newChangeFeed(kind string, open func(ctx context.Context, kind string, ids []string, resumeAfter bson.Raw) (changeStream, error), logger *slog.Logger) *changeFeed {
+	return &changeFeed{kind: kind, open: open, logger: logger, watchers: map[*feedWatcher]bool{}}
+}
+
+// watch sends the changes of ids to changes until ctx is done or the feed fails, done is called afterward.
+// Without resumeAfter the changes start at the returned position of the shared stream.
+func (this *changeFeed) watch(ctx context.Context, ids []string, resumeAfter bson.Raw, changes chan<- feedChange, done func()) (start bson.Raw, err error) {
+	watcher := &feedWatcher{ids: map[string]bool{}, live: make(chan feedChange, feedBuffer)}
+	for _, id := range ids {
+		watcher.ids[id] = true
+	}
+	start, err = this.add(watcher)
+	if err != nil {
+		return nil, err
+	}
+	var missed changeStream
+	if resumeAfter != nil {
+		missed, err = this.open(ctx, this.kind, ids, resumeAfter)
+		if err != nil {
+			this.remove(watcher)
+			return nil, err
+		}
+		start = nil
+	}
+	go func() {
+		defer done()
+		defer this.remove(watcher)
+		this.forward(ctx, watcher, missed, changes)
+	}()
+	return start, nil
+}
+
+// forward sends the changes of missed until it has no further changes, followed by the changes of the shared stream.
+// Changes of the shared stream already sent from missed are skipped, both streams return the changes in the order of their cluster time.
+func (this *changeFeed) forward(ctx context.Context, watcher *feedWatcher, missed changeStream, changes chan<- feedChange) {
+	send := func(change feedChange) bool {
+		select {
+		case changes <- change:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	var last primitive.Timestamp
+	sent := map[string]bool{} // tokens of the sent changes with cluster time last
+	pending := []feedChange{}
+	receive := func() bool {
+		for {
+			select {
+			case change, ok := <-watcher.live:
+				if !ok || len(pending) >= feedBuffer {
+					return false
+				}
+				pending = append(pending, change)
+			default:
+				return true
+			}
+		}
+	}
+	defer func() {
+		if missed != nil {
+			_ = missed.Close(context.Background())
+		}
+	}()
+	if missed != nil {
+		for {
+			if !receive() {
+				return
+			}
+			if !missed.TryNext(ctx) {
+				break
+			}
+			change, ok := this.decode(missed)
+			if !ok {
+				continue
+			}
+			if last.Compare(change.time) != 0 {
+				clear(sent)
+			}
+			last = change.time
+			sent[string(change.token)] = true
+			if !send(change) {
+				return
+			}
+		}
+		// a getMore without changes reached the end of the oplog, later changes are received by the shared stream
+		if err := missed.Err(); err != nil {
+			if ctx.Err() == nil {
+				this.logger.Error("change stream failed", "kind", this.kind, "error", err)
+			}
+			return
+		}
+		if !receive() {
+			return
+		}
+		_ = missed.Close(context.Background())
+		missed = nil
+	}
+	skip := func(change feedChange) bool {
+		c := change.time.Compare(last)
+		return c < 0 || (c == 0 && sent[string(change.token)])
+	}
+	for _, change := range pending {
+		if !skip(change) && !send(change) {
+			return
+		}
+	}
+	for {
+		select {
+		case change, ok := <-watcher.live:
+			if !ok {
+				return
+			}
+			if !skip(change) && !send(change) {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// add registers watcher and opens the shared stream if necessary.
+func (this *changeFeed) add(watcher *feedWatcher) (bson.Raw, error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if this.stop == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		stream, err := this.open(ctx, this.kind, nil, nil)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		this.stop = cancel
+		this.position = stream.ResumeToken()
+		go this.run(ctx, stream)
+	}
+	this.watchers[watcher] = true
+	return this.position, nil
+}
+
+// remove unregisters watcher and closes the shared stream after the last watcher.
+func (this *changeFeed) remove(watcher *feedWatcher) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.removeLocked(watcher)
+}
+
+func (this *changeFeed) removeLocked(watcher *feedWatcher) {
+	if !this.watchers[watcher] {
+		return
+	}
+	delete(this.watchers, watcher)
+	close(watcher.live)
+	if len(this.watchers) == 0 {
+		this.closeLocked()
+	}
+}
+
+func (this *changeFeed) closeLocked() {
+	if this.stop != nil {
+		this.stop()
+		this.stop = nil
+		this.position = nil
+	}
+}
+
+// Close closes the shared stream and all watchers.
+func (this *changeFeed) Close() {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	for watcher := range this.watchers {
+		this.removeLocked(watcher)
+	}
+	this.closeLocked()
+}
+
+// run passes the changes of the shared stream to the watchers until ctx is done, all watchers are closed if the stream fails.
+func (this *changeFeed) run(ctx context.Context, stream changeStream) {
+	defer stream.Close(context.Background())
+	for {
+		next := stream.TryNext(ctx)
+		var change feedChange
+		if next {
+			change, next = this.decode(stream)
+		}
+		this.mux.Lock()
+		if ctx.Err() != nil {
+			// closed by closeLocked, the watchers may already belong to a new stream
+			this.mux.Unlock()
+			return
+		}
+		if err := stream.Err(); err != nil {
+			this.logger.Error("change stream failed", "kind", this.kind, "error", err)
+			for watcher := range this.watchers {
+				this.removeLocked(watcher)
+			}
+			this.closeLocked()
+			this.mux.Unlock()
+			return
+		}
+		this.position = stream.ResumeToken()
+		if next {
+			for watcher := range this.watchers {
+				if !watcher.ids[change.change.ID] {
+					continue
+				}
+				select {
+				case watcher.live <- change:
+				default:
+					this.logger.Warn("close change stream watcher, buffer is full", "kind", this.kind)
+					this.removeLocked(watcher)
+				}
+			}
+		}
+		this.mux.Unlock()
+	}
+}
+
+func (this *changeFeed) decode(stream changeStream) (feedChange, bool) {
+	var event changeEvent
+	if err := stream.Decode(&event); err != nil {
+		this.logger.Error("unable to decode change event", "error", err)
+		return feedChange{}, false
+	}
+	if event.FullDocument == nil {
+		// document deleted before the lookup
+		return feedChange{}, false
+	}
+	t := event.FullDocument.Time
+	if t.IsZero() {
+		t = time.Unix(int64(event.ClusterTime.T), 0)
+	}
+	return feedChange{
+		change: model.StateChange{
+			ID:        event.FullDocument.ID(this.kind),
+			Kind:      this.kind,
+			Connected: event.FullDocument.Online,
+			Time:      t.UTC(),
+		},
+		token: event.ID,
+		time:  event.ClusterTime,
+	}, true
+}
@@ -0,0 +1,307 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testOplog is a state collection with change streams, the resume token "n" is the position after the first n changes.
+type testOplog struct {
+	mux     sync.Mutex
+	changes []changeEvent
+	streams []*testStream
+}
+
+func testToken(position int) bson.Raw {
+	raw, _ := bson.Marshal(bson.M{"_data": strconv.Itoa(position)})
+	return raw
+}
+
+func (this *testOplog) add(kind string, id string, online bool) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	document := &State{Online: online}
+	if kind == model.GatewayKind {
+		document.GatewayID = id
+	} else {
+		document.DeviceID = id
+	}
+	position := len(this.changes) + 1
+	this.changes = append(this.changes, changeEvent{ID: testToken(position), ClusterTime: primitive.Timestamp{T: uint32(position)}, FullDocument: document})
+}
+
+func (this *testOplog) open(ctx context.Context, kind string, ids []string, resumeAfter bson.Raw) (changeStream, error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	stream := &testStream{oplog: this, kind: kind, ids: ids, position: len(this.changes)}
+	if resumeAfter != nil {
+		position, err := strconv.Atoi(resumeAfter.Lookup("_data").StringValue())
+		if err != nil || position > len(this.changes) {
+			return nil, errors.New("unknown resume token")
+		}
+		stream.position = position
+	}
+	this.streams = append(this.streams, stream)
+	return stream, nil
+}
+
+// count returns the number of open streams of all documents and of selected ids.
+func (this *testOplog) count() (shared int, dedicated int) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	for _, stream := range this.streams {
+		switch {
+		case stream.closed:
+		case stream.ids == nil:
+			shared++
+		default:
+			dedicated++
+		}
+	}
+	return shared, dedicated
+}
+
+type testStream struct {
+	oplog    *testOplog
+	kind     string
+	ids      []string
+	position int
+	current  changeEvent
+	closed   bool
+	err      error
+}
+
+func (this *testStream) TryNext(ctx context.Context) bool {
+	if this.err = ctx.Err(); this.err != nil {
+		return false
+	}
+	this.oplog.mux.Lock()
+	for this.position < len(this.oplog.changes) {
+		change := this.oplog.changes[this.position]
+		this.position++
+		if this.ids == nil || slices.Contains(this.ids, change.FullDocument.ID(this.kind)) {
+			this.current = change
+			this.oplog.mux.Unlock()
+			return true
+		}
+	}
+	this.oplog.mux.Unlock()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Millisecond):
+	}
+	return false
+}
+
+func (this *testStream) Decode(val interface{}) error {
+	raw, err := bson.Marshal(this.current)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(raw, val)
+}
+
+func (this *testStream) ResumeToken() bson.Raw {
+	this.oplog.mux.Lock()
+	defer this.oplog.mux.Unlock()
+	return testToken(this.position)
+}
+
+func (this *testStream) Err() error {
+	return this.err
+}
+
+func (this *testStream) Close(context.Context) error {
+	this.oplog.mux.Lock()
+	defer this.oplog.mux.Unlock()
+	this.closed = true
+	return nil
+}
+
+func receive(t *testing.T, changes <-chan model.StateChange, count int) []model.StateChange {
+	t.Helper()
+	result := []model.StateChange{}
+	for range count {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				t.Fatal("closed change stream")
+			}
+			result = append(result, change)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("missing change after %v", result)
+		}
+	}
+	select {
+	case change, ok := <-changes:
+		if ok {
+			t.Fatalf("unexpected change %v", change)
+		}
+	case <-time.After(20 * time.Millisecond):
+	}
+	return result
+}
+
+func summary(changes []model.StateChange) []string {
+	result := []string{}
+	for _, change := range changes {
+		result = append(result, fmt.Sprintf("%v/%v", change.ID, change.Connected))
+	}
+	slices.Sort(result)
+	return result
+}
+
+func waitOpen(t *testing.T, oplog *testOplog, shared int, dedicated int) {
+	t.Helper()
+	for range 500 {
+		if s, d := oplog.count(); s == shared && d == dedicated {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s, d := oplog.count()
+	t.Fatalf("expected %v shared and %v dedicated streams, got %v and %v", shared, dedicated, s, d)
+}
+
+func TestWatchCurrentStates(t *testing.T) {
+	config := configuration.Config{LogLevel: "error"}
+	devices, gateways := &testOplog{}, &testOplog{}
+	this := &Mongo{config: config, feeds: map[string]*changeFeed{
+		model.DeviceKind:  newChangeFeed(model.DeviceKind, devices.open, config.GetLogger()),
+		model.GatewayKind: newChangeFeed(model.GatewayKind, gateways.open, config.GetLogger()),
+	}}
+	defer this.feeds[model.DeviceKind].Close()
+	defer this.feeds[model.GatewayKind].Close()
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	defer cancel1()
+	changes1, err := this.WatchCurrentStates(ctx1, map[string][]string{model.DeviceKind: {"a"}, model.GatewayKind: {"g"}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	changes2, err := this.WatchCurrentStates(ctx2, map[string][]string{model.DeviceKind: {"b"}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitOpen(t, devices, 1, 0)
+	waitOpen(t, gateways, 1, 0)
+
+	devices.add(model.DeviceKind, "a", true)
+	devices.add(model.DeviceKind, "b", true)
+	gateways.add(model.GatewayKind, "g", true)
+	received := receive(t, changes1, 2)
+	if result := summary(received); !slices.Equal(result, []string{"a/true", "g/true"}) {
+		t.Errorf("unexpected changes %v", result)
+	}
+	if result := summary(receive(t, changes2, 1)); !slices.Equal(result, []string{"b/true"}) {
+		t.Errorf("unexpected changes %v", result)
+	}
+
+	// changes after the token are sent after resuming, without duplicates of the shared stream
+	cancel1()
+	devices.add(model.DeviceKind, "a", false)
+	gateways.add(model.GatewayKind, "g", false)
+	devices.add(model.DeviceKind, "b", false)
+	if result := summary(receive(t, changes2, 1)); !slices.Equal(result, []string{"b/false"}) {
+		t.Errorf("unexpected changes %v", result)
+	}
+	waitOpen(t, gateways, 0, 0)
+	ctx3, cancel3 := context.WithCancel(context.Background())
+	defer cancel3()
+	changes3, err := this.WatchCurrentStates(ctx3, map[string][]string{model.DeviceKind: {"a"}, model.GatewayKind: {"g"}}, received[1].ResumeToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := summary(receive(t, changes3, 2)); !slices.Equal(result, []string{"a/false", "g/false"}) {
+		t.Errorf("unexpected changes %v", result)
+	}
+	waitOpen(t, devices, 1, 0)
+	waitOpen(t, gateways, 1, 0)
+	devices.add(model.DeviceKind, "a", true)
+	if result := summary(receive(t, changes3, 1)); !slices.Equal(result, []string{"a/true"}) {
+		t.Errorf("unexpected changes %v", result)
+	}
+
+	// the shared streams are closed with the last watcher
+	cancel2()
+	cancel3()
+	waitOpen(t, devices, 0, 0)
+	waitOpen(t, gateways, 0, 0)
+
+	for _, token := range []string{"invalid", "e30", received[1].ResumeToken[:10]} {
+		if _, err = this.WatchCurrentStates(context.Background(), map[string][]string{model.DeviceKind: {"a"}}, token); err == nil {
+			t.Errorf("expected error for token %q", token)
+		}
+	}
+}
+
+func TestChangeFeedForward(t *testing.T) {
+	config := configuration.Config{LogLevel: "error"}
+	oplog := &testOplog{}
+	feed := newChangeFeed(model.DeviceKind, oplog.open, config.GetLogger())
+	oplog.add(model.DeviceKind, "a", true)
+	oplog.add(model.DeviceKind, "b", true)
+	oplog.add(model.DeviceKind, "a", false)
+	live := func(position int) feedChange {
+		change := oplog.changes[position-1]
+		return feedChange{
+			change: model.StateChange{ID: change.FullDocument.DeviceID, Kind: model.DeviceKind, Connected: change.FullDocument.Online, Time: time.Unix(int64(position), 0).UTC()},
+			token:  change.ID,
+			time:   change.ClusterTime,
+		}
+	}
+
+	// the shared stream received the last change before the dedicated stream caught up
+	watcher := &feedWatcher{ids: map[string]bool{"a": true}, live: make(chan feedChange, feedBuffer)}
+	watcher.live <- live(3)
+	missed, err := oplog.open(context.Background(), model.DeviceKind, []string{"a"}, testToken(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan feedChange)
+	go feed.forward(ctx, watcher, missed, changes)
+	result := []string{}
+	for range 2 {
+		change := <-changes
+		result = append(result, fmt.Sprintf("%v/%v/%v", change.change.ID, change.change.Connected, change.time.T))
+	}
+	oplog.add(model.DeviceKind, "a", true)
+	watcher.live <- live(4)
+	change := <-changes
+	result = append(result, fmt.Sprintf("%v/%v/%v", change.change.ID, change.change.Connected, change.time.T))
+	if expected := []string{"a/true/1", "a/false/3", "a/true/4"}; !slices.Equal(result, expected) {
+		t.Errorf("\n%v\n%v", result, expected)
+	}
+	waitOpen(t, oplog, 0, 0)
+}
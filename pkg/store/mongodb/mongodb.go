@@ -0,0 +1,229 @@
+/*
+ * Copyright 2018 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Mongo struct {
+	config configuration.Config
+	client *mongo.Client
+	feeds  map[string]*changeFeed
+}
+
+func New(config configuration.Config) (*Mongo, error) {
+	ctx, cf := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cf()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(config.MongoUrl))
+	if err != nil {
+		return nil, err
+	}
+	err = createIndexes(config, client)
+	if err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+	result := &Mongo{config: config, client: client}
+	result.feeds = map[string]*changeFeed{
+		model.DeviceKind:  newChangeFeed(model.DeviceKind, result.openChangeStream, config.GetLogger()),
+		model.GatewayKind: newChangeFeed(model.GatewayKind, result.openChangeStream, config.GetLogger()),
+	}
+	return result, nil
+}
+
+func (this *Mongo) Close() error {
+	for _, feed := range this.feeds {
+		feed.Close()
+	}
+	return this.client.Disconnect(context.Background())
+}
+
+func createIndexes(config configuration.Config, db *mongo.Client) error {
+	err := createDeviceIndexes(config, db)
+	if err != nil {
+		return err
+	}
+	err = createGatewayIndexes(config, db)
+	if err != nil {
+		return err
+	}
+	err = createWebhookIndexes(config, db)
+	if err != nil {
+		return err
+	}
+	err = createAlertIndexes(config, db)
+	if err != nil {
+		return err
+	}
+	return createDigestIndexes(config, db)
+}
+
+func createDeviceIndexes(config configuration.Config, db *mongo.Client) error {
+	collection := db.Database(config.MongoTable).Collection(config.DeviceStateCollection)
+	indexname := "device_1"
+	indexkey := "device"
+	direction := 1
+	ctx, cf := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cf()
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: indexkey, Value: direction}},
+		Options: options.Index().SetName(indexname),
+	})
+	return err
+}
+
+func createGatewayIndexes(config configuration.Config, db *mongo.Client) error {
+	collection := db.Database(config.MongoTable).Collection(config.GatewayStateCollection)
+	indexname := "gateway_1"
+	indexkey := "gateway"
+	direction := 1
+	ctx, cf := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cf()
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: indexkey, Value: direction}},
+		Options: options.Index().SetName(indexname),
+	})
+	return err
+}
+
+// CreateHistoryCollection prepares the time-series collection used by the mongodb history store.
+func (this *Mongo) CreateHistoryCollection() error {
+	return createHistoryCollection(this.config, this.client)
+}
+
+func createHistoryCollection(config configuration.Config, db *mongo.Client) error {
+	ctx, cf := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cf()
+	err := db.Database(config.MongoTable).CreateCollection(ctx, config.HistoryCollection, options.CreateCollection().SetTimeSeriesOptions(
+		options.TimeSeries().SetTimeField(historyTimeField).SetMetaField(historyMetaField).SetGranularity("seconds"),
+	))
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Name == "NamespaceExists" {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	collection := db.Database(config.MongoTable).Collection(config.HistoryCollection)
+	indexname := "meta_kind_1_meta_id_1_time_1"
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: historyMetaField + ".kind", Value: 1}, {Key: historyMetaField + ".id", Value: 1}, {Key: historyTimeField, Value: 1}},
+		Options: options.Index().SetName(indexname),
+	})
+	if err != nil {
+		return err
+	}
+	return createHistoryKeyIndex(ctx, config, db)
+}
+
+// createHistoryKeyIndex removes keys of the history collection after HistoryKeyRetention.
+// Keys only guard concurrent inserts, older duplicates are found by the count in AddHistoricalState.
+func createHistoryKeyIndex(ctx context.Context, config configuration.Config, db *mongo.Client) error {
+	if config.HistoryKeyRetention == "" || config.HistoryKeyRetention == "-" {
+		return nil
+	}
+	retention, err := time.ParseDuration(config.HistoryKeyRetention)
+	if err != nil {
+		return fmt.Errorf("invalid HistoryKeyRetention: %w", err)
+	}
+	if retention < time.Second {
+		return fmt.Errorf("invalid HistoryKeyRetention: %v is less than a second", retention)
+	}
+	indexname := historyKeyCreatedField + "_ttl"
+	collection := db.Database(config.MongoTable).Collection(config.HistoryCollection + "_keys")
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: historyKeyCreatedField, Value: 1}},
+		Options: options.Index().SetName(indexname).SetExpireAfterSeconds(int32(retention.Seconds())),
+	})
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Name == "IndexOptionsConflict" {
+		// the retention changed since the index was created
+		err = db.Database(config.MongoTable).RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: collection.Name()},
+			{Key: "index", Value: bson.M{"name": indexname, "expireAfterSeconds": int32(retention.Seconds())}},
+		}).Err()
+	}
+	return err
+}
+
+func (this *Mongo) getCollection(kind string) *mongo.Collection {
+	if kind == model.GatewayKind {
+		return this.client.Database(this.config.MongoTable).Collection(this.config.GatewayStateCollection)
+	}
+	return this.client.Database(this.config.MongoTable).Collection(this.config.DeviceStateCollection)
+}
+
+func (this *Mongo) getHistoryCollection() *mongo.Collection {
+	return this.client.Database(this.config.MongoTable).Collection(this.config.HistoryCollection)
+}
+
+// getHistoryKeyCollection returns the collection with the keys of stored history states, see AddHistoricalState.
+func (this *Mongo) getHistoryKeyCollection() *mongo.Collection {
+	return this.client.Database(this.config.MongoTable).Collection(this.config.HistoryCollection + "_keys")
+}
+
+type State struct {
+	DeviceID  string    `json:"device,omitempty" bson:"device,omitempty"`
+	GatewayID string    `json:"gateway,omitempty" bson:"gateway,omitempty"`
+	Online    bool      `json:"online" bson:"online"`
+	Time      time.Time `json:"time,omitempty" bson:"time,omitempty"`   // Time of the latest applied state change, not set by the connectionlog-worker.
+	Since     time.Time `json:"since,omitempty" bson:"since,omitempty"` // Time of the last transition between online and offline, not set by the connectionlog-worker.
+}
+
+func (this State) ResourceCurrentState(kind string) model.ResourceCurrentState {
+	result := model.ResourceCurrentState{ID: this.ID(kind), Connected: this.Online}
+	if !this.Since.IsZero() {
+		since := this.Since.UTC()
+		result.Since = &since
+	}
+	return result
+}
+
+func (this State) ID(kind string) string {
+	if kind == model.GatewayKind {
+		return this.GatewayID
+	}
+	return this.DeviceID
+}
+
+const (
+	historyTimeField = "time"
+	historyMetaField = "meta"
+
+	historyKeyCreatedField = "created"
+)
+
+type HistoryState struct {
+	Time      time.Time   `json:"time" bson:"time"`
+	Meta      HistoryMeta `json:"meta" bson:"meta"`
+	Connected bool        `json:"connected" bson:"connected"`
+}
+
+type HistoryMeta struct {
+	Kind string `json:"kind" bson:"kind"`
+	ID   string `json:"id" bson:"id"`
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mongodb
+
+import (
+	"context"
+	"encoding/base64"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WatchCurrentStates shares one change stream per state collection between all watchers, see changeFeed.
+// Updates are only reported if the online field changed. Resume tokens are base64 encoded documents
+// with the change stream token of each kind, kinds without token start with new changes.
+func (this *Mongo) WatchCurrentStates(ctx context.Context, idsByKind map[string][]string, resumeToken string) (<-chan model.StateChange, error) {
+	tokens, err := decodeResumeToken(resumeToken)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	feedChanges := make(chan feedChange)
+	wg := sync.WaitGroup{}
+	for kind, ids := range idsByKind {
+		if len(ids) == 0 {
+			continue
+		}
+		wg.Add(1)
+		start, err := this.feeds[kind].watch(ctx, ids, tokens[kind], feedChanges, func() {
+			cancel()
+			wg.Done()
+		})
+		if err != nil {
+			wg.Done()
+			cancel()
+			wg.Wait()
+			return nil, err
+		}
+		if start != nil {
+			tokens[kind] = start
+		}
+	}
+	go func() {
+		<-ctx.Done()
+		wg.Wait()
+		close(feedChanges)
+	}()
+	changes := make(chan model.StateChange)
+	go func() {
+		defer close(changes)
+		defer cancel()
+		for change := range feedChanges {
+			tokens[change.change.Kind] = change.token
+			change.change.ResumeToken = encodeResumeToken(tokens)
+			select {
+			case changes <- change.change:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return changes, nil
+}
+
+func decodeResumeToken(resumeToken string) (map[string]bson.Raw, error) {
+	tokens := map[string]bson.Raw{}
+	if resumeToken == "" {
+		return tokens, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(resumeToken)
+	if err != nil || bson.Raw(raw).Validate() != nil {
+		return nil, store.ErrInvalidResumeToken
+	}
+	elements, err := bson.Raw(raw).Elements()
+	if err != nil {
+		return nil, store.ErrInvalidResumeToken
+	}
+	for _, element := range elements {
+		token, ok := element.Value().DocumentOK()
+		if !ok || (element.Key() != model.DeviceKind && element.Key() != model.GatewayKind) {
+			return nil, store.ErrInvalidResumeToken
+		}
+		tokens[element.Key()] = token
+	}
+	return tokens, nil
+}
+
+func encodeResumeToken(tokens map[string]bson.Raw) string {
+	doc := bson.D{}
+	for _, kind := range slices.Sorted(maps.Keys(tokens)) {
+		doc = append(doc, bson.E{Key: kind, Value: tokens[kind]})
+	}
+	raw, _ := bson.Marshal(doc)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// openChangeStream watches the changes of the online field in the state collection of kind.
+// All documents of the collection are watched if ids is nil.
+func (this *Mongo) openChangeStream(ctx context.Context, kind string, ids []string, resumeAfter bson.Raw) (changeStream, error) {
+	match := bson.A{bson.M{"$or": bson.A{
+		bson.M{"operationType": bson.M{"$in": bson.A{"insert", "replace"}}},
+		bson.M{"operationType": "update", "updateDescription.updatedFields.online": bson.M{"$exists": true}},
+	}}}
+	if ids != nil {
+		match = append(match, bson.M{"fullDocument." + kind: bson.M{"$in": ids}})
+	}
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{"$and": match}}}}
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if resumeAfter != nil {
+		opts.SetResumeAfter(resumeAfter)
+	}
+	return this.getCollection(kind).Watch(ctx, pipeline, opts)
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Webhook struct {
+	ID        string               `bson:"id"`
+	Owner     string               `bson:"owner"`
+	URL       string               `bson:"url"`
+	Secret    string               `bson:"secret"`
+	IDs       []string             `bson:"ids"`
+	Threshold int64                `bson:"threshold"` // nanoseconds
+	Resources []string             `bson:"resources"`
+	Alerts    map[string]time.Time `bson:"alerts,omitempty"`
+}
+
+func (this Webhook) Model() model.Webhook {
+	return model.Webhook{
+		ID:        this.ID,
+		Owner:     this.Owner,
+		URL:       this.URL,
+		Secret:    this.Secret,
+		IDs:       this.IDs,
+		Threshold: model.Duration(this.Threshold),
+		Resources: this.Resources,
+		Alerts:    this.Alerts,
+	}
+}
+
+func createWebhookIndexes(config configuration.Config, db *mongo.Client) error {
+	collection := db.Database(config.MongoTable).Collection(config.WebhookCollection)
+	ctx, cf := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cf()
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "id", Value: 1}},
+			Options: options.Index().SetName("id_1").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "owner", Value: 1}},
+			Options: options.Index().SetName("owner_1"),
+		},
+	})
+	return err
+}
+
+func (this *Mongo) getWebhookCollection() *mongo.Collection {
+	return this.client.Database(this.config.MongoTable).Collection(this.config.WebhookCollection)
+}
+
+func (this *Mongo) ListWebhooks(ctx context.Context, owner string) ([]model.Webhook, error) {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	filter := bson.M{}
+	if owner != "" {
+		filter["owner"] = owner
+	}
+	cursor, err := this.getWebhookCollection().Find(ctxWt, filter, options.Find().SetSort(bson.D{{Key: "id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+	result := []model.Webhook{}
+	for cursor.Next(ctxWt) {
+		var webhook Webhook
+		if err = cursor.Decode(&webhook); err != nil {
+			return nil, err
+		}
+		result = append(result, webhook.Model())
+	}
+	return result, cursor.Err()
+}
+
+func (this *Mongo) GetWebhook(ctx context.Context, id string) (model.Webhook, error) {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	var webhook Webhook
+	err := this.getWebhookCollection().FindOne(ctxWt, bson.M{"id": id}).Decode(&webhook)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return model.Webhook{}, store.ErrNotFound
+	}
+	if err != nil {
+		return model.Webhook{}, err
+	}
+	return webhook.Model(), nil
+}
+
+func (this *Mongo) SetWebhook(ctx context.Context, webhook model.Webhook) error {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	_, err := this.getWebhookCollection().UpdateOne(ctxWt, bson.M{"id": webhook.ID}, bson.M{"$set": bson.M{
+		"owner":     webhook.Owner,
+		"url":       webhook.URL,
+		"secret":    webhook.Secret,
+		"ids":       webhook.IDs,
+		"threshold": int64(webhook.Threshold),
+		"resources": webhook.Resources,
+	}}, options.Update().SetUpsert(true))
+	return err
+}
+
+func (this *Mongo) DeleteWebhook(ctx context.Context, id string) error {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	_, err := this.getWebhookCollection().DeleteOne(ctxWt, bson.M{"id": id})
+	return err
+}
+
+func (this *Mongo) UpdateWebhookAlerts(ctx context.Context, id string, alerts map[string]time.Time, resolved []string) error {
+	ctxWt, cf := context.WithTimeout(ctx, time.Duration(this.config.MongodbTimeout)*time.Second)
+	defer cf()
+	update := bson.M{}
+	if len(alerts) > 0 {
+		set := bson.M{}
+		for resource, since := range alerts {
+			set["alerts."+resource] = since
+		}
+		update["$set"] = set
+	}
+	if len(resolved) > 0 {
+		unset := bson.M{}
+		for _, resource := range resolved {
+			unset["alerts."+resource] = ""
+		}
+		update["$unset"] = unset
+	}
+	if len(update) == 0 {
+		return nil
+	}
+	_, err := this.getWebhookCollection().UpdateOne(ctxWt, bson.M{"id": id}, update)
+	return err
+}
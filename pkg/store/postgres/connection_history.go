@@ -0,0 +1,269 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/store"
+	"github.com/lib/pq"
+)
+
+const (
+	selectAll   = ""
+	selectFirst = "ASC"
+	selectLast  = "DESC"
+)
+
+type row struct {
+	ID        string
+	Time      time.Time
+	Connected bool
+}
+
+// queryStates selects states of the given ids matching all conditions, the first condition argument is $3.
+// With selectFirst or selectLast only the first or last matching state per id is returned.
+func (this *Postgres) queryStates(ctx context.Context, ids []string, kind string, selector string, conditions []string, args ...any) ([]row, error) {
+	statement := statesStatement(this.table, selector, conditions)
+	ctxWt, cf := this.timeout(ctx)
+	defer cf()
+	rows, err := this.db.QueryContext(ctxWt, statement, append([]any{kind, pq.Array(ids)}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := []row{}
+	for rows.Next() {
+		var r row
+		if err = rows.Scan(&r.ID, &r.Time, &r.Connected); err != nil {
+			return nil, err
+		}
+		r.Time = r.Time.Truncate(time.Second).UTC()
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// statesStatement returns the statement of queryStates, kind is $1 and the ids are $2.
+func statesStatement(table string, selector string, conditions []string) string {
+	var b strings.Builder
+	if selector == selectAll {
+		b.WriteString(`SELECT id, time, connected FROM ` + table)
+	} else {
+		b.WriteString(`SELECT DISTINCT ON (id) id, time, connected FROM ` + table)
+	}
+	b.WriteString(` WHERE kind = $1 AND id = ANY($2)`)
+	for _, condition := range conditions {
+		b.WriteString(` AND ` + condition)
+	}
+	if selector == selectAll {
+		b.WriteString(` ORDER BY id, time`)
+	} else {
+		b.WriteString(` ORDER BY id, time ` + selector)
+	}
+	return b.String()
+}
+
+// statePrevQuery selects the last state with time < timestamp.
+func (this *Postgres) statePrevQuery(ctx context.Context, ids []string, kind string, timestamp time.Time) ([]row, error) {
+	return this.queryStates(ctx, ids, kind, selectLast, []string{"time < $3"}, bound(timestamp))
+}
+
+// stateNextQuery selects the first state with time > timestamp.
+func (this *Postgres) stateNextQuery(ctx context.Context, ids []string, kind string, timestamp time.Time) ([]row, error) {
+	return this.queryStates(ctx, ids, kind, selectFirst, []string{"time > $3"}, bound(timestamp))
+}
+
+// statesTimeGrtEqQuery selects all states with time >= timestamp.
+func (this *Postgres) statesTimeGrtEqQuery(ctx context.Context, ids []string, kind string, timestamp time.Time) ([]row, error) {
+	return this.queryStates(ctx, ids, kind, selectAll, []string{"time >= $3"}, bound(timestamp))
+}
+
+// statesTimeLesEqQuery selects all states with time <= timestamp.
+func (this *Postgres) statesTimeLesEqQuery(ctx context.Context, ids []string, kind string, timestamp time.Time) ([]row, error) {
+	return this.queryStates(ctx, ids, kind, selectAll, []string{"time <= $3"}, bound(timestamp))
+}
+
+// statesTimeGrtEqLesEqQuery selects all states with timestampA <= time <= timestampB.
+func (this *Postgres) statesTimeGrtEqLesEqQuery(ctx context.Context, ids []string, kind string, timestampA, timestampB time.Time) ([]row, error) {
+	return this.queryStates(ctx, ids, kind, selectAll, []string{"time >= $3", "time <= $4"}, bound(timestampA), bound(timestampB))
+}
+
+// bound truncates timestamps to seconds like the RFC 3339 timestamps in the influxdb query templates.
+func bound(t time.Time) time.Time {
+	return t.Truncate(time.Second)
+}
+
+func (this *Postgres) QueryHistoricalStates(ctx context.Context, query model.QueryHistorical, kind string) (map[string]model.HistoricalStates, error) {
+	window := store.NewHistoryWindow(query, time.Now())
+	resMap := map[string]model.HistoricalStates{}
+	if window.WithPrev {
+		rows, err := this.statePrevQuery(ctx, query.IDs, kind, window.Since)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			resource := resMap[r.ID]
+			resource.PrevState = &model.State{Time: r.Time, Connected: r.Connected}
+			resMap[r.ID] = resource
+		}
+	}
+	var rows []row
+	var err error
+	switch {
+	case !window.Since.IsZero() && !window.Until.IsZero():
+		rows, err = this.statesTimeGrtEqLesEqQuery(ctx, query.IDs, kind, window.Since, window.Until)
+	case !window.Since.IsZero():
+		rows, err = this.statesTimeGrtEqQuery(ctx, query.IDs, kind, window.Since)
+	default:
+		rows, err = this.statesTimeLesEqQuery(ctx, query.IDs, kind, window.Until)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		resource := resMap[r.ID]
+		resource.States = append(resource.States, model.State{Time: r.Time, Connected: r.Connected})
+		resMap[r.ID] = resource
+	}
+	if window.WithNext {
+		rows, err := this.stateNextQuery(ctx, query.IDs, kind, window.Until)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			resource := resMap[r.ID]
+			resource.NextState = &model.State{Time: r.Time, Connected: r.Connected}
+			resMap[r.ID] = resource
+		}
+	}
+	return resMap, nil
+}
+
+func (this *Postgres) GetOfflineSince(ctx context.Context, ids []string, kind string, notBefore time.Time) ([]model.OfflineSinceResponse, error) {
+	var conditions []string
+	var args []any
+	if !notBefore.IsZero() {
+		conditions, args = []string{"time >= $3"}, []any{bound(notBefore)}
+	}
+	rows, err := this.queryStates(ctx, ids, kind, selectLast, conditions, args...)
+	if err != nil {
+		return nil, err
+	}
+	result := []model.OfflineSinceResponse{}
+	for _, r := range rows {
+		if r.Connected {
+			continue
+		}
+		result = append(result, model.OfflineSinceResponse{ID: r.ID, OfflineSince: time.Unix(r.Time.Unix(), 0)})
+	}
+	slices.SortFunc(result, func(a, b model.OfflineSinceResponse) int {
+		return a.OfflineSince.Compare(b.OfflineSince)
+	})
+	return result, nil
+}
+
+func (this *Postgres) GetFirstOnlineStates(ctx context.Context, ids []string, kind string, after time.Time) (map[string]model.State, error) {
+	rows, err := this.queryStates(ctx, ids, kind, selectFirst, []string{"time >= $3", "connected"}, bound(after))
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]model.State{}
+	for _, r := range rows {
+		result[r.ID] = model.State{Time: r.Time, Connected: r.Connected}
+	}
+	return result, nil
+}
+
+func (this *Postgres) GetLastStates(ctx context.Context, ids []string, kind string) (map[string]model.State, error) {
+	rows, err := this.queryStates(ctx, ids, kind, selectLast, nil)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]model.State{}
+	for _, r := range rows {
+		result[r.ID] = model.State{Time: r.Time, Connected: r.Connected}
+	}
+	return result, nil
+}
+
+func (this *Postgres) GetResourcesHistory(ctx context.Context, ids []string, kind string, duration string) (any, error) {
+	dur, err := store.ParseInfluxDuration(duration)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := this.queryStates(ctx, ids, kind, selectAll, []string{"time > $3"}, time.Now().Add(-dur))
+	if err != nil {
+		return nil, err
+	}
+	result := store.HistoryResult{Series: []store.HistorySeries{}}
+	for i, r := range rows {
+		if i == 0 || rows[i-1].ID != r.ID {
+			result.Series = append(result.Series, store.HistorySeries{
+				Name:    kind,
+				Tags:    map[string]string{kind: r.ID},
+				Columns: []string{"time", "connected"},
+			})
+		}
+		series := &result.Series[len(result.Series)-1]
+		series.Values = append(series.Values, []any{r.Time.Unix(), r.Connected})
+	}
+	return []store.HistoryResult{result}, nil
+}
+
+func (this *Postgres) GetResourcesLogstart(ctx context.Context, ids []string, kind string) (map[string]float64, error) {
+	rows, err := this.queryStates(ctx, ids, kind, selectFirst, nil)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]float64{}
+	for _, r := range rows {
+		result[r.ID] = float64(r.Time.Unix())
+	}
+	return result, nil
+}
+
+func (this *Postgres) GetResourcesLogEdge(ctx context.Context, ids []string, kind string, duration string) (map[string]any, error) {
+	dur, err := store.ParseInfluxDuration(duration)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := this.queryStates(ctx, ids, kind, selectLast, []string{"time < $3"}, time.Now().Add(-dur))
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]any{}
+	for _, r := range rows {
+		result[r.ID] = []any{r.Time.Unix(), r.Connected}
+	}
+	return result, nil
+}
+
+func (this *Postgres) AddHistoricalState(ctx context.Context, id, kind string, state model.State) (bool, error) {
+	ctxWt, cf := this.timeout(ctx)
+	defer cf()
+	res, err := this.db.ExecContext(ctxWt, `INSERT INTO `+this.table+` (time, kind, id, connected) VALUES ($1, $2, $3, $4) ON CONFLICT (kind, id, time) DO NOTHING`, state.Time, kind, id, state.Connected)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	return rows > 0, err
+}
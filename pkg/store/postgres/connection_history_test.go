@@ -0,0 +1,228 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+// testQuery is a statement received by testDB with its arguments after kind and ids.
+type testQuery struct {
+	statement string
+	args      []any
+}
+
+// testDB is a database/sql connector recording queries, prev, states and next rows are returned by the order of the statement.
+type testDB struct {
+	mux     sync.Mutex
+	queries []testQuery
+	rows    map[string][][]driver.Value
+}
+
+func (this *testDB) Connect(context.Context) (driver.Conn, error) {
+	return this, nil
+}
+
+func (this *testDB) Driver() driver.Driver {
+	return nil
+}
+
+func (this *testDB) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (this *testDB) Close() error {
+	return nil
+}
+
+func (this *testDB) Begin() (driver.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (this *testDB) QueryContext(_ context.Context, statement string, args []driver.NamedValue) (driver.Rows, error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	query := testQuery{statement: statement}
+	for _, arg := range args[2:] {
+		query.args = append(query.args, arg.Value)
+	}
+	this.queries = append(this.queries, query)
+	order := selectAll
+	switch {
+	case strings.HasSuffix(statement, selectFirst):
+		order = selectFirst
+	case strings.HasSuffix(statement, selectLast):
+		order = selectLast
+	}
+	return &testRows{values: this.rows[order]}, nil
+}
+
+type testRows struct {
+	values [][]driver.Value
+}
+
+func (this *testRows) Columns() []string {
+	return []string{"id", "time", "connected"}
+}
+
+func (this *testRows) Close() error {
+	return nil
+}
+
+func (this *testRows) Next(dest []driver.Value) error {
+	if len(this.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, this.values[0])
+	this.values = this.values[1:]
+	return nil
+}
+
+func TestStatesStatement(t *testing.T) {
+	tests := []struct {
+		selector   string
+		conditions []string
+		expected   string
+	}{
+		{selector: selectAll, expected: `SELECT id, time, connected FROM "history" WHERE kind = $1 AND id = ANY($2) ORDER BY id, time`},
+		{selector: selectAll, conditions: []string{"time >= $3", "time <= $4"}, expected: `SELECT id, time, connected FROM "history" WHERE kind = $1 AND id = ANY($2) AND time >= $3 AND time <= $4 ORDER BY id, time`},
+		{selector: selectFirst, conditions: []string{"time > $3"}, expected: `SELECT DISTINCT ON (id) id, time, connected FROM "history" WHERE kind = $1 AND id = ANY($2) AND time > $3 ORDER BY id, time ASC`},
+		{selector: selectLast, conditions: []string{"time < $3"}, expected: `SELECT DISTINCT ON (id) id, time, connected FROM "history" WHERE kind = $1 AND id = ANY($2) AND time < $3 ORDER BY id, time DESC`},
+	}
+	for _, test := range tests {
+		if result := statesStatement(`"history"`, test.selector, test.conditions); result != test.expected {
+			t.Errorf("\n%s\n%s", result, test.expected)
+		}
+	}
+}
+
+func TestQueryHistoricalStates(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(8 * time.Hour)
+	rng := model.Duration(time.Hour)
+	prev := func(timestamp time.Time) testQuery {
+		return testQuery{statement: statesStatement(`"history"`, selectLast, []string{"time < $3"}), args: []any{timestamp}}
+	}
+	next := func(timestamp time.Time) testQuery {
+		return testQuery{statement: statesStatement(`"history"`, selectFirst, []string{"time > $3"}), args: []any{timestamp}}
+	}
+	states := func(conditions []string, timestamps ...any) testQuery {
+		return testQuery{statement: statesStatement(`"history"`, selectAll, conditions), args: timestamps}
+	}
+	tests := []struct {
+		name     string
+		query    model.QueryHistorical
+		expected []testQuery
+	}{
+		{
+			name:     "since and until",
+			query:    model.QueryHistorical{Since: since.Add(time.Millisecond), Until: until},
+			expected: []testQuery{prev(since), states([]string{"time >= $3", "time <= $4"}, since, until), next(until)},
+		},
+		{
+			name:     "range and until",
+			query:    model.QueryHistorical{Range: rng, Until: until},
+			expected: []testQuery{prev(until.Add(-time.Hour)), states([]string{"time >= $3", "time <= $4"}, until.Add(-time.Hour), until), next(until)},
+		},
+		{
+			name:     "range and since",
+			query:    model.QueryHistorical{Range: rng, Since: since},
+			expected: []testQuery{prev(since), states([]string{"time >= $3", "time <= $4"}, since, since.Add(time.Hour)), next(since.Add(time.Hour))},
+		},
+		{
+			name:     "until",
+			query:    model.QueryHistorical{Until: until},
+			expected: []testQuery{states([]string{"time <= $3"}, until), next(until)},
+		},
+		{
+			name:     "since",
+			query:    model.QueryHistorical{Since: since},
+			expected: []testQuery{prev(since), states([]string{"time >= $3"}, since)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := &testDB{}
+			this := &Postgres{config: configuration.Config{PostgresTimeout: 10}, db: sql.OpenDB(db), table: `"history"`}
+			test.query.IDs = []string{"a"}
+			if _, err := this.QueryHistoricalStates(context.Background(), test.query, model.DeviceKind); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(db.queries, test.expected) {
+				t.Errorf("\n%#v\n%#v", db.queries, test.expected)
+			}
+		})
+	}
+
+	t.Run("results", func(t *testing.T) {
+		db := &testDB{rows: map[string][][]driver.Value{
+			selectLast:  {{"a", since.Add(-time.Hour), true}},
+			selectAll:   {{"a", since.Add(time.Hour), false}, {"a", since.Add(2 * time.Hour), true}, {"b", since.Add(time.Hour), false}},
+			selectFirst: {{"b", until.Add(time.Hour), true}},
+		}}
+		this := &Postgres{config: configuration.Config{PostgresTimeout: 10}, db: sql.OpenDB(db), table: `"history"`}
+		result, err := this.QueryHistoricalStates(context.Background(), model.QueryHistorical{QueryBase: model.QueryBase{IDs: []string{"a", "b"}}, Since: since, Until: until}, model.DeviceKind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := map[string]model.HistoricalStates{
+			"a": {
+				PrevState: &model.State{Time: since.Add(-time.Hour), Connected: true},
+				States:    []model.State{{Time: since.Add(time.Hour)}, {Time: since.Add(2 * time.Hour), Connected: true}},
+			},
+			"b": {
+				States:    []model.State{{Time: since.Add(time.Hour)}},
+				NextState: &model.State{Time: until.Add(time.Hour), Connected: true},
+			},
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("\n%#v\n%#v", result, expected)
+		}
+	})
+}
+
+func TestGetFirstOnlineStates(t *testing.T) {
+	after := time.Date(2026, 1, 1, 0, 0, 0, int(500*time.Millisecond), time.UTC)
+	db := &testDB{rows: map[string][][]driver.Value{selectFirst: {{"a", after.Add(time.Hour), true}}}}
+	this := &Postgres{config: configuration.Config{PostgresTimeout: 10}, db: sql.OpenDB(db), table: `"history"`}
+	result, err := this.GetFirstOnlineStates(context.Background(), []string{"a", "b"}, model.DeviceKind, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []testQuery{{
+		statement: `SELECT DISTINCT ON (id) id, time, connected FROM "history" WHERE kind = $1 AND id = ANY($2) AND time >= $3 AND connected ORDER BY id, time ASC`,
+		args:      []any{after.Truncate(time.Second)},
+	}}
+	if !reflect.DeepEqual(db.queries, expected) {
+		t.Errorf("\n%#v\n%#v", db.queries, expected)
+	}
+	if len(result) != 1 || !result["a"].Time.Equal(after.Add(time.Hour).Truncate(time.Second)) || !result["a"].Connected {
+		t.Errorf("unexpected result %#v", result)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/lib/pq"
+)
+
+// Postgres stores the connection history as one row per state change.
+// If the timescaledb extension is installed the table is converted to a hypertable.
+type Postgres struct {
+	config configuration.Config
+	db     *sql.DB
+	table  string
+}
+
+func New(config configuration.Config) (*Postgres, error) {
+	db, err := sql.Open("postgres", config.PostgresUrl)
+	if err != nil {
+		return nil, err
+	}
+	this := &Postgres{
+		config: config,
+		db:     db,
+		table:  pq.QuoteIdentifier(config.PostgresTable),
+	}
+	err = this.createSchema()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return this, nil
+}
+
+func (this *Postgres) Close() error {
+	return this.db.Close()
+}
+
+func (this *Postgres) createSchema() error {
+	ctx, cf := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cf()
+	_, err := this.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+this.table+` (
+		time      TIMESTAMPTZ NOT NULL,
+		kind      TEXT        NOT NULL,
+		id        TEXT        NOT NULL,
+		connected BOOLEAN     NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+	_, err = this.db.ExecContext(ctx, `CREATE UNIQUE INDEX IF NOT EXISTS `+pq.QuoteIdentifier(this.config.PostgresTable+"_kind_id_time")+` ON `+this.table+` (kind, id, time DESC)`)
+	if err != nil {
+		return err
+	}
+	var timescale bool
+	err = this.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'timescaledb')`).Scan(&timescale)
+	if err != nil {
+		return err
+	}
+	if !timescale {
+		this.config.GetLogger().Warn("timescaledb extension not installed, using plain postgres table", "table", this.config.PostgresTable)
+		return nil
+	}
+	_, err = this.db.ExecContext(ctx, `SELECT create_hypertable($1::regclass, 'time', if_not_exists => TRUE)`, this.table)
+	return err
+}
+
+func (this *Postgres) timeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(this.config.PostgresTimeout)*time.Second)
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// CurrentStateStore provides the latest known connection state of devices and gateways.
+// The kind parameter is always a validated model.DeviceKind or model.GatewayKind.
+type CurrentStateStore interface {
+	GetCurrentState(ctx context.Context, id, kind string) (model.ResourceCurrentState, error)
+	QueryCurrentStates(ctx context.Context, ids []string, kind string) (map[string]bool, error)
+	// ListCurrentStates returns the current states of the given ids with the time of the last transition, if known.
+	ListCurrentStates(ctx context.Context, ids []string, kind string) ([]model.ResourceCurrentState, error)
+	Close() error
+}
+
+// HistoryStore provides the connection history of devices and gateways.
+// The kind parameter is always a validated model.DeviceKind or model.GatewayKind.
+type HistoryStore interface {
+	QueryHistoricalStates(ctx context.Context, query model.QueryHistorical, kind string) (map[string]model.HistoricalStates, error)
+	// GetOfflineSince returns the offline resources sorted by offline timestamp.
+	// With a non-zero notBefore, resources offline since an earlier time are omitted.
+	GetOfflineSince(ctx context.Context, ids []string, kind string, notBefore time.Time) ([]model.OfflineSinceResponse, error)
+	// GetLastStates returns the latest state of each id, ids without history are omitted.
+	GetLastStates(ctx context.Context, ids []string, kind string) (map[string]model.State, error)
+	// GetFirstOnlineStates returns the first online state with time >= after of each id, ids without such a state are omitted.
+	GetFirstOnlineStates(ctx context.Context, ids []string, kind string, after time.Time) (map[string]model.State, error)
+
+	// GetResourcesHistory, GetResourcesLogstart and GetResourcesLogEdge serve the old api,
+	// durations are expected in influxdb format https://docs.influxdata.com/influxdb/v1.5/query_language/spec/#durations
+	GetResourcesHistory(ctx context.Context, ids []string, kind string, duration string) (any, error)
+	GetResourcesLogstart(ctx context.Context, ids []string, kind string) (map[string]float64, error)
+	GetResourcesLogEdge(ctx context.Context, ids []string, kind string, duration string) (map[string]any, error)
+
+	Close() error
+}
+
+// CurrentStateWriter is implemented by current state stores that accept state changes.
+type CurrentStateWriter interface {
+	// SetCurrentState stores state as current state of the resource, unless a newer state is already known.
+	// applied is false if the state is outdated and was ignored.
+	SetCurrentState(ctx context.Context, id, kind string, state model.State) (applied bool, err error)
+}
+
+// HistoryWriter is implemented by history stores that accept new states.
+// Adding a state with the same id and time more than once must not create duplicates, added is false for those.
+type HistoryWriter interface {
+	AddHistoricalState(ctx context.Context, id, kind string, state model.State) (added bool, err error)
+}
+
+var ErrInvalidResumeToken = errors.New("invalid resume token")
+
+// CurrentStateWatcher is implemented by current state stores that provide a feed of state changes.
+type CurrentStateWatcher interface {
+	// WatchCurrentStates returns after the feed of changes of the given ids by kind is opened.
+	// An empty resumeToken starts with new changes, otherwise the feed continues after the change of the token.
+	// The channel is closed when ctx is done or the feed fails.
+	WatchCurrentStates(ctx context.Context, idsByKind map[string][]string, resumeToken string) (<-chan model.StateChange, error)
+}
+
+// WebhookStore is implemented by stores that keep webhook registrations.
+type WebhookStore interface {
+	// ListWebhooks returns the webhooks of owner, or all webhooks if owner is empty.
+	ListWebhooks(ctx context.Context, owner string) ([]model.Webhook, error)
+	// GetWebhook returns ErrNotFound for unknown ids.
+	GetWebhook(ctx context.Context, id string) (model.Webhook, error)
+	// SetWebhook creates or replaces the registration, alerts are kept.
+	SetWebhook(ctx context.Context, webhook model.Webhook) error
+	DeleteWebhook(ctx context.Context, id string) error
+	// UpdateWebhookAlerts adds the given alerts and removes the resources in resolved.
+	UpdateWebhookAlerts(ctx context.Context, id string, alerts map[string]time.Time, resolved []string) error
+}
+
+// AlertStore is implemented by stores that keep alert rules and alerts.
+type AlertStore interface {
+	// ListAlertRules returns the rules of owner, or all rules if owner is empty.
+	ListAlertRules(ctx context.Context, owner string) ([]model.AlertRule, error)
+	// GetAlertRule returns ErrNotFound for unknown ids.
+	GetAlertRule(ctx context.Context, id string) (model.AlertRule, error)
+	SetAlertRule(ctx context.Context, rule model.AlertRule) error
+	// DeleteAlertRule removes the rule and its alerts.
+	DeleteAlertRule(ctx context.Context, id string) error
+	// ListAlerts returns the matching alerts, latest first.
+	ListAlerts(ctx context.Context, filter model.AlertFilter) ([]model.Alert, error)
+	// GetAlert returns ErrNotFound for unknown ids.
+	GetAlert(ctx context.Context, id string) (model.Alert, error)
+	SetAlert(ctx context.Context, alert model.Alert) error
+}
+
+// DigestStore is implemented by stores that keep digest subscriptions.
+type DigestStore interface {
+	// ListDigestSubscriptions returns the subscriptions of owner, or all subscriptions if owner is empty.
+	ListDigestSubscriptions(ctx context.Context, owner string) ([]model.DigestSubscription, error)
+	// GetDigestSubscription returns ErrNotFound for unknown ids.
+	GetDigestSubscription(ctx context.Context, id string) (model.DigestSubscription, error)
+	SetDigestSubscription(ctx context.Context, subscription model.DigestSubscription) error
+	DeleteDigestSubscription(ctx context.Context, id string) error
+	SetDigestSent(ctx context.Context, id string, sent time.Time) error
+}
+
+type HistoryResult struct {
+	Series []HistorySeries `json:"Series"`
+}
+
+type HistorySeries struct {
+	Name    string            `json:"name"`
+	Tags    map[string]string `json:"tags"`
+	Columns []string          `json:"columns"`
+	Values  [][]interface{}   `json:"values"`
+}
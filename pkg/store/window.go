@@ -0,0 +1,64 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package store
+
+import (
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+// HistoryWindow is the time frame of a historical states query.
+type HistoryWindow struct {
+	Since    time.Time // Start of the time frame, zero if open.
+	Until    time.Time // End of the time frame, zero if open.
+	WithPrev bool      // Include the last state before Since.
+	WithNext bool      // Include the first state after Until.
+}
+
+// NewHistoryWindow returns the time frame selected by since, until and range of query, now is the reference of queries without a timestamp.
+// All backends select the same states:
+//
+//	since && until: since <= time <= until, with prev and next
+//	range && until: until - range <= time <= until, with prev and next
+//	range && since: since <= time <= since + range, with prev and next
+//	range:          now - range <= time, with prev
+//	until:          time <= until, with next
+//	since:          since <= time, with prev
+//	none:           time <= now
+func NewHistoryWindow(query model.QueryHistorical, now time.Time) HistoryWindow {
+	rng := time.Duration(query.Range)
+	hasRange := rng > 0
+	hasSince := !query.Since.IsZero()
+	hasUntil := !query.Until.IsZero()
+	switch {
+	case hasSince && hasUntil:
+		return HistoryWindow{Since: query.Since, Until: query.Until, WithPrev: true, WithNext: true}
+	case hasRange && hasUntil:
+		return HistoryWindow{Since: query.Until.Add(-rng), Until: query.Until, WithPrev: true, WithNext: true}
+	case hasRange && hasSince:
+		return HistoryWindow{Since: query.Since, Until: query.Since.Add(rng), WithPrev: true, WithNext: true}
+	case hasRange:
+		return HistoryWindow{Since: now.Add(-rng), WithPrev: true}
+	case hasUntil:
+		return HistoryWindow{Until: query.Until, WithNext: true}
+	case hasSince:
+		return HistoryWindow{Since: query.Since, WithPrev: true}
+	default:
+		return HistoryWindow{Until: now}
+	}
+}
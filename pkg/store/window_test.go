@@ -0,0 +1,55 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+func TestNewHistoryWindow(t *testing.T) {
+	now := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	rng := model.Duration(time.Hour)
+	tests := []struct {
+		name     string
+		query    model.QueryHistorical
+		expected HistoryWindow
+	}{
+		{name: "since and until", query: model.QueryHistorical{Since: since, Until: until}, expected: HistoryWindow{Since: since, Until: until, WithPrev: true, WithNext: true}},
+		{name: "since, until and range", query: model.QueryHistorical{Since: since, Until: until, Range: rng}, expected: HistoryWindow{Since: since, Until: until, WithPrev: true, WithNext: true}},
+		{name: "range and until", query: model.QueryHistorical{Until: until, Range: rng}, expected: HistoryWindow{Since: until.Add(-time.Hour), Until: until, WithPrev: true, WithNext: true}},
+		{name: "range and since", query: model.QueryHistorical{Since: since, Range: rng}, expected: HistoryWindow{Since: since, Until: since.Add(time.Hour), WithPrev: true, WithNext: true}},
+		{name: "range", query: model.QueryHistorical{Range: rng}, expected: HistoryWindow{Since: now.Add(-time.Hour), WithPrev: true}},
+		{name: "until", query: model.QueryHistorical{Until: until}, expected: HistoryWindow{Until: until, WithNext: true}},
+		{name: "since", query: model.QueryHistorical{Since: since}, expected: HistoryWindow{Since: since, WithPrev: true}},
+		{name: "none", query: model.QueryHistorical{}, expected: HistoryWindow{Until: now}},
+		{name: "negative range", query: model.QueryHistorical{Range: -rng}, expected: HistoryWindow{Until: now}},
+		{name: "negative range and since", query: model.QueryHistorical{Since: since, Range: -rng}, expected: HistoryWindow{Since: since, WithPrev: true}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := NewHistoryWindow(test.query, now)
+			if result != test.expected {
+				t.Errorf("\n%#v\n%#v", result, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// boundHost is the host suffix of the urls returned by Bind, the .invalid top level domain never resolves.
+const boundHost = ".bound.invalid"
+
+type binding struct {
+	ctx       context.Context
+	client    *http.Client
+	serverUrl string
+}
+
+var (
+	bindings   sync.Map // host -> binding
+	lastBindId atomic.Uint64
+)
+
+// The permissions-v2 and device-repository clients send their requests with http.DefaultClient and without context.
+// Requests to urls returned by Bind are passed to the bound client, all other requests to the previous transport.
+func init() {
+	http.DefaultClient.Transport = &boundTransport{next: http.DefaultClient.Transport}
+}
+
+// Bind returns a server url for clients that send their requests with http.DefaultClient and without context.
+// Requests to the returned url are sent to serverUrl with ctx and client, until unbind is called.
+func Bind(ctx context.Context, client *http.Client, serverUrl string) (boundUrl string, unbind func()) {
+	host := strconv.FormatUint(lastBindId.Add(1), 10) + boundHost
+	bindings.Store(host, binding{ctx: ctx, client: client, serverUrl: strings.TrimSuffix(serverUrl, "/")})
+	return "http://" + host, func() { bindings.Delete(host) }
+}
+
+type boundTransport struct {
+	next http.RoundTripper
+}
+
+func (this *boundTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.HasSuffix(req.URL.Host, boundHost) {
+		next := this.next
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		return next.RoundTrip(req)
+	}
+	value, ok := bindings.Load(req.URL.Host)
+	if !ok {
+		return nil, errors.New("request after the end of its binding")
+	}
+	bound := value.(binding)
+	target, err := url.Parse(bound.serverUrl + strings.TrimPrefix(req.URL.String(), "http://"+req.URL.Host))
+	if err != nil {
+		return nil, err
+	}
+	out := req.Clone(bound.ctx)
+	out.URL = target
+	out.Host = ""
+	return bound.client.Do(out)
+}
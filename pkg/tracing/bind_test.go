@@ -0,0 +1,65 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBind(t *testing.T) {
+	requests := make(chan *http.Request, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		requests <- request
+		if request.URL.Path == "/api/slow" {
+			<-request.Context().Done()
+			return
+		}
+		_, _ = io.WriteString(writer, "ok")
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	boundUrl, unbind := Bind(ctx, server.Client(), server.URL+"/api/")
+	resp, err := http.Get(boundUrl + "/devices/a%2Fb?p=r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Errorf("unexpected body %q", body)
+	}
+	if request := <-requests; request.URL.EscapedPath() != "/api/devices/a%2Fb" || request.URL.RawQuery != "p=r" {
+		t.Errorf("unexpected request %v", request.URL)
+	}
+
+	// requests end with the bound context
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err = http.Get(boundUrl + "/slow"); err == nil {
+		t.Error("expected error of the canceled context")
+	}
+
+	unbind()
+	if _, err = http.Get(boundUrl + "/devices"); err == nil {
+		t.Error("expected error after unbind")
+	}
+}
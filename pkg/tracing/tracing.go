@@ -0,0 +1,91 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/SENERGY-Platform/connection-log"
+
+const serviceName = "connection-log"
+
+// Init sets the W3C trace context propagator and exports the spans over OTLP/HTTP to config.OtlpEndpoint.
+// Only the http clients of the service send the trace context, they use Transport.
+// Spans are not recorded if the endpoint is empty or "-", incoming trace context is still passed on.
+// The returned function flushes and stops the exporter.
+func Init(ctx context.Context, config configuration.Config) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if config.OtlpEndpoint == "" || config.OtlpEndpoint == "-" {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(config.OtlpEndpoint))
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	config.GetLogger().Info("export traces", "endpoint", config.OtlpEndpoint)
+	return provider.Shutdown, nil
+}
+
+// Start creates a span as child of the span in ctx.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End records err on span, if set, and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Transport adds a client span and the trace context headers to requests with a span in their context.
+// A nil base uses http.DefaultTransport.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
+
+// Handler creates a server span per request, continuing the trace context of the request headers.
+// The response writer keeps its flush and hijack support.
+func Handler(operation string, handler http.Handler) http.Handler {
+	return otelhttp.NewHandler(handler, operation, otelhttp.WithSpanNameFormatter(func(operation string, _ *http.Request) string {
+		return operation
+	}))
+}
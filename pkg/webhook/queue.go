@@ -0,0 +1,92 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+// Queue delivers the payloads of webhooks and alert notifications with a fixed number of workers,
+// so a burst of events can not start an unbounded number of requests.
+type Queue struct {
+	config     configuration.Config
+	sender     *Sender
+	deliveries chan delivery
+}
+
+type delivery struct {
+	webhook model.Webhook
+	payload any
+}
+
+// NewQueue starts config.WebhookWorkers workers, they stop with running deliveries when ctx is done.
+func NewQueue(ctx context.Context, config configuration.Config) (*Queue, error) {
+	timeout, err := time.ParseDuration(config.WebhookTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook timeout: %w", err)
+	}
+	if config.WebhookWorkers <= 0 {
+		return nil, errors.New("WebhookWorkers must be positive")
+	}
+	sender, err := NewSender(config, timeout)
+	if err != nil {
+		return nil, err
+	}
+	queue := &Queue{
+		config:     config,
+		sender:     sender,
+		deliveries: make(chan delivery, max(config.WebhookQueueSize, 0)),
+	}
+	for range config.WebhookWorkers {
+		go queue.work(ctx)
+	}
+	return queue, nil
+}
+
+// Enqueue schedules the delivery of payload to webhook. It waits while the queue is full
+// and returns the error of ctx if the delivery was not accepted before ctx is done.
+func (this *Queue) Enqueue(ctx context.Context, webhook model.Webhook, payload any) error {
+	select {
+	case this.deliveries <- delivery{webhook: webhook, payload: payload}:
+		return nil
+	default:
+	}
+	this.config.GetLogger().Warn("webhook queue full, wait for free workers", "webhook", webhook.ID)
+	select {
+	case this.deliveries <- delivery{webhook: webhook, payload: payload}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (this *Queue) work(ctx context.Context) {
+	for {
+		select {
+		case d := <-this.deliveries:
+			this.sender.Deliver(ctx, d.webhook, d.payload)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
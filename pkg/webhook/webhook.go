@@ -0,0 +1,267 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/controller"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+	"github.com/SENERGY-Platform/connection-log/pkg/tracing"
+)
+
+const maxRetryWait = time.Minute
+
+const SignatureHeader = "X-Signature-256"
+
+// Start evaluates the registered webhooks every config.WebhookInterval until ctx is done, events are delivered by queue.
+// Nothing is started if the interval is empty, the current state store does not support webhooks
+// or no user tokens can be requested to check the permissions of the webhook owners.
+func Start(ctx context.Context, config configuration.Config, ctrl *controller.Controller, queue *Queue) error {
+	if config.WebhookInterval == "" || config.WebhookInterval == "-" {
+		return nil
+	}
+	if config.AuthEndpoint == "" || config.AuthEndpoint == "-" {
+		config.GetLogger().Warn("webhooks disabled", "reason", controller.ErrTokenExchangeDisabled)
+		return nil
+	}
+	if err := ctrl.CheckWebhooks(); err != nil {
+		if errors.Is(err, controller.ErrWebhooksNotSupported) {
+			config.GetLogger().Warn("webhooks disabled", "reason", err)
+			return nil
+		}
+		return err
+	}
+	interval, err := time.ParseDuration(config.WebhookInterval)
+	if err != nil {
+		return fmt.Errorf("invalid webhook interval: %w", err)
+	}
+	w := &worker{
+		config: config,
+		ctrl:   ctrl,
+		queue:  queue,
+	}
+	go w.run(ctx, interval)
+	return nil
+}
+
+// Sign returns the value of the signature header: the hex encoded HMAC-SHA256 of body, prefixed with "sha256=".
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type worker struct {
+	config configuration.Config
+	ctrl   *controller.Controller
+	queue  *Queue
+}
+
+func (this *worker) run(ctx context.Context, interval time.Duration) {
+	this.config.GetLogger().Info("start webhook worker", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		this.evaluate(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			this.config.GetLogger().Info("stop webhook worker")
+			return
+		}
+	}
+}
+
+func (this *worker) evaluate(ctx context.Context) {
+	webhooks, err := this.ctrl.ListWebhooks(ctx, "")
+	if err != nil {
+		this.config.GetLogger().Error("unable to list webhooks", "error", err)
+		return
+	}
+	for _, webhook := range webhooks {
+		if err = this.evaluateWebhook(ctx, webhook); err != nil {
+			this.config.GetLogger().Error("unable to evaluate webhook", "webhook", webhook.ID, "error", err)
+		}
+	}
+}
+
+// evaluateWebhook sends an offline event for resources that are offline longer than the threshold
+// and an online event for alerted resources that are no longer offline.
+// Alerts are stored after the events are accepted by the queue, so events are delivered at least once.
+// The resources are resolved on each evaluation, with the current permissions of the owner and the current group and location members.
+func (this *worker) evaluateWebhook(ctx context.Context, webhook model.Webhook) error {
+	now := time.Now()
+	resources, err := this.ctrl.ResolveOwnerResources(ctx, webhook.Owner, webhook.IDs)
+	if err != nil {
+		return err
+	}
+	offline, err := this.ctrl.GetOfflineSinceMixed(ctx, resources, model.OfflineSinceFilter{MinOfflineDuration: webhook.Threshold})
+	if err != nil {
+		return err
+	}
+	events := []model.WebhookEvent{}
+	offlineIds := map[string]bool{}
+	for _, state := range offline {
+		offlineIds[state.ID] = true
+		if since, ok := webhook.Alerts[state.ID]; ok && since.Equal(state.OfflineSince) {
+			continue
+		}
+		events = append(events, newEvent(model.WebhookEventOffline, webhook.ID, state.ID, state.OfflineSince, now))
+	}
+	resolved := []string{}
+	for id, since := range webhook.Alerts {
+		if offlineIds[id] {
+			continue
+		}
+		if slices.Contains(resources, id) {
+			events = append(events, newEvent(model.WebhookEventOnline, webhook.ID, id, since, now))
+		} else {
+			// no longer accessible or member, resolved without event
+			resolved = append(resolved, id)
+		}
+	}
+	if len(events) == 0 && len(resolved) == 0 {
+		return nil
+	}
+	// only the alerts of accepted events are stored, the others are sent again by the next evaluation
+	alerts := map[string]time.Time{}
+	var enqueueErr error
+	for _, event := range events {
+		if enqueueErr = this.queue.Enqueue(ctx, webhook, event); enqueueErr != nil {
+			break
+		}
+		if event.Type == model.WebhookEventOffline {
+			alerts[event.ID] = event.OfflineSince
+		} else {
+			resolved = append(resolved, event.ID)
+		}
+	}
+	if len(alerts) > 0 || len(resolved) > 0 {
+		if err = this.ctrl.UpdateWebhookAlerts(context.WithoutCancel(ctx), webhook.ID, alerts, resolved); err != nil {
+			return err
+		}
+	}
+	return enqueueErr
+}
+
+func newEvent(eventType string, webhookId string, id string, offlineSince time.Time, now time.Time) model.WebhookEvent {
+	kind, _ := controller.GetKindFromId(id, false)
+	return model.WebhookEvent{
+		Type:         eventType,
+		WebhookID:    webhookId,
+		ID:           id,
+		Kind:         kind,
+		OfflineSince: offlineSince.UTC(),
+		Time:         now.UTC().Truncate(time.Second),
+	}
+}
+
+// Sender delivers signed JSON payloads to webhooks.
+type Sender struct {
+	config configuration.Config
+	client *http.Client
+}
+
+// NewSender creates a sender that only connects to addresses allowed by controller.CheckWebhookIP.
+// The addresses are checked on connect, after the DNS resolution, and no proxy is used, so every request is checked.
+func NewSender(config configuration.Config, timeout time.Duration) (*Sender, error) {
+	allowed, err := controller.ParseNetworks(config.WebhookAllowedNetworks)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			return controller.CheckWebhookIP(net.ParseIP(host), allowed)
+		},
+	}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &Sender{config: config, client: &http.Client{Timeout: timeout, Transport: tracing.Transport(transport)}}, nil
+}
+
+// Deliver posts the payload, failed requests are repeated with exponential backoff up to config.WebhookMaxAttempts times.
+func (this *Sender) Deliver(ctx context.Context, webhook model.Webhook, payload any) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		this.config.GetLogger().Error("unable to marshal webhook payload", "webhook", webhook.ID, "error", err)
+		return
+	}
+	wait := time.Second
+	for attempt := int64(1); ; attempt++ {
+		err = this.send(ctx, webhook, body)
+		if err == nil {
+			return
+		}
+		if attempt >= this.config.WebhookMaxAttempts {
+			this.config.GetLogger().Error("webhook delivery failed", "webhook", webhook.ID, "attempts", attempt, "error", err)
+			return
+		}
+		this.config.GetLogger().Warn("webhook delivery failed, retry", "webhook", webhook.ID, "attempt", attempt, "wait", wait, "error", err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+		wait = min(wait*2, maxRetryWait)
+	}
+}
+
+func (this *Sender) send(ctx context.Context, webhook model.Webhook, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Id", webhook.ID)
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, body))
+	resp, err := this.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
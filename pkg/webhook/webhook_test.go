@@ -0,0 +1,104 @@
+/*
+ * Copyright 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/connection-log/pkg/configuration"
+	"github.com/SENERGY-Platform/connection-log/pkg/model"
+)
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		secret   string
+		body     string
+		expected string
+	}{
+		{
+			secret:   "key",
+			body:     "The quick brown fox jumps over the lazy dog",
+			expected: "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		},
+		{
+			secret:   "",
+			body:     "",
+			expected: "sha256=b613679a0814d9ec772f95d778c35fc5ff1697c493715653c6c712144292c5ad",
+		},
+	}
+	for _, test := range tests {
+		if result := Sign(test.secret, []byte(test.body)); result != test.expected {
+			t.Errorf("%v != %v", result, test.expected)
+		}
+	}
+}
+
+// TestSignVerify checks the signature like a receiver would: recompute the HMAC of the raw body and compare in constant time.
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":"urn:infai:ses:device:1","connected":false}`)
+	signature := Sign("secret", body)
+	if !strings.HasPrefix(signature, "sha256=") {
+		t.Fatalf("missing prefix: %v", signature)
+	}
+	received, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	if !hmac.Equal(received, mac.Sum(nil)) {
+		t.Error("signature does not match body")
+	}
+	if Sign("other", body) == signature {
+		t.Error("signature does not depend on the secret")
+	}
+	if Sign("secret", append(body, ' ')) == signature {
+		t.Error("signature does not depend on the body")
+	}
+}
+
+func TestQueueEnqueue(t *testing.T) {
+	queue := &Queue{config: configuration.Config{LogLevel: "error"}, deliveries: make(chan delivery, 1)}
+	webhook := model.Webhook{ID: "webhook"}
+	if err := queue.Enqueue(context.Background(), webhook, "first"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := queue.Enqueue(ctx, webhook, "second"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the error of ctx, got %v", err)
+	}
+
+	// a full queue waits for the workers
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-queue.deliveries
+	}()
+	if err := queue.Enqueue(context.Background(), webhook, "third"); err != nil {
+		t.Fatal(err)
+	}
+	if d := <-queue.deliveries; d.payload != "third" {
+		t.Errorf("unexpected delivery %v", d.payload)
+	}
+}